@@ -1,50 +1,53 @@
 package api
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
-	"os"
-
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/logger"
-	"github.com/gofiber/fiber/v2/middleware/recover"
-	"github.com/joho/godotenv"
+	"time"
 
 	"nova/api/config"
 	"nova/api/database"
-	"nova/api/routes"
-	"nova/api/services"
+	"nova/api/store"
 )
 
 func Main() {
-	err := godotenv.Load()
-	if err != nil {
+	if err := config.LoadEnvFile(); err != nil {
 		fmt.Println("Error loading .env file, falling back to environment variables")
 	}
 
 	cfg := config.Load()
-	db := database.New(cfg)
-
-	solanaService := services.NewSolanaService(cfg.SolaanRPCURL, db.Redis)
-	routes.InitSolanaService(solanaService)
-
-	app := fiber.New(fiber.Config{
-		JSONEncoder: json.Marshal,
-		JSONDecoder: json.Unmarshal,
-	})
 
-	app.Use(recover.New())
-	app.Use(logger.New())
+	var stores *store.Stores
+	if cfg.Storage == store.StorageMemory {
+		fmt.Println("Using in-memory storage, data is lost on restart")
+		stores = store.NewMemory()
+	} else {
+		db := database.New(cfg)
+
+		if cfg.AutoMigrate {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			if _, err := database.Migrate(ctx, cfg, db); err != nil {
+				log.Fatal("Error running migrations: ", err)
+			}
+			cancel()
+		}
+
+		stores = store.New(cfg, db)
+	}
 
-	routes.InitRoutes(app, db)
+	server, err := NewServer(WithConfig(cfg), WithStores(stores))
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	port := os.Getenv("API_PORT")
+	go server.Run(context.Background())
 
-	if port == "" {
-		port = "8080"
-	}
+	go func() {
+		fmt.Println("gRPC is up and running on port", cfg.GRPCPort)
+		log.Fatal(server.ListenGRPC("0.0.0.0:" + cfg.GRPCPort))
+	}()
 
-	fmt.Println("API is up and running on port", port)
-	log.Fatal(app.Listen("0.0.0.0:" + port))
+	fmt.Println("API is up and running on port", cfg.Port)
+	log.Fatal(server.Listen("0.0.0.0:" + cfg.Port))
 }
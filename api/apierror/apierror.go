@@ -0,0 +1,185 @@
+// Package apierror writes error responses in the format of the API version
+// a request was made to. Legacy routes keep the {success, message} body with
+// a code added; /api/v1 routes and /ws handshakes get {code, message,
+// details, request_id}, /graphql gets a GraphQL errors list carrying the
+// same fields, and /rpc a JSON-RPC error with them as its data.
+package apierror
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"nova/api/types"
+)
+
+const (
+	v1Prefix    = "/api/v1/"
+	graphQLPath = "/graphql"
+	rpcPath     = "/rpc"
+	wsPath      = "/ws"
+)
+
+// messages are the public descriptions of codes that can appear on a single
+// wallet. They replace upstream error text, which may leak RPC details.
+var messages = map[string]string{
+	types.CodeInvalidAddress:  "Not a valid Solana address",
+	types.CodeUpstreamError:   "The Solana RPC could not return this balance",
+	types.CodeUpstreamTimeout: "The balance was not fetched before the request deadline",
+}
+
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Details map[string]interface{}
+}
+
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// With adds a detail to the error and returns it.
+func (e *Error) With(key string, value interface{}) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+	e.Details[key] = value
+	return e
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Extensions makes the error carry its code and details when a GraphQL
+// resolver returns it.
+func (e *Error) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": e.Code}
+	if len(e.Details) > 0 {
+		extensions["details"] = e.Details
+	}
+	return extensions
+}
+
+// RPC returns the error as a JSON-RPC server error.
+func (e *Error) RPC(requestID string) *types.RPCError {
+	return &types.RPCError{
+		Code:    types.RPCServerError,
+		Message: e.Message,
+		Data:    types.RPCErrorData{Code: e.Code, Details: e.Details, RequestID: requestID},
+	}
+}
+
+func (e *Error) Send(c *fiber.Ctx) error {
+	if IsRPC(c) {
+		return c.Status(e.Status).JSON(types.RPCResponse{
+			JSONRPC: "2.0",
+			Error:   e.RPC(c.GetRespHeader(fiber.HeaderXRequestID)),
+		})
+	}
+
+	if IsGraphQL(c) {
+		return c.Status(e.Status).JSON(types.GraphQLResponse{
+			Errors: []types.GraphQLError{{
+				Message: e.Message,
+				Extensions: &types.GraphQLErrorExtensions{
+					Code:      e.Code,
+					Details:   e.Details,
+					RequestID: c.GetRespHeader(fiber.HeaderXRequestID),
+				},
+			}},
+		})
+	}
+
+	if IsV1(c) || IsWebSocket(c) {
+		return c.Status(e.Status).JSON(types.APIError{
+			Code:      e.Code,
+			Message:   e.Message,
+			Details:   e.Details,
+			RequestID: c.GetRespHeader(fiber.HeaderXRequestID),
+		})
+	}
+
+	return c.Status(e.Status).JSON(types.ErrorResponse{
+		Success: false,
+		Message: e.Message,
+		Code:    e.Code,
+	})
+}
+
+func IsV1(c *fiber.Ctx) bool {
+	return strings.HasPrefix(c.Path(), v1Prefix)
+}
+
+func IsGraphQL(c *fiber.Ctx) bool {
+	return c.Path() == graphQLPath
+}
+
+func IsRPC(c *fiber.Ctx) bool {
+	return c.Path() == rpcPath
+}
+
+func IsWebSocket(c *fiber.Ctx) bool {
+	return c.Path() == wsPath
+}
+
+// Handler is the fiber error handler. Errors that escape a handler, such as
+// unknown routes, oversized bodies and panics, are sent like any other
+// error.
+func Handler(c *fiber.Ctx, err error) error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Send(c)
+	}
+
+	var fiberErr *fiber.Error
+	if !errors.As(err, &fiberErr) {
+		return New(fiber.StatusInternalServerError, types.CodeInternal, "Internal server error").Send(c)
+	}
+
+	code := types.CodeInvalidRequest
+	switch {
+	case fiberErr.Code == fiber.StatusNotFound:
+		code = types.CodeNotFound
+	case fiberErr.Code >= fiber.StatusInternalServerError:
+		code = types.CodeInternal
+	}
+	return New(fiberErr.Code, code, fiberErr.Message).Send(c)
+}
+
+// WalletError returns the /api/v1 error of a wallet result, or nil if it
+// succeeded.
+func WalletError(result types.WalletBalance) *types.WalletError {
+	return Sanitized(result.Error, result.ErrorCode)
+}
+
+// Sanitized returns the public error of a lookup that failed with message,
+// or nil if message is empty. Lookups without a code are upstream errors.
+func Sanitized(message, code string) *types.WalletError {
+	if message == "" {
+		return nil
+	}
+
+	if code == "" {
+		code = types.CodeUpstreamError
+	}
+	return &types.WalletError{Code: code, Message: messages[code]}
+}
+
+func BalanceResult(result types.WalletBalance) types.BalanceResult {
+	return types.BalanceResult{
+		Address: result.Address,
+		Balance: result.Balance,
+		Error:   WalletError(result),
+	}
+}
+
+func BalanceResults(results []types.WalletBalance) []types.BalanceResult {
+	converted := make([]types.BalanceResult, len(results))
+	for i, result := range results {
+		converted[i] = BalanceResult(result)
+	}
+	return converted
+}
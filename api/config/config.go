@@ -1,29 +1,211 @@
 package config
 
 import (
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
+	"nova/api/store"
 	"nova/api/types"
 	"os"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/joho/godotenv"
 )
 
+// LoadEnvFile loads .env into the environment when present. Variables that
+// are already set take precedence.
+func LoadEnvFile() error {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
 func Load() *types.Config {
-	heliusAPIKey := getEnv("HELIUS_API_KEY", "")
-	if heliusAPIKey == "" {
-		log.Fatal("HELIUS_API_KEY environment variable is required")
+	cfg, err := Parse()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := Validate(cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	return cfg
+}
+
+func Parse() (*types.Config, error) {
+	solanaRPCURL := getEnv("SOLANA_RPC_URL", "")
+	if solanaRPCURL == "" {
+		heliusAPIKey := getEnv("HELIUS_API_KEY", "")
+		if heliusAPIKey == "" {
+			return nil, errors.New("HELIUS_API_KEY environment variable is required")
+		}
+
+		solanaRPCURL = fmt.Sprintf("https://pomaded-lithotomies-xfbhnqagbt-dedicated.helius-rpc.com/?api-key=%s", heliusAPIKey)
+	}
+
+	// Solana nodes serve pubsub on the RPC URL with a websocket scheme.
+	solanaWSURL := getEnv("SOLANA_WS_URL", "")
+	if solanaWSURL == "" {
+		solanaWSURL = strings.Replace(strings.Replace(solanaRPCURL, "https://", "wss://", 1), "http://", "ws://", 1)
+	}
+
+	// API_PORT is the deprecated name of PORT.
+	port := getEnv("PORT", "")
+	if port == "" {
+		port = getEnv("API_PORT", "8080")
+		if _, ok := os.LookupEnv("API_PORT"); ok {
+			log.Println("API_PORT is deprecated, set PORT instead")
+		}
+	}
+
+	rpcConcurrency, err := strconv.Atoi(getEnv("RPC_CONCURRENCY", "64"))
+	if err != nil {
+		return nil, fmt.Errorf("RPC_CONCURRENCY must be a number: %w", err)
+	}
+
+	requestTimeout, err := time.ParseDuration(getEnv("REQUEST_TIMEOUT", "30s"))
+	if err != nil {
+		return nil, fmt.Errorf("REQUEST_TIMEOUT must be a duration such as 30s: %w", err)
 	}
-	
-	solanaRPCURL := getEnv("SOLANA_RPC_URL", fmt.Sprintf("https://pomaded-lithotomies-xfbhnqagbt-dedicated.helius-rpc.com/?api-key=%s", heliusAPIKey))
 
 	return &types.Config{
-		Port:         getEnv("PORT", "3000"),
-		MongoURI:     getEnv("MONGO_URI", "mongodb://localhost:27017"),
-		RedisURI:     getEnv("REDIS_URI", "localhost:6379"),
-		SolaanRPCURL: solanaRPCURL,
-		CacheTTL:     10 * time.Second,
-		RateLimit:    10,
+		Port:           port,
+		GRPCPort:       getEnv("GRPC_PORT", "9090"),
+		MongoURI:       getEnv("MONGO_URI", "mongodb://localhost:27017"),
+		MongoDatabase:  getEnv("MONGO_DATABASE", "nova"),
+		AutoMigrate:    getEnv("AUTO_MIGRATE", "true") == "true",
+		Storage:        getEnv("STORAGE", store.StorageMongo),
+		RedisURI:       getEnv("REDIS_URI", "localhost:6379"),
+		SolaanRPCURL:   solanaRPCURL,
+		SolanaWSURL:    solanaWSURL,
+		RPCConcurrency: rpcConcurrency,
+		RequestTimeout: requestTimeout,
+		Network:        getEnv("SOLANA_NETWORK", types.NetworkMainnet),
+		CacheTTL:       10 * time.Second,
+		RateLimit:      10,
+		AdminAPIKey:    getEnv("ADMIN_API_KEY", ""),
+		DocsUI:         getEnv("DOCS_UI", "false") == "true",
+
+		ProxyHeader:    getEnv("PROXY_HEADER", ""),
+		TrustedProxies: splitList(getEnv("TRUSTED_PROXIES", "")),
+
+		SigningEncryptionKey: getEnv("SIGNING_ENCRYPTION_KEY", ""),
+		SignatureMaxSkew:     5 * time.Minute,
+
+		JWKSFile:     getEnv("JWKS_FILE", ""),
+		JWKSURL:      getEnv("JWKS_URL", ""),
+		JWTIssuer:    getEnv("JWT_ISSUER", ""),
+		JWTAudience:  getEnv("JWT_AUDIENCE", ""),
+		JWTTierClaim: getEnv("JWT_TIER_CLAIM", "nova_tier"),
+
+		RateLimitTiers: DefaultRateLimitTiers(),
+	}, nil
+}
+
+// Validate reports every problem with cfg at once rather than stopping at
+// the first one.
+func Validate(cfg *types.Config) error {
+	var errs []error
+
+	if port, err := strconv.Atoi(cfg.Port); err != nil || port <= 0 || port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT must be a valid port number, got %q", cfg.Port))
+	}
+
+	if port, err := strconv.Atoi(cfg.GRPCPort); err != nil || port <= 0 || port > 65535 {
+		errs = append(errs, fmt.Errorf("GRPC_PORT must be a valid port number, got %q", cfg.GRPCPort))
+	}
+
+	if !strings.HasPrefix(cfg.MongoURI, "mongodb://") && !strings.HasPrefix(cfg.MongoURI, "mongodb+srv://") {
+		errs = append(errs, errors.New("MONGO_URI must start with mongodb:// or mongodb+srv://"))
+	}
+
+	if cfg.MongoDatabase == "" || strings.ContainsAny(cfg.MongoDatabase, `/\. "$`) {
+		errs = append(errs, fmt.Errorf("MONGO_DATABASE %q is not a valid database name", cfg.MongoDatabase))
+	}
+
+	if cfg.Storage != store.StorageMongo && cfg.Storage != store.StorageMemory {
+		errs = append(errs, fmt.Errorf("STORAGE must be mongo or memory, got %q", cfg.Storage))
+	}
+
+	if cfg.RedisURI == "" {
+		errs = append(errs, errors.New("REDIS_URI is required"))
+	}
+
+	if !strings.HasPrefix(cfg.SolanaWSURL, "ws://") && !strings.HasPrefix(cfg.SolanaWSURL, "wss://") {
+		errs = append(errs, errors.New("SOLANA_WS_URL must start with ws:// or wss://"))
+	}
+
+	if strings.ContainsAny(cfg.ProxyHeader, " \t:,") {
+		errs = append(errs, fmt.Errorf("PROXY_HEADER %q is not a valid header name", cfg.ProxyHeader))
+	}
+
+	if cfg.ProxyHeader != "" && len(cfg.TrustedProxies) == 0 {
+		errs = append(errs, errors.New("TRUSTED_PROXIES is required when PROXY_HEADER is set"))
+	}
+
+	if cfg.ProxyHeader == "" && len(cfg.TrustedProxies) > 0 {
+		errs = append(errs, errors.New("PROXY_HEADER is required when TRUSTED_PROXIES is set"))
+	}
+
+	for _, proxy := range cfg.TrustedProxies {
+		if !types.IsValidIPRule(proxy) {
+			errs = append(errs, fmt.Errorf("TRUSTED_PROXIES entry %q is not an IP address or CIDR range", proxy))
+		}
+	}
+
+	if cfg.RPCConcurrency <= 0 {
+		errs = append(errs, errors.New("RPC_CONCURRENCY must be at least 1"))
+	}
+
+	if cfg.RequestTimeout <= 0 {
+		errs = append(errs, errors.New("REQUEST_TIMEOUT must be positive"))
+	}
+
+	if !types.IsValidNetwork(cfg.Network) {
+		errs = append(errs, fmt.Errorf("SOLANA_NETWORK %q is not a known network", cfg.Network))
+	}
+
+	if cfg.SigningEncryptionKey != "" {
+		if key, err := hex.DecodeString(cfg.SigningEncryptionKey); err != nil || len(key) != 32 {
+			errs = append(errs, errors.New("SIGNING_ENCRYPTION_KEY must be 32 bytes encoded as hex"))
+		}
+	}
+
+	if cfg.JWKSFile != "" && cfg.JWKSURL != "" {
+		errs = append(errs, errors.New("only one of JWKS_FILE and JWKS_URL may be set"))
+	}
+
+	for tier, limit := range cfg.RateLimitTiers {
+		if limit.RequestsPerMinute <= 0 || limit.Burst <= 0 || limit.MaxWallets <= 0 {
+			errs = append(errs, fmt.Errorf("rate limit tier %q must have a positive rate, burst and wallet limit", tier))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func DefaultRateLimitTiers() map[string]types.RateLimitTier {
+	return map[string]types.RateLimitTier{
+		types.TierFree:       {RequestsPerMinute: 120, Burst: 20, MaxWallets: 100},
+		types.TierPro:        {RequestsPerMinute: 1200, Burst: 100, MaxWallets: 1000},
+		types.TierEnterprise: {RequestsPerMinute: 12000, Burst: 1000, MaxWallets: 5000},
+	}
+}
+
+// splitList splits a comma-separated variable, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
 }
 
 func getEnv(key, defaultValue string) string {
@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"strings"
 	"time"
@@ -15,13 +16,42 @@ import (
 )
 
 func New(cfg *types.Config) *types.Database {
-	db := &types.Database{}
-	initMongoDB(db, cfg)
-	initRedis(db, cfg)
+	db, err := Connect(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
 	return db
 }
 
-func initMongoDB(db *types.Database, cfg *types.Config) {
+func Connect(cfg *types.Config) (*types.Database, error) {
+	db := &types.Database{}
+
+	if err := initMongoDB(db, cfg); err != nil {
+		return nil, err
+	}
+
+	if err := initRedis(db, cfg); err != nil {
+		Close(db)
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func Close(db *types.Database) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if db.MongoDB != nil {
+		db.MongoDB.Disconnect(ctx)
+	}
+
+	if db.Redis != nil {
+		db.Redis.Close()
+	}
+}
+
+func initMongoDB(db *types.Database, cfg *types.Config) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -38,18 +68,18 @@ func initMongoDB(db *types.Database, cfg *types.Config) {
 	var err error
 	db.MongoDB, err = mongo.Connect(clientOptions)
 	if err != nil {
-		log.Fatal("Error connecting to MongoDB:", err)
-		return
+		return fmt.Errorf("error connecting to MongoDB: %w", err)
 	}
 
 	err = db.MongoDB.Ping(ctx, readpref.Primary())
 	if err != nil {
-		log.Fatal("Error pinging MongoDB:", err)
-		return
+		return fmt.Errorf("error pinging MongoDB: %w", err)
 	}
+
+	return nil
 }
 
-func initRedis(db *types.Database, cfg *types.Config) {
+func initRedis(db *types.Database, cfg *types.Config) error {
 	addr := cfg.RedisURI
 	if after, ok := strings.CutPrefix(addr, "redis://"); ok {
 		addr = after
@@ -60,7 +90,8 @@ func initRedis(db *types.Database, cfg *types.Config) {
 	})
 
 	if err := db.Redis.Ping(context.Background()).Err(); err != nil {
-		log.Fatal("Error connecting to Redis:", err)
-		return
+		return fmt.Errorf("error connecting to Redis: %w", err)
 	}
+
+	return nil
 }
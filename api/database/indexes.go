@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"nova/api/types"
+)
+
+const (
+	// Expired keys are kept for a while so clients get a clear "expired"
+	// error instead of "invalid", then removed by MongoDB.
+	expiredKeyRetention = 30 * 24 * time.Hour
+	usageRetention      = 90 * 24 * time.Hour
+	// Jobs and their chunks share a creation time, so results are never
+	// removed before the job that points at them.
+	jobRetention = 7 * 24 * time.Hour
+)
+
+type CollectionIndex struct {
+	Collection string
+	Model      mongo.IndexModel
+}
+
+type IndexResult struct {
+	Collection string `json:"collection"`
+	Name       string `json:"name"`
+}
+
+func exists(field string) bson.M {
+	return bson.M{field: bson.M{"$exists": true}}
+}
+
+func createIndexes(ctx context.Context, database *mongo.Database, indexes []CollectionIndex) ([]IndexResult, error) {
+	var results []IndexResult
+
+	for _, index := range indexes {
+		name, err := database.Collection(index.Collection).Indexes().CreateOne(ctx, index.Model)
+		if err != nil {
+			return results, fmt.Errorf("failed to create index on %s: %w", index.Collection, err)
+		}
+
+		results = append(results, IndexResult{Collection: index.Collection, Name: name})
+	}
+
+	return results, nil
+}
+
+// EnsureIndexes creates every index declared by the migrations, whether or
+// not they have been recorded as applied. Existing indexes with the same
+// definition are left untouched, so it is safe to run repeatedly.
+func EnsureIndexes(ctx context.Context, cfg *types.Config, db *types.Database) ([]IndexResult, error) {
+	database := db.MongoDB.Database(cfg.MongoDatabase)
+
+	var results []IndexResult
+	for _, migration := range migrations {
+		created, err := createIndexes(ctx, database, migration.Indexes)
+		results = append(results, created...)
+		if err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,294 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"nova/api/types"
+)
+
+const (
+	migrationsCollection = "schema_migrations"
+	migrationLockID      = "lock"
+	migrationLockLease   = 5 * time.Minute
+	migrationLockPoll    = 500 * time.Millisecond
+)
+
+var ErrMigrationLocked = errors.New("migrations are locked by another instance")
+
+// Migration is applied at most once per database. Indexes are created
+// before Up runs; either may be empty.
+type Migration struct {
+	Version int
+	Name    string
+	Indexes []CollectionIndex
+	Up      func(ctx context.Context, database *mongo.Database) error
+}
+
+// Migrations must only ever be appended to. Changing an applied migration
+// has no effect on databases that already recorded it.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "api_keys_lookup_indexes",
+		Indexes: []CollectionIndex{
+			{"api_keys", mongo.IndexModel{
+				// Plaintext keys have no key_id until they are migrated.
+				Keys:    bson.D{{Key: "key_id", Value: 1}},
+				Options: options.Index().SetName("key_id_unique").SetUnique(true).SetPartialFilterExpression(exists("key_id")),
+			}},
+			{"api_keys", mongo.IndexModel{
+				Keys:    bson.D{{Key: "key_hash", Value: 1}},
+				Options: options.Index().SetName("key_hash_unique").SetUnique(true).SetPartialFilterExpression(exists("key_hash")),
+			}},
+			{"api_keys", mongo.IndexModel{
+				Keys:    bson.D{{Key: "key", Value: 1}},
+				Options: options.Index().SetName("legacy_plaintext_key").SetPartialFilterExpression(exists("key")),
+			}},
+			{"api_keys", mongo.IndexModel{
+				Keys:    bson.D{{Key: "created_at", Value: -1}},
+				Options: options.Index().SetName("created_at"),
+			}},
+		},
+	},
+	{
+		Version: 2,
+		Name:    "api_keys_revocation_indexes",
+		Indexes: []CollectionIndex{
+			{"api_keys", mongo.IndexModel{
+				Keys:    bson.D{{Key: "revoked_at", Value: 1}},
+				Options: options.Index().SetName("revoked_at").SetSparse(true),
+			}},
+			{"api_keys", mongo.IndexModel{
+				Keys:    bson.D{{Key: "rotated_at", Value: 1}},
+				Options: options.Index().SetName("rotated_at").SetSparse(true),
+			}},
+		},
+	},
+	{
+		Version: 3,
+		Name:    "api_keys_expiry_ttl",
+		Indexes: []CollectionIndex{
+			{"api_keys", mongo.IndexModel{
+				Keys:    bson.D{{Key: "expires_at", Value: 1}},
+				Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(int32(expiredKeyRetention.Seconds())),
+			}},
+		},
+	},
+	{
+		Version: 4,
+		Name:    "usage_indexes",
+		Indexes: []CollectionIndex{
+			{"usage", mongo.IndexModel{
+				Keys:    bson.D{{Key: "key_id", Value: 1}, {Key: "minute", Value: 1}},
+				Options: options.Index().SetName("key_id_minute_unique").SetUnique(true),
+			}},
+			{"usage", mongo.IndexModel{
+				Keys:    bson.D{{Key: "minute", Value: 1}},
+				Options: options.Index().SetName("minute_ttl").SetExpireAfterSeconds(int32(usageRetention.Seconds())),
+			}},
+		},
+	},
+	{
+		Version: 5,
+		Name:    "balance_jobs_indexes",
+		Indexes: []CollectionIndex{
+			{"balance_jobs", mongo.IndexModel{
+				Keys:    bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+				Options: options.Index().SetName("status_created_at"),
+			}},
+			{"balance_jobs", mongo.IndexModel{
+				Keys:    bson.D{{Key: "created_at", Value: 1}},
+				Options: options.Index().SetName("created_at_ttl").SetExpireAfterSeconds(int32(jobRetention.Seconds())),
+			}},
+			{"balance_job_chunks", mongo.IndexModel{
+				Keys:    bson.D{{Key: "job_id", Value: 1}, {Key: "index", Value: 1}},
+				Options: options.Index().SetName("job_id_index_unique").SetUnique(true),
+			}},
+			{"balance_job_chunks", mongo.IndexModel{
+				Keys:    bson.D{{Key: "created_at", Value: 1}},
+				Options: options.Index().SetName("created_at_ttl").SetExpireAfterSeconds(int32(jobRetention.Seconds())),
+			}},
+		},
+	},
+}
+
+type MigrationRecord struct {
+	Version   int        `bson:"_id" json:"version"`
+	Name      string     `bson:"name" json:"name"`
+	AppliedAt *time.Time `bson:"applied_at,omitempty" json:"applied_at,omitempty"`
+}
+
+// Migrate applies every pending migration in version order and returns the
+// ones it applied. Only one instance migrates at a time: the others wait for
+// the lock and then find nothing left to do.
+func Migrate(ctx context.Context, cfg *types.Config, db *types.Database) ([]MigrationRecord, error) {
+	database := db.MongoDB.Database(cfg.MongoDatabase)
+	collection := database.Collection(migrationsCollection)
+
+	owner := lockOwner()
+	if err := acquireMigrationLock(ctx, collection, owner); err != nil {
+		return nil, err
+	}
+	defer releaseMigrationLock(collection, owner)
+
+	applied, err := appliedMigrations(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []MigrationRecord
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		// Long migrations must not outlive the lease.
+		if err := extendMigrationLock(ctx, collection, owner); err != nil {
+			return ran, err
+		}
+
+		if _, err := createIndexes(ctx, database, migration.Indexes); err != nil {
+			return ran, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+
+		if migration.Up != nil {
+			if err := migration.Up(ctx, database); err != nil {
+				return ran, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+			}
+		}
+
+		now := time.Now().UTC()
+		record := MigrationRecord{Version: migration.Version, Name: migration.Name, AppliedAt: &now}
+		if _, err := collection.InsertOne(ctx, record); err != nil {
+			return ran, fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		}
+
+		log.Printf("Applied migration %d (%s)", migration.Version, migration.Name)
+		ran = append(ran, record)
+	}
+
+	return ran, nil
+}
+
+// MigrationStatus lists every known migration; pending ones have no
+// AppliedAt.
+func MigrationStatus(ctx context.Context, cfg *types.Config, db *types.Database) ([]MigrationRecord, error) {
+	collection := db.MongoDB.Database(cfg.MongoDatabase).Collection(migrationsCollection)
+
+	applied, err := appliedMigrations(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationRecord, 0, len(migrations))
+	for _, migration := range migrations {
+		record := MigrationRecord{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			record.AppliedAt = &appliedAt
+		}
+		status = append(status, record)
+	}
+
+	return status, nil
+}
+
+func appliedMigrations(ctx context.Context, collection *mongo.Collection) (map[int]time.Time, error) {
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$type": "number"}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	var records []MigrationRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode applied migrations: %w", err)
+	}
+
+	applied := make(map[int]time.Time, len(records))
+	for _, record := range records {
+		if record.AppliedAt != nil {
+			applied[record.Version] = *record.AppliedAt
+		}
+	}
+
+	return applied, nil
+}
+
+// acquireMigrationLock waits until it holds the lock document or ctx is done.
+// A lock whose lease has expired is assumed to belong to a crashed instance
+// and is taken over.
+func acquireMigrationLock(ctx context.Context, collection *mongo.Collection, owner string) error {
+	for {
+		err := tryMigrationLock(ctx, collection, owner)
+		if err == nil {
+			return nil
+		}
+
+		if !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ErrMigrationLocked
+		case <-time.After(migrationLockPoll):
+		}
+	}
+}
+
+func tryMigrationLock(ctx context.Context, collection *mongo.Collection, owner string) error {
+	now := time.Now()
+
+	// When another owner holds an unexpired lease the filter matches nothing
+	// and the upsert collides with the existing _id.
+	_, err := collection.UpdateOne(ctx,
+		bson.M{
+			"_id": migrationLockID,
+			"$or": bson.A{
+				bson.M{"owner": owner},
+				bson.M{"locked_until": bson.M{"$lt": now}},
+			},
+		},
+		bson.M{"$set": bson.M{"owner": owner, "locked_until": now.Add(migrationLockLease)}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	return err
+}
+
+func extendMigrationLock(ctx context.Context, collection *mongo.Collection, owner string) error {
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": migrationLockID, "owner": owner},
+		bson.M{"$set": bson.M{"locked_until": time.Now().Add(migrationLockLease)}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to extend migration lock: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return errors.New("lost the migration lock")
+	}
+
+	return nil
+}
+
+func releaseMigrationLock(collection *mongo.Collection, owner string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := collection.DeleteOne(ctx, bson.M{"_id": migrationLockID, "owner": owner}); err != nil {
+		log.Printf("Failed to release migration lock: %v", err)
+	}
+}
+
+func lockOwner() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d:%d", hostname, os.Getpid(), time.Now().UnixNano())
+}
@@ -0,0 +1,80 @@
+package graphqlapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"nova/api/apierror"
+	"nova/api/types"
+)
+
+// Costs in rate-limit credits, as documented on the Query type.
+const (
+	costRequest       = 1
+	accountsPerCredit = 100
+	costTokenAccounts = 2
+	costTransactions  = 1
+	costStakeAccounts = 3
+)
+
+// stateFields are the Account fields read with getMultipleAccounts.
+var stateFields = []string{"lamports", "balance", "owner", "executable", "exists"}
+
+// estimate prices a query. The query is run once with an estimate in its
+// context; the root resolvers then add up the cost of their selections
+// instead of resolving anything.
+type estimate struct {
+	mu       sync.Mutex
+	accounts int
+	cost     int
+}
+
+// add prices n accounts with the selection of the current resolver.
+// Aliases of a field count once, so execution enforces the price as well.
+func (e *estimate) add(ctx context.Context, n int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if selectsState(ctx) {
+		e.accounts += n
+	}
+	if graphql.HasSelectedField(ctx, "tokenAccounts") {
+		e.cost += n * costTokenAccounts
+	}
+	if graphql.HasSelectedField(ctx, "transactions") {
+		e.cost += n * costTransactions
+	}
+	if graphql.HasSelectedField(ctx, "stakeAccounts") {
+		e.cost += n * costStakeAccounts
+	}
+}
+
+func (e *estimate) total() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return costRequest + e.cost + stateCalls(e.accounts)
+}
+
+func selectsState(ctx context.Context) bool {
+	for _, field := range stateFields {
+		if graphql.HasSelectedField(ctx, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// stateCalls is the number of getMultipleAccounts calls for n accounts.
+func stateCalls(n int) int {
+	return (n + accountsPerCredit - 1) / accountsPerCredit
+}
+
+func tooComplex(cost, max int, tierName string) *apierror.Error {
+	message := fmt.Sprintf("Query costs %d credits, more than the %d the %s tier allows", cost, max, tierName)
+	return apierror.New(http.StatusBadRequest, types.CodeQueryTooComplex, message).
+		With("cost", cost).With("max", max)
+}
@@ -0,0 +1,139 @@
+// Package graphqlapi serves /graphql, which reads several kinds of account
+// data in one request. Queries are priced in rate-limit credits before they
+// run, account reads of a query are batched into getMultipleAccounts calls,
+// and queries can be sent by hash as automatic persisted queries.
+package graphqlapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/graph-gophers/graphql-go"
+
+	"nova/api/apierror"
+	"nova/api/middleware"
+	"nova/api/services"
+	"nova/api/types"
+)
+
+//go:embed schema.graphql
+var schema string
+
+const (
+	maxDepth       = 8
+	maxQueryLength = 16 * 1024
+)
+
+type Deps struct {
+	Config   *types.Config
+	Solana   *services.SolanaService
+	Limiters *middleware.RateLimiters
+	Now      func() time.Time
+}
+
+type Server struct {
+	Deps
+	schema    *graphql.Schema
+	persisted *persistedQueries
+}
+
+func NewServer(deps Deps) *Server {
+	s := &Server{Deps: deps, persisted: newPersistedQueries()}
+	s.schema = graphql.MustParseSchema(schema, &resolver{s},
+		graphql.MaxDepth(maxDepth),
+		graphql.MaxQueryLength(maxQueryLength),
+	)
+	return s
+}
+
+// Handle serves a GET or POST /graphql request. It runs after the
+// middleware chain, which has taken the first credit of the query.
+func (s *Server) Handle(c *fiber.Ctx) error {
+	req, parseErr := parseRequest(c)
+	if parseErr != nil {
+		return parseErr.Send(c)
+	}
+
+	text, persistedErr := s.persisted.resolve(&req)
+	if persistedErr != nil {
+		return persistedErr.Send(c)
+	}
+
+	principal := c.Locals("principal").(*types.Principal)
+	tierName := principal.Tier
+	tier, ok := s.Config.RateLimitTiers[tierName]
+	if !ok {
+		tierName = types.TierFree
+		tier = s.Config.RateLimitTiers[tierName]
+	}
+
+	priced := &query{principal: principal, tierName: tierName, tier: tier, estimate: &estimate{}}
+	resp := s.schema.Exec(context.WithValue(c.UserContext(), queryKey{}, priced), text, req.OperationName, req.Variables)
+	if len(resp.Errors) > 0 {
+		return c.JSON(resp)
+	}
+	s.persisted.store(&req)
+
+	cost := priced.estimate.total()
+	if cost > tier.Burst {
+		return tooComplex(cost, tier.Burst, tierName).Send(c)
+	}
+	if cost > costRequest {
+		limit, limitErr := s.Limiters.SpendPrincipal(principal, s.Config.RateLimitTiers, s.Now(), cost-costRequest)
+		middleware.SetRateLimitHeaders(c, limit)
+		if limitErr != nil {
+			return limitErr.With("cost", cost).Send(c)
+		}
+	}
+
+	q := &query{principal: principal, tierName: tierName, tier: tier, budget: cost, spent: costRequest}
+	q.accounts = newAccountLoader(s.fetchAccounts(q))
+
+	resp = s.schema.Exec(context.WithValue(c.UserContext(), queryKey{}, q), text, req.OperationName, req.Variables)
+	resp.Extensions = map[string]interface{}{"cost": cost}
+
+	c.Locals("usage", q.counters())
+	return c.JSON(resp)
+}
+
+// fetchAccounts reads a batch of accounts for q, paying for it from what
+// the query was priced at.
+func (s *Server) fetchAccounts(q *query) func(ctx context.Context, addresses []string) ([]types.AccountInfo, error) {
+	return func(ctx context.Context, addresses []string) ([]types.AccountInfo, error) {
+		if err := q.spend(stateCalls(len(addresses)), 0); err != nil {
+			return nil, err
+		}
+		infos, calls := s.Solana.GetAccounts(ctx, q.principal.KeyID, addresses)
+		q.spend(0, calls)
+		return infos, nil
+	}
+}
+
+func parseRequest(c *fiber.Ctx) (types.GraphQLRequest, *apierror.Error) {
+	var req types.GraphQLRequest
+	if c.Method() == fiber.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				return req, apierror.New(http.StatusBadRequest, types.CodeInvalidRequest, "variables must be a JSON object")
+			}
+		}
+		if extensions := c.Query("extensions"); extensions != "" {
+			if err := json.Unmarshal([]byte(extensions), &req.Extensions); err != nil {
+				return req, apierror.New(http.StatusBadRequest, types.CodeInvalidRequest, "extensions must be a JSON object")
+			}
+		}
+	} else if err := c.BodyParser(&req); err != nil {
+		return req, apierror.New(http.StatusBadRequest, types.CodeInvalidRequest, "Invalid request body")
+	}
+
+	if req.Query == "" && (req.Extensions == nil || req.Extensions.PersistedQuery == nil) {
+		return req, apierror.New(http.StatusBadRequest, types.CodeInvalidRequest, "No query provided")
+	}
+	return req, nil
+}
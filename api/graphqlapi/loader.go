@@ -0,0 +1,92 @@
+package graphqlapi
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"nova/api/types"
+)
+
+// batchWindow is how long a batch collects addresses after the first one.
+// Resolvers of one query run concurrently, so they all arrive within it.
+const batchWindow = 2 * time.Millisecond
+
+// accountLoader batches the account reads of one query, so that any number
+// of accounts read together cost one getMultipleAccounts call per 100.
+type accountLoader struct {
+	fetch func(ctx context.Context, addresses []string) ([]types.AccountInfo, error)
+
+	mu      sync.Mutex
+	pending *accountBatch
+	loaded  map[string]*accountBatch
+}
+
+type accountBatch struct {
+	addresses []string
+	results   map[string]types.AccountInfo
+	err       error
+	done      chan struct{}
+}
+
+func newAccountLoader(fetch func(ctx context.Context, addresses []string) ([]types.AccountInfo, error)) *accountLoader {
+	return &accountLoader{fetch: fetch, loaded: make(map[string]*accountBatch)}
+}
+
+// Prime adds addresses to the next batch without waiting for it, so that a
+// list of accounts is read at once rather than as its resolvers get to it.
+func (l *accountLoader) Prime(ctx context.Context, addresses ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, address := range addresses {
+		l.enqueue(ctx, address)
+	}
+}
+
+func (l *accountLoader) Load(ctx context.Context, address string) (types.AccountInfo, error) {
+	l.mu.Lock()
+	batch := l.enqueue(ctx, address)
+	l.mu.Unlock()
+
+	select {
+	case <-batch.done:
+		return batch.results[address], batch.err
+	case <-ctx.Done():
+		return types.AccountInfo{}, ctx.Err()
+	}
+}
+
+// enqueue returns the batch that reads address, starting a batch if none
+// is pending. l.mu must be held.
+func (l *accountLoader) enqueue(ctx context.Context, address string) *accountBatch {
+	if batch, ok := l.loaded[address]; ok {
+		return batch
+	}
+
+	if l.pending == nil {
+		batch := &accountBatch{done: make(chan struct{})}
+		l.pending = batch
+		time.AfterFunc(batchWindow, func() { l.dispatch(ctx, batch) })
+	}
+
+	l.pending.addresses = append(l.pending.addresses, address)
+	l.loaded[address] = l.pending
+	return l.pending
+}
+
+func (l *accountLoader) dispatch(ctx context.Context, batch *accountBatch) {
+	l.mu.Lock()
+	if l.pending == batch {
+		l.pending = nil
+	}
+	l.mu.Unlock()
+
+	infos, err := l.fetch(ctx, batch.addresses)
+	batch.err = err
+	batch.results = make(map[string]types.AccountInfo, len(infos))
+	for _, info := range infos {
+		batch.results[info.Address] = info
+	}
+	close(batch.done)
+}
@@ -0,0 +1,80 @@
+package graphqlapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+
+	"nova/api/apierror"
+	"nova/api/types"
+)
+
+// maxPersistedQueries bounds the store. The oldest query goes first; its
+// clients get persisted_query_not_found and register it again.
+const maxPersistedQueries = 1000
+
+// persistedQueries holds the queries registered through automatic persisted
+// queries, by the hex SHA-256 of their text.
+type persistedQueries struct {
+	mu      sync.Mutex
+	queries map[string]string
+	order   []string
+}
+
+func newPersistedQueries() *persistedQueries {
+	return &persistedQueries{queries: make(map[string]string)}
+}
+
+// resolve returns the text of the query req asks for. A request with both
+// a hash and a query registers the query once it is known to run, through
+// store.
+func (p *persistedQueries) resolve(req *types.GraphQLRequest) (string, *apierror.Error) {
+	if req.Extensions == nil || req.Extensions.PersistedQuery == nil {
+		return req.Query, nil
+	}
+
+	persisted := req.Extensions.PersistedQuery
+	if persisted.Version != 1 {
+		return "", apierror.New(http.StatusBadRequest, types.CodeInvalidRequest, "Unsupported persisted query version")
+	}
+
+	hash := strings.ToLower(persisted.SHA256Hash)
+	if req.Query != "" {
+		sum := sha256.Sum256([]byte(req.Query))
+		if hex.EncodeToString(sum[:]) != hash {
+			return "", apierror.New(http.StatusBadRequest, types.CodeInvalidRequest, "sha256Hash does not match the query")
+		}
+		return req.Query, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	query, ok := p.queries[hash]
+	if !ok {
+		return "", apierror.New(http.StatusOK, types.CodePersistedQueryNotFound, "PersistedQueryNotFound")
+	}
+	return query, nil
+}
+
+func (p *persistedQueries) store(req *types.GraphQLRequest) {
+	if req.Extensions == nil || req.Extensions.PersistedQuery == nil || req.Query == "" {
+		return
+	}
+	hash := strings.ToLower(req.Extensions.PersistedQuery.SHA256Hash)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.queries[hash]; ok {
+		return
+	}
+	if len(p.order) == maxPersistedQueries {
+		delete(p.queries, p.order[0])
+		p.order = p.order[1:]
+	}
+	p.queries[hash] = req.Query
+	p.order = append(p.order, hash)
+}
@@ -0,0 +1,354 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"nova/api/apierror"
+	"nova/api/middleware"
+	"nova/api/services"
+	"nova/api/types"
+)
+
+const maxTransactions = 100
+
+// fieldMessages replace the wording of apierror's public messages, which
+// speak of balances, for fields that are not.
+var fieldMessages = map[string]string{
+	types.CodeUpstreamError:   "The Solana RPC could not return this field",
+	types.CodeUpstreamTimeout: "The field was not fetched before the request deadline",
+}
+
+// query is the state of one request. While the query is priced, estimate
+// is set and nothing is resolved.
+type query struct {
+	principal *types.Principal
+	tierName  string
+	tier      types.RateLimitTier
+	estimate  *estimate
+
+	accounts *accountLoader
+
+	mu     sync.Mutex
+	budget int
+	spent  int
+	usage  types.UsageCounters
+}
+
+type queryKey struct{}
+
+func queryFrom(ctx context.Context) *query {
+	return ctx.Value(queryKey{}).(*query)
+}
+
+// spend takes credits from what the query was priced at. It only fails for
+// queries that do more than their price says, such as through aliases.
+func (q *query) spend(credits, rpcCalls int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.spent+credits > q.budget {
+		return tooComplex(q.spent+credits, q.budget, q.tierName)
+	}
+	q.spent += credits
+	q.usage.RPCCalls += int64(rpcCalls)
+	return nil
+}
+
+func (q *query) countWallets(n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.usage.Wallets += int64(n)
+}
+
+func (q *query) counters() types.UsageCounters {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.usage
+}
+
+// fieldError is the error of a field whose lookup failed. Like per-wallet
+// errors over HTTP it comes with a 200, and upstream errors are replaced
+// with a public message.
+func fieldError(err error) error {
+	code := services.ErrorCode(err)
+	if code == types.CodeInvalidRequest {
+		return apierror.New(http.StatusOK, code, err.Error())
+	}
+	return resultError(err.Error(), code)
+}
+
+// resultError is fieldError for a lookup that reported its error on its
+// result.
+func resultError(message, code string) error {
+	sanitized := apierror.Sanitized(message, code)
+	if public, ok := fieldMessages[sanitized.Code]; ok {
+		sanitized.Message = public
+	}
+	return apierror.New(http.StatusOK, sanitized.Code, sanitized.Message)
+}
+
+type resolver struct {
+	s *Server
+}
+
+func (r *resolver) Account(ctx context.Context, args struct{ Address string }) (*accountResolver, error) {
+	accounts, err := r.accounts(ctx, []string{args.Address})
+	if err != nil || accounts == nil {
+		return nil, err
+	}
+	return accounts[0], nil
+}
+
+func (r *resolver) Accounts(ctx context.Context, args struct{ Addresses []string }) (*[]*accountResolver, error) {
+	q := queryFrom(ctx)
+	if len(args.Addresses) > q.tier.MaxWallets {
+		message := fmt.Sprintf("Too many wallets (max %d for the %s tier)", q.tier.MaxWallets, q.tierName)
+		return nil, apierror.New(http.StatusBadRequest, types.CodeTooManyWallets, message).
+			With("max", q.tier.MaxWallets).With("tier", q.tierName)
+	}
+
+	accounts, err := r.accounts(ctx, args.Addresses)
+	if err != nil || accounts == nil {
+		return nil, err
+	}
+	return &accounts, nil
+}
+
+// accounts returns nil while the query is priced.
+func (r *resolver) accounts(ctx context.Context, addresses []string) ([]*accountResolver, error) {
+	q := queryFrom(ctx)
+	if q.estimate != nil {
+		q.estimate.add(ctx, len(addresses))
+		return nil, nil
+	}
+
+	q.countWallets(len(addresses))
+	if selectsState(ctx) {
+		q.accounts.Prime(ctx, addresses...)
+	}
+
+	accounts := make([]*accountResolver, len(addresses))
+	for i, address := range addresses {
+		accounts[i] = &accountResolver{s: r.s, q: q, address: address}
+	}
+	return accounts, nil
+}
+
+type accountResolver struct {
+	s       *Server
+	q       *query
+	address string
+}
+
+func (a *accountResolver) Address() string {
+	return a.address
+}
+
+func (a *accountResolver) state(ctx context.Context) (types.AccountInfo, error) {
+	info, err := a.q.accounts.Load(ctx, a.address)
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return info, fieldError(services.ErrLookupTimeout)
+	case err != nil:
+		return info, err
+	case info.Error != "":
+		return info, resultError(info.Error, info.ErrorCode)
+	}
+	return info, nil
+}
+
+func (a *accountResolver) Lamports(ctx context.Context) (*string, error) {
+	info, err := a.state(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lamports := strconv.FormatUint(info.Lamports, 10)
+	return &lamports, nil
+}
+
+func (a *accountResolver) Balance(ctx context.Context) (*float64, error) {
+	info, err := a.state(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &info.Balance, nil
+}
+
+func (a *accountResolver) Owner(ctx context.Context) (*string, error) {
+	info, err := a.state(ctx)
+	if err != nil || !info.Exists {
+		return nil, err
+	}
+	return &info.Owner, nil
+}
+
+func (a *accountResolver) Executable(ctx context.Context) (*bool, error) {
+	info, err := a.state(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &info.Executable, nil
+}
+
+func (a *accountResolver) Exists(ctx context.Context) (*bool, error) {
+	info, err := a.state(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &info.Exists, nil
+}
+
+func (a *accountResolver) TokenAccounts(ctx context.Context, args struct{ Mint *string }) (*[]*tokenAccountResolver, error) {
+	if scopeErr := middleware.CheckScope(a.q.principal, types.ScopeTokensRead); scopeErr != nil {
+		return nil, scopeErr
+	}
+	if err := a.q.spend(costTokenAccounts, 0); err != nil {
+		return nil, err
+	}
+
+	mint := ""
+	if args.Mint != nil {
+		mint = *args.Mint
+	}
+	results, err := a.s.Solana.GetTokenBalances(ctx, a.q.principal.KeyID, []string{a.address}, mint)
+	if err != nil {
+		return nil, fieldError(err)
+	}
+
+	result := results[0]
+	a.q.spend(0, result.RPCCalls)
+	if result.Error != "" {
+		return nil, resultError(result.Error, result.ErrorCode)
+	}
+
+	tokens := make([]*tokenAccountResolver, len(result.Tokens))
+	for i := range result.Tokens {
+		tokens[i] = &tokenAccountResolver{result.Tokens[i]}
+	}
+	return &tokens, nil
+}
+
+func (a *accountResolver) Transactions(ctx context.Context, args struct {
+	Limit  int32
+	Before *string
+}) (*[]*transactionResolver, error) {
+	if args.Limit < 1 || args.Limit > maxTransactions {
+		return nil, apierror.New(http.StatusOK, types.CodeInvalidRequest, fmt.Sprintf("limit must be between 1 and %d", maxTransactions))
+	}
+	if err := a.q.spend(costTransactions, 1); err != nil {
+		return nil, err
+	}
+
+	before := ""
+	if args.Before != nil {
+		before = *args.Before
+	}
+	signatures, err := a.s.Solana.GetSignatures(ctx, a.q.principal.KeyID, a.address, int(args.Limit), before)
+	if err != nil {
+		return nil, fieldError(err)
+	}
+
+	transactions := make([]*transactionResolver, len(signatures))
+	for i := range signatures {
+		transactions[i] = &transactionResolver{signatures[i]}
+	}
+	return &transactions, nil
+}
+
+func (a *accountResolver) StakeAccounts(ctx context.Context) (*[]*stakeAccountResolver, error) {
+	if err := a.q.spend(costStakeAccounts, 1); err != nil {
+		return nil, err
+	}
+
+	stakes, err := a.s.Solana.GetStakeAccounts(ctx, a.q.principal.KeyID, a.address)
+	if err != nil {
+		return nil, fieldError(err)
+	}
+
+	accounts := make([]*stakeAccountResolver, len(stakes))
+	for i := range stakes {
+		accounts[i] = &stakeAccountResolver{stakes[i]}
+	}
+	return &accounts, nil
+}
+
+type tokenAccountResolver struct {
+	token types.TokenBalance
+}
+
+func (t *tokenAccountResolver) Address() string   { return t.token.Account }
+func (t *tokenAccountResolver) Mint() string      { return t.token.Mint }
+func (t *tokenAccountResolver) Amount() string    { return t.token.Amount }
+func (t *tokenAccountResolver) Decimals() int32   { return int32(t.token.Decimals) }
+func (t *tokenAccountResolver) UiAmount() float64 { return t.token.UIAmount }
+
+type transactionResolver struct {
+	signature types.Signature
+}
+
+func (t *transactionResolver) Signature() string { return t.signature.Signature }
+func (t *transactionResolver) Slot() string      { return strconv.FormatUint(t.signature.Slot, 10) }
+func (t *transactionResolver) Success() bool     { return !t.signature.Failed }
+
+func (t *transactionResolver) BlockTime() *string {
+	if t.signature.BlockTime == nil {
+		return nil
+	}
+	blockTime := t.signature.BlockTime.Format(time.RFC3339)
+	return &blockTime
+}
+
+func (t *transactionResolver) Memo() *string {
+	if t.signature.Memo == "" {
+		return nil
+	}
+	return &t.signature.Memo
+}
+
+type stakeAccountResolver struct {
+	stake types.StakeAccount
+}
+
+func (s *stakeAccountResolver) Address() string    { return s.stake.Address }
+func (s *stakeAccountResolver) Lamports() string   { return strconv.FormatUint(s.stake.Lamports, 10) }
+func (s *stakeAccountResolver) Balance() float64   { return s.stake.Balance }
+func (s *stakeAccountResolver) State() string      { return s.stake.State }
+func (s *stakeAccountResolver) Staker() string     { return s.stake.Staker }
+func (s *stakeAccountResolver) Withdrawer() string { return s.stake.Withdrawer }
+
+func (s *stakeAccountResolver) Voter() *string {
+	if s.stake.Voter == "" {
+		return nil
+	}
+	return &s.stake.Voter
+}
+
+func (s *stakeAccountResolver) DelegatedStake() *string {
+	if s.stake.Voter == "" {
+		return nil
+	}
+	stake := strconv.FormatUint(s.stake.DelegatedStake, 10)
+	return &stake
+}
+
+func (s *stakeAccountResolver) ActivationEpoch() *string {
+	return formatEpoch(s.stake.ActivationEpoch)
+}
+
+func (s *stakeAccountResolver) DeactivationEpoch() *string {
+	return formatEpoch(s.stake.DeactivationEpoch)
+}
+
+func formatEpoch(epoch *uint64) *string {
+	if epoch == nil {
+		return nil
+	}
+	formatted := strconv.FormatUint(*epoch, 10)
+	return &formatted
+}
@@ -0,0 +1,291 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"nova/api/apierror"
+	"nova/api/middleware"
+	"nova/api/types"
+)
+
+const (
+	metadataAPIKey        = "x-api-key"
+	metadataAuthorization = "authorization"
+	metadataOrigin        = "origin"
+	metadataLimit         = "x-ratelimit-limit"
+	metadataRemaining     = "x-ratelimit-remaining"
+	metadataRetryAfter    = "retry-after"
+)
+
+// metadataTimeout is the X-Request-Timeout header of the HTTP API.
+var metadataTimeout = strings.ToLower(middleware.RequestTimeoutHeader)
+
+// check is one step of the interceptor chain. It returns the context the
+// rest of the chain runs with and, optionally, a function to run once the
+// handler has returned.
+type check func(ctx context.Context, method string) (context.Context, func(), error)
+
+// call is the state of an authenticated call. Handlers fill in usage.
+type call struct {
+	principal *types.Principal
+	usage     types.UsageCounters
+}
+
+type callKey struct{}
+
+func callFrom(ctx context.Context) *call {
+	c, _ := ctx.Value(callKey{}).(*call)
+	return c
+}
+
+// checks are in the order of the fiber middleware chain.
+func (s *server) checks() []check {
+	return []check{s.timeout, s.authenticate, s.tierLimit, s.scope, s.quota}
+}
+
+func (s *server) unaryInterceptors() []grpc.UnaryServerInterceptor {
+	interceptors := []grpc.UnaryServerInterceptor{s.observeUnary, s.recoverUnary}
+	for _, c := range s.checks() {
+		interceptors = append(interceptors, c.unary())
+	}
+	return interceptors
+}
+
+func (s *server) streamInterceptors() []grpc.StreamServerInterceptor {
+	interceptors := []grpc.StreamServerInterceptor{s.observeStream, s.recoverStream}
+	for _, c := range s.checks() {
+		interceptors = append(interceptors, c.stream())
+	}
+	return interceptors
+}
+
+func (c check) unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, after, err := c(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		if after != nil {
+			defer after()
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (c check) stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, after, err := c(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		if after != nil {
+			defer after()
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// serverStream replaces the context of a stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *server) observeUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	s.Metrics.Observe(info.FullMethod, status.Code(err).String(), time.Since(start))
+	return resp, err
+}
+
+func (s *server) observeStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	s.Metrics.Observe(info.FullMethod, status.Code(err).String(), time.Since(start))
+	return err
+}
+
+func (s *server) recoverUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer s.recoverPanic(info.FullMethod, &err)
+	return handler(ctx, req)
+}
+
+func (s *server) recoverStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer s.recoverPanic(info.FullMethod, &err)
+	return handler(srv, ss)
+}
+
+func (s *server) recoverPanic(method string, err *error) {
+	if r := recover(); r != nil {
+		s.Logger.Printf("Panic in %s: %v\n%s", method, r, debug.Stack())
+		*err = status.Error(codes.Internal, "Internal server error")
+	}
+}
+
+// timeout caps the call's deadline like RequestTimeoutMiddleware. A
+// deadline set by the client applies as well.
+func (s *server) timeout(ctx context.Context, _ string) (context.Context, func(), error) {
+	timeout, timeoutErr := middleware.RequestTimeout(s.Config.RequestTimeout, incoming(ctx, metadataTimeout))
+	if timeoutErr != nil {
+		return nil, nil, toStatus(timeoutErr)
+	}
+	if timeout <= 0 {
+		return ctx, nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, nil
+}
+
+func (s *server) authenticate(ctx context.Context, _ string) (context.Context, func(), error) {
+	credentials := middleware.Credentials{
+		APIKey: incoming(ctx, metadataAPIKey),
+		IP:     s.clientIP(ctx),
+		Origin: incoming(ctx, metadataOrigin),
+	}
+	if scheme, token, ok := strings.Cut(incoming(ctx, metadataAuthorization), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		credentials.BearerToken, credentials.HasBearer = strings.TrimSpace(token), true
+	}
+
+	// Like RateLimitMiddleware, an IP with no failures left is rejected
+	// before any lookup, and only failed calls count towards its limit.
+	if limit, limitErr := s.Limiters.CheckClient(credentials.IP, s.Now()); limitErr != nil {
+		setRateLimit(ctx, limit)
+		return nil, nil, toStatus(limitErr)
+	}
+
+	principal, authErr := middleware.Authenticate(ctx, s.Config, s.Keys, s.JWT, credentials, s.Now())
+	if authErr != nil {
+		s.Limiters.AllowClient(credentials.IP, s.Now())
+		return nil, nil, toStatus(authErr)
+	}
+
+	return context.WithValue(ctx, callKey{}, &call{principal: principal}), nil, nil
+}
+
+func (s *server) tierLimit(ctx context.Context, _ string) (context.Context, func(), error) {
+	limit, limitErr := s.Limiters.AllowPrincipal(callFrom(ctx).principal, s.Config.RateLimitTiers, s.Now())
+	setRateLimit(ctx, limit)
+	if limitErr != nil {
+		return nil, nil, toStatus(limitErr)
+	}
+	return ctx, nil, nil
+}
+
+func (s *server) scope(ctx context.Context, method string) (context.Context, func(), error) {
+	scope, ok := scopes[method]
+	if !ok {
+		return ctx, nil, nil
+	}
+
+	if scopeErr := middleware.CheckScope(callFrom(ctx).principal, scope); scopeErr != nil {
+		return nil, nil, toStatus(scopeErr)
+	}
+	return ctx, nil, nil
+}
+
+func (s *server) quota(ctx context.Context, _ string) (context.Context, func(), error) {
+	c := callFrom(ctx)
+	if quotaErr := middleware.ConsumeQuota(ctx, s.Usage, s.Logger, c.principal, s.Now()); quotaErr != nil {
+		return nil, nil, toStatus(quotaErr)
+	}
+
+	return ctx, func() {
+		middleware.RecordUsage(s.Usage, s.Logger, c.principal.UsageKey(), c.usage, s.Now())
+	}, nil
+}
+
+// clientIP is the peer's address, or the first address in the proxy
+// header when the peer is a trusted proxy. Peers that are not on an IP
+// network, such as unix sockets, are local and trusted.
+func (s *server) clientIP(ctx context.Context) string {
+	var host string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host = p.Addr.String()
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+	}
+
+	header := s.Config.ProxyHeader
+	if header != "" && (net.ParseIP(host) == nil || types.MatchesIPRule(s.Config.TrustedProxies, host)) {
+		if forwarded := incoming(ctx, strings.ToLower(header)); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	return host
+}
+
+func incoming(ctx context.Context, key string) string {
+	values := metadata.ValueFromIncomingContext(ctx, key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func setRateLimit(ctx context.Context, limit middleware.RateLimit) {
+	grpc.SetHeader(ctx, metadata.Pairs(
+		metadataLimit, strconv.Itoa(limit.Limit),
+		metadataRemaining, strconv.Itoa(limit.Remaining),
+	))
+	if limit.RetryAfter > 0 {
+		grpc.SetTrailer(ctx, metadata.Pairs(metadataRetryAfter, strconv.Itoa(int(math.Ceil(limit.RetryAfter.Seconds())))))
+	}
+}
+
+// codesByStatus maps the HTTP status of an API error to a gRPC code.
+var codesByStatus = map[int]codes.Code{
+	http.StatusBadRequest:         codes.InvalidArgument,
+	http.StatusUnauthorized:       codes.Unauthenticated,
+	http.StatusForbidden:          codes.PermissionDenied,
+	http.StatusNotFound:           codes.NotFound,
+	http.StatusConflict:           codes.FailedPrecondition,
+	http.StatusTooManyRequests:    codes.ResourceExhausted,
+	http.StatusBadGateway:         codes.Unavailable,
+	http.StatusServiceUnavailable: codes.Unavailable,
+	http.StatusGatewayTimeout:     codes.DeadlineExceeded,
+}
+
+// toStatus converts an API error to a gRPC status. The error code and
+// details travel as an ErrorInfo, with the code as its reason.
+func toStatus(apiErr *apierror.Error) error {
+	code, ok := codesByStatus[apiErr.Status]
+	if !ok {
+		code = codes.Internal
+	}
+
+	info := &errdetails.ErrorInfo{Reason: apiErr.Code, Domain: errorDomain}
+	if len(apiErr.Details) > 0 {
+		info.Metadata = make(map[string]string, len(apiErr.Details))
+		for key, value := range apiErr.Details {
+			info.Metadata[key] = fmt.Sprint(value)
+		}
+	}
+
+	st, err := status.New(code, apiErr.Message).WithDetails(info)
+	if err != nil {
+		return status.Error(code, apiErr.Message)
+	}
+	return st.Err()
+}
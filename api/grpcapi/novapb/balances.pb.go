@@ -0,0 +1,620 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: balances.proto
+
+package novapb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// WalletError uses the error codes of the v1 HTTP API, such as
+// invalid_address and upstream_timeout.
+type WalletError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          string                 `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WalletError) Reset() {
+	*x = WalletError{}
+	mi := &file_balances_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WalletError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WalletError) ProtoMessage() {}
+
+func (x *WalletError) ProtoReflect() protoreflect.Message {
+	mi := &file_balances_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WalletError.ProtoReflect.Descriptor instead.
+func (*WalletError) Descriptor() ([]byte, []int) {
+	return file_balances_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *WalletError) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *WalletError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type GetBalancesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Wallets       []string               `protobuf:"bytes,1,rep,name=wallets,proto3" json:"wallets,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBalancesRequest) Reset() {
+	*x = GetBalancesRequest{}
+	mi := &file_balances_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBalancesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBalancesRequest) ProtoMessage() {}
+
+func (x *GetBalancesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_balances_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBalancesRequest.ProtoReflect.Descriptor instead.
+func (*GetBalancesRequest) Descriptor() ([]byte, []int) {
+	return file_balances_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetBalancesRequest) GetWallets() []string {
+	if x != nil {
+		return x.Wallets
+	}
+	return nil
+}
+
+type Balance struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Address string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	// In SOL.
+	Balance       float64      `protobuf:"fixed64,2,opt,name=balance,proto3" json:"balance,omitempty"`
+	Error         *WalletError `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Balance) Reset() {
+	*x = Balance{}
+	mi := &file_balances_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Balance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Balance) ProtoMessage() {}
+
+func (x *Balance) ProtoReflect() protoreflect.Message {
+	mi := &file_balances_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Balance.ProtoReflect.Descriptor instead.
+func (*Balance) Descriptor() ([]byte, []int) {
+	return file_balances_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Balance) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Balance) GetBalance() float64 {
+	if x != nil {
+		return x.Balance
+	}
+	return 0
+}
+
+func (x *Balance) GetError() *WalletError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type GetBalancesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Balances      []*Balance             `protobuf:"bytes,1,rep,name=balances,proto3" json:"balances,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBalancesResponse) Reset() {
+	*x = GetBalancesResponse{}
+	mi := &file_balances_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBalancesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBalancesResponse) ProtoMessage() {}
+
+func (x *GetBalancesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_balances_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBalancesResponse.ProtoReflect.Descriptor instead.
+func (*GetBalancesResponse) Descriptor() ([]byte, []int) {
+	return file_balances_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetBalancesResponse) GetBalances() []*Balance {
+	if x != nil {
+		return x.Balances
+	}
+	return nil
+}
+
+type BalanceUpdate struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The wallet's position in the request.
+	Index         int32    `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Balance       *Balance `protobuf:"bytes,2,opt,name=balance,proto3" json:"balance,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BalanceUpdate) Reset() {
+	*x = BalanceUpdate{}
+	mi := &file_balances_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BalanceUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BalanceUpdate) ProtoMessage() {}
+
+func (x *BalanceUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_balances_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BalanceUpdate.ProtoReflect.Descriptor instead.
+func (*BalanceUpdate) Descriptor() ([]byte, []int) {
+	return file_balances_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BalanceUpdate) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *BalanceUpdate) GetBalance() *Balance {
+	if x != nil {
+		return x.Balance
+	}
+	return nil
+}
+
+type GetTokenBalancesRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Owners []string               `protobuf:"bytes,1,rep,name=owners,proto3" json:"owners,omitempty"`
+	// Only report this mint when set.
+	Mint          string `protobuf:"bytes,2,opt,name=mint,proto3" json:"mint,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTokenBalancesRequest) Reset() {
+	*x = GetTokenBalancesRequest{}
+	mi := &file_balances_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTokenBalancesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTokenBalancesRequest) ProtoMessage() {}
+
+func (x *GetTokenBalancesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_balances_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTokenBalancesRequest.ProtoReflect.Descriptor instead.
+func (*GetTokenBalancesRequest) Descriptor() ([]byte, []int) {
+	return file_balances_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetTokenBalancesRequest) GetOwners() []string {
+	if x != nil {
+		return x.Owners
+	}
+	return nil
+}
+
+func (x *GetTokenBalancesRequest) GetMint() string {
+	if x != nil {
+		return x.Mint
+	}
+	return ""
+}
+
+type TokenBalance struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Mint  string                 `protobuf:"bytes,1,opt,name=mint,proto3" json:"mint,omitempty"`
+	// The token account holding the balance.
+	Account string `protobuf:"bytes,2,opt,name=account,proto3" json:"account,omitempty"`
+	// In base units, as a decimal string so that no precision is lost.
+	Amount        string  `protobuf:"bytes,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	Decimals      uint32  `protobuf:"varint,4,opt,name=decimals,proto3" json:"decimals,omitempty"`
+	UiAmount      float64 `protobuf:"fixed64,5,opt,name=ui_amount,json=uiAmount,proto3" json:"ui_amount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TokenBalance) Reset() {
+	*x = TokenBalance{}
+	mi := &file_balances_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TokenBalance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TokenBalance) ProtoMessage() {}
+
+func (x *TokenBalance) ProtoReflect() protoreflect.Message {
+	mi := &file_balances_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TokenBalance.ProtoReflect.Descriptor instead.
+func (*TokenBalance) Descriptor() ([]byte, []int) {
+	return file_balances_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TokenBalance) GetMint() string {
+	if x != nil {
+		return x.Mint
+	}
+	return ""
+}
+
+func (x *TokenBalance) GetAccount() string {
+	if x != nil {
+		return x.Account
+	}
+	return ""
+}
+
+func (x *TokenBalance) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *TokenBalance) GetDecimals() uint32 {
+	if x != nil {
+		return x.Decimals
+	}
+	return 0
+}
+
+func (x *TokenBalance) GetUiAmount() float64 {
+	if x != nil {
+		return x.UiAmount
+	}
+	return 0
+}
+
+type OwnerTokenBalances struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Owner         string                 `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	Tokens        []*TokenBalance        `protobuf:"bytes,2,rep,name=tokens,proto3" json:"tokens,omitempty"`
+	Error         *WalletError           `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OwnerTokenBalances) Reset() {
+	*x = OwnerTokenBalances{}
+	mi := &file_balances_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OwnerTokenBalances) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OwnerTokenBalances) ProtoMessage() {}
+
+func (x *OwnerTokenBalances) ProtoReflect() protoreflect.Message {
+	mi := &file_balances_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OwnerTokenBalances.ProtoReflect.Descriptor instead.
+func (*OwnerTokenBalances) Descriptor() ([]byte, []int) {
+	return file_balances_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *OwnerTokenBalances) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *OwnerTokenBalances) GetTokens() []*TokenBalance {
+	if x != nil {
+		return x.Tokens
+	}
+	return nil
+}
+
+func (x *OwnerTokenBalances) GetError() *WalletError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type GetTokenBalancesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Owners        []*OwnerTokenBalances  `protobuf:"bytes,1,rep,name=owners,proto3" json:"owners,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTokenBalancesResponse) Reset() {
+	*x = GetTokenBalancesResponse{}
+	mi := &file_balances_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTokenBalancesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTokenBalancesResponse) ProtoMessage() {}
+
+func (x *GetTokenBalancesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_balances_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTokenBalancesResponse.ProtoReflect.Descriptor instead.
+func (*GetTokenBalancesResponse) Descriptor() ([]byte, []int) {
+	return file_balances_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetTokenBalancesResponse) GetOwners() []*OwnerTokenBalances {
+	if x != nil {
+		return x.Owners
+	}
+	return nil
+}
+
+var File_balances_proto protoreflect.FileDescriptor
+
+const file_balances_proto_rawDesc = "" +
+	"\n" +
+	"\x0ebalances.proto\x12\anova.v1\";\n" +
+	"\vWalletError\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\".\n" +
+	"\x12GetBalancesRequest\x12\x18\n" +
+	"\awallets\x18\x01 \x03(\tR\awallets\"i\n" +
+	"\aBalance\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x18\n" +
+	"\abalance\x18\x02 \x01(\x01R\abalance\x12*\n" +
+	"\x05error\x18\x03 \x01(\v2\x14.nova.v1.WalletErrorR\x05error\"C\n" +
+	"\x13GetBalancesResponse\x12,\n" +
+	"\bbalances\x18\x01 \x03(\v2\x10.nova.v1.BalanceR\bbalances\"Q\n" +
+	"\rBalanceUpdate\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12*\n" +
+	"\abalance\x18\x02 \x01(\v2\x10.nova.v1.BalanceR\abalance\"E\n" +
+	"\x17GetTokenBalancesRequest\x12\x16\n" +
+	"\x06owners\x18\x01 \x03(\tR\x06owners\x12\x12\n" +
+	"\x04mint\x18\x02 \x01(\tR\x04mint\"\x8d\x01\n" +
+	"\fTokenBalance\x12\x12\n" +
+	"\x04mint\x18\x01 \x01(\tR\x04mint\x12\x18\n" +
+	"\aaccount\x18\x02 \x01(\tR\aaccount\x12\x16\n" +
+	"\x06amount\x18\x03 \x01(\tR\x06amount\x12\x1a\n" +
+	"\bdecimals\x18\x04 \x01(\rR\bdecimals\x12\x1b\n" +
+	"\tui_amount\x18\x05 \x01(\x01R\buiAmount\"\x85\x01\n" +
+	"\x12OwnerTokenBalances\x12\x14\n" +
+	"\x05owner\x18\x01 \x01(\tR\x05owner\x12-\n" +
+	"\x06tokens\x18\x02 \x03(\v2\x15.nova.v1.TokenBalanceR\x06tokens\x12*\n" +
+	"\x05error\x18\x03 \x01(\v2\x14.nova.v1.WalletErrorR\x05error\"O\n" +
+	"\x18GetTokenBalancesResponse\x123\n" +
+	"\x06owners\x18\x01 \x03(\v2\x1b.nova.v1.OwnerTokenBalancesR\x06owners2\xfc\x01\n" +
+	"\x0eBalanceService\x12H\n" +
+	"\vGetBalances\x12\x1b.nova.v1.GetBalancesRequest\x1a\x1c.nova.v1.GetBalancesResponse\x12G\n" +
+	"\x0eStreamBalances\x12\x1b.nova.v1.GetBalancesRequest\x1a\x16.nova.v1.BalanceUpdate0\x01\x12W\n" +
+	"\x10GetTokenBalances\x12 .nova.v1.GetTokenBalancesRequest\x1a!.nova.v1.GetTokenBalancesResponseB Z\x1enova/api/grpcapi/novapb;novapbb\x06proto3"
+
+var (
+	file_balances_proto_rawDescOnce sync.Once
+	file_balances_proto_rawDescData []byte
+)
+
+func file_balances_proto_rawDescGZIP() []byte {
+	file_balances_proto_rawDescOnce.Do(func() {
+		file_balances_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_balances_proto_rawDesc), len(file_balances_proto_rawDesc)))
+	})
+	return file_balances_proto_rawDescData
+}
+
+var file_balances_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_balances_proto_goTypes = []any{
+	(*WalletError)(nil),              // 0: nova.v1.WalletError
+	(*GetBalancesRequest)(nil),       // 1: nova.v1.GetBalancesRequest
+	(*Balance)(nil),                  // 2: nova.v1.Balance
+	(*GetBalancesResponse)(nil),      // 3: nova.v1.GetBalancesResponse
+	(*BalanceUpdate)(nil),            // 4: nova.v1.BalanceUpdate
+	(*GetTokenBalancesRequest)(nil),  // 5: nova.v1.GetTokenBalancesRequest
+	(*TokenBalance)(nil),             // 6: nova.v1.TokenBalance
+	(*OwnerTokenBalances)(nil),       // 7: nova.v1.OwnerTokenBalances
+	(*GetTokenBalancesResponse)(nil), // 8: nova.v1.GetTokenBalancesResponse
+}
+var file_balances_proto_depIdxs = []int32{
+	0, // 0: nova.v1.Balance.error:type_name -> nova.v1.WalletError
+	2, // 1: nova.v1.GetBalancesResponse.balances:type_name -> nova.v1.Balance
+	2, // 2: nova.v1.BalanceUpdate.balance:type_name -> nova.v1.Balance
+	6, // 3: nova.v1.OwnerTokenBalances.tokens:type_name -> nova.v1.TokenBalance
+	0, // 4: nova.v1.OwnerTokenBalances.error:type_name -> nova.v1.WalletError
+	7, // 5: nova.v1.GetTokenBalancesResponse.owners:type_name -> nova.v1.OwnerTokenBalances
+	1, // 6: nova.v1.BalanceService.GetBalances:input_type -> nova.v1.GetBalancesRequest
+	1, // 7: nova.v1.BalanceService.StreamBalances:input_type -> nova.v1.GetBalancesRequest
+	5, // 8: nova.v1.BalanceService.GetTokenBalances:input_type -> nova.v1.GetTokenBalancesRequest
+	3, // 9: nova.v1.BalanceService.GetBalances:output_type -> nova.v1.GetBalancesResponse
+	4, // 10: nova.v1.BalanceService.StreamBalances:output_type -> nova.v1.BalanceUpdate
+	8, // 11: nova.v1.BalanceService.GetTokenBalances:output_type -> nova.v1.GetTokenBalancesResponse
+	9, // [9:12] is the sub-list for method output_type
+	6, // [6:9] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_balances_proto_init() }
+func file_balances_proto_init() {
+	if File_balances_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_balances_proto_rawDesc), len(file_balances_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_balances_proto_goTypes,
+		DependencyIndexes: file_balances_proto_depIdxs,
+		MessageInfos:      file_balances_proto_msgTypes,
+	}.Build()
+	File_balances_proto = out.File
+	file_balances_proto_goTypes = nil
+	file_balances_proto_depIdxs = nil
+}
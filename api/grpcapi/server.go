@@ -0,0 +1,196 @@
+// Package grpcapi serves the balance API over gRPC. Its interceptors run the
+// checks of the fiber middleware chain, in the same order and with the same
+// rate limiters and quotas, so a key has one budget across both transports.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"nova/api/apierror"
+	"nova/api/grpcapi/novapb"
+	"nova/api/metrics"
+	"nova/api/middleware"
+	"nova/api/services"
+	"nova/api/types"
+)
+
+const errorDomain = "nova"
+
+// scopes are the scopes each method requires.
+var scopes = map[string]string{
+	novapb.BalanceService_GetBalances_FullMethodName:      types.ScopeBalanceRead,
+	novapb.BalanceService_StreamBalances_FullMethodName:   types.ScopeBalanceRead,
+	novapb.BalanceService_GetTokenBalances_FullMethodName: types.ScopeTokensRead,
+}
+
+// Deps are the services the server is built from. JWT may be nil when
+// bearer tokens are not configured.
+type Deps struct {
+	Config   *types.Config
+	Solana   *services.SolanaService
+	Keys     *services.KeyService
+	JWT      *services.JWTVerifier
+	Usage    *services.UsageService
+	Limiters *middleware.RateLimiters
+	Metrics  *metrics.Requests
+	Logger   *log.Logger
+	Now      func() time.Time
+}
+
+type server struct {
+	novapb.UnimplementedBalanceServiceServer
+	Deps
+}
+
+func NewServer(deps Deps) *grpc.Server {
+	s := &server{Deps: deps}
+
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unaryInterceptors()...),
+		grpc.ChainStreamInterceptor(s.streamInterceptors()...),
+	)
+	novapb.RegisterBalanceServiceServer(srv, s)
+	return srv
+}
+
+func (s *server) GetBalances(ctx context.Context, req *novapb.GetBalancesRequest) (*novapb.GetBalancesResponse, error) {
+	c := callFrom(ctx)
+	wallets, err := s.addresses(c.principal, req.GetWallets(), "wallets")
+	if err != nil {
+		return nil, err
+	}
+
+	results := s.Solana.GetMultipleBalances(ctx, c.principal.KeyID, wallets)
+
+	c.usage.Wallets = int64(len(results))
+	resp := &novapb.GetBalancesResponse{Balances: make([]*novapb.Balance, len(results))}
+	for i, result := range results {
+		countSource(&c.usage, result.Source)
+		resp.Balances[i] = balance(result)
+	}
+
+	return resp, nil
+}
+
+func (s *server) StreamBalances(req *novapb.GetBalancesRequest, stream grpc.ServerStreamingServer[novapb.BalanceUpdate]) error {
+	ctx := stream.Context()
+	c := callFrom(ctx)
+	wallets, err := s.addresses(c.principal, req.GetWallets(), "wallets")
+	if err != nil {
+		return err
+	}
+
+	c.usage.Wallets = int64(len(wallets))
+	return s.Solana.StreamBalances(ctx, c.principal.KeyID, wallets, func(index int, result types.WalletBalance) error {
+		countSource(&c.usage, result.Source)
+		return stream.Send(&novapb.BalanceUpdate{Index: int32(index), Balance: balance(result)})
+	})
+}
+
+func (s *server) GetTokenBalances(ctx context.Context, req *novapb.GetTokenBalancesRequest) (*novapb.GetTokenBalancesResponse, error) {
+	c := callFrom(ctx)
+	owners, err := s.addresses(c.principal, req.GetOwners(), "owners")
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.Solana.GetTokenBalances(ctx, c.principal.KeyID, owners, strings.TrimSpace(req.GetMint()))
+	if errors.Is(err, services.ErrInvalidAddress) {
+		return nil, toStatus(apierror.New(http.StatusBadRequest, types.CodeInvalidAddress, "Invalid mint address").
+			With("mint", req.GetMint()))
+	}
+	if err != nil {
+		return nil, toStatus(apierror.New(http.StatusInternalServerError, types.CodeInternal, "Internal server error"))
+	}
+
+	c.usage.Wallets = int64(len(results))
+	resp := &novapb.GetTokenBalancesResponse{Owners: make([]*novapb.OwnerTokenBalances, len(results))}
+	for i, result := range results {
+		c.usage.RPCCalls += int64(result.RPCCalls)
+
+		owner := &novapb.OwnerTokenBalances{
+			Owner:  result.Owner,
+			Tokens: make([]*novapb.TokenBalance, len(result.Tokens)),
+			Error:  walletError(apierror.Sanitized(result.Error, result.ErrorCode)),
+		}
+		for j, token := range result.Tokens {
+			owner.Tokens[j] = &novapb.TokenBalance{
+				Mint:     token.Mint,
+				Account:  token.Account,
+				Amount:   token.Amount,
+				Decimals: uint32(token.Decimals),
+				UiAmount: token.UIAmount,
+			}
+		}
+		resp.Owners[i] = owner
+	}
+
+	return resp, nil
+}
+
+// addresses validates a list of addresses the way GetBalance does over
+// HTTP: the list may not be empty or exceed the tier's MaxWallets, and
+// blank entries are dropped.
+func (s *server) addresses(principal *types.Principal, addresses []string, noun string) ([]string, error) {
+	if len(addresses) == 0 {
+		return nil, toStatus(apierror.New(http.StatusBadRequest, types.CodeInvalidRequest, "No "+noun+" provided"))
+	}
+
+	tierName := principal.Tier
+	tier, ok := s.Config.RateLimitTiers[tierName]
+	if !ok {
+		tierName = types.TierFree
+		tier = s.Config.RateLimitTiers[tierName]
+	}
+
+	if len(addresses) > tier.MaxWallets {
+		message := fmt.Sprintf("Too many %s (max %d for the %s tier)", noun, tier.MaxWallets, tierName)
+		return nil, toStatus(apierror.New(http.StatusBadRequest, types.CodeTooManyWallets, message).
+			With("max", tier.MaxWallets).With("tier", tierName))
+	}
+
+	valid := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		address = strings.TrimSpace(address)
+		if address != "" {
+			valid = append(valid, address)
+		}
+	}
+
+	if len(valid) == 0 {
+		return nil, toStatus(apierror.New(http.StatusBadRequest, types.CodeInvalidRequest, "No valid "+noun+" provided"))
+	}
+	return valid, nil
+}
+
+func countSource(counters *types.UsageCounters, source string) {
+	switch source {
+	case types.SourceCache:
+		counters.CacheHits++
+	case types.SourceRPC:
+		counters.RPCCalls++
+	}
+}
+
+func balance(result types.WalletBalance) *novapb.Balance {
+	return &novapb.Balance{
+		Address: result.Address,
+		Balance: result.Balance,
+		Error:   walletError(apierror.WalletError(result)),
+	}
+}
+
+func walletError(err *types.WalletError) *novapb.WalletError {
+	if err == nil {
+		return nil
+	}
+	return &novapb.WalletError{Code: err.Code, Message: err.Message}
+}
@@ -0,0 +1,155 @@
+// Package metrics writes gauges, counters and histograms in the Prometheus
+// text exposition format without pulling in the Prometheus client.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DurationBuckets are upper bounds in seconds suited to request and queue
+// wait times.
+var DurationBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func NewHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// Snapshot returns cumulative bucket counts, the total count and the sum.
+func (h *Histogram) Snapshot() ([]uint64, uint64, float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	counts := make([]uint64, len(h.counts))
+	copy(counts, h.counts)
+	return counts, h.count, h.sum
+}
+
+// Requests counts requests by method and status code and times them.
+type Requests struct {
+	mu        sync.Mutex
+	counts    map[requestLabels]uint64
+	durations *Histogram
+}
+
+type requestLabels struct {
+	method string
+	code   string
+}
+
+func NewRequests() *Requests {
+	return &Requests{
+		counts:    make(map[requestLabels]uint64),
+		durations: NewHistogram(DurationBuckets),
+	}
+}
+
+func (r *Requests) Observe(method, code string, duration time.Duration) {
+	r.mu.Lock()
+	r.counts[requestLabels{method, code}]++
+	r.mu.Unlock()
+
+	r.durations.Observe(duration.Seconds())
+}
+
+// Count returns how many requests to method ended with code.
+func (r *Requests) Count(method, code string) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counts[requestLabels{method, code}]
+}
+
+type Writer struct {
+	w io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (w *Writer) Gauge(name, help string, value float64) {
+	w.header(name, help, "gauge")
+	fmt.Fprintf(w.w, "%s %s\n", name, formatFloat(value))
+}
+
+func (w *Writer) Counter(name, help string, value float64) {
+	w.header(name, help, "counter")
+	fmt.Fprintf(w.w, "%s %s\n", name, formatFloat(value))
+}
+
+func (w *Writer) Histogram(name, help string, h *Histogram) {
+	counts, count, sum := h.Snapshot()
+
+	w.header(name, help, "histogram")
+	for i, bound := range h.buckets {
+		fmt.Fprintf(w.w, "%s_bucket{le=\"%s\"} %d\n", name, formatFloat(bound), counts[i])
+	}
+	fmt.Fprintf(w.w, "%s_bucket{le=\"+Inf\"} %d\n", name, count)
+	fmt.Fprintf(w.w, "%s_sum %s\n", name, formatFloat(sum))
+	fmt.Fprintf(w.w, "%s_count %d\n", name, count)
+}
+
+// Requests writes r as <prefix>_requests_total by method and code, and
+// <prefix>_request_duration_seconds.
+func (w *Writer) Requests(prefix, what string, r *Requests) {
+	r.mu.Lock()
+	labels := make([]requestLabels, 0, len(r.counts))
+	for label := range r.counts {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].method != labels[j].method {
+			return labels[i].method < labels[j].method
+		}
+		return labels[i].code < labels[j].code
+	})
+
+	name := prefix + "_requests_total"
+	w.header(name, what+" handled, by method and status code.", "counter")
+	for _, label := range labels {
+		fmt.Fprintf(w.w, "%s{method=%q,code=%q} %d\n", name, label.method, label.code, r.counts[label])
+	}
+	r.mu.Unlock()
+
+	w.Histogram(prefix+"_request_duration_seconds", "Time taken to handle "+what+".", r.durations)
+}
+
+func (w *Writer) header(name, help, kind string) {
+	fmt.Fprintf(w.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
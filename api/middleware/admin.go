@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/gofiber/fiber/v2"
+
+	"nova/api/apierror"
+	"nova/api/types"
+)
+
+func AdminAuthMiddleware(adminKey string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if adminKey == "" {
+			return apierror.New(fiber.StatusServiceUnavailable, types.CodeUnavailable, "Admin API is not configured").Send(c)
+		}
+
+		provided := c.Get("X-Admin-Key")
+
+		if provided == "" {
+			return apierror.New(fiber.StatusUnauthorized, types.CodeUnauthorized, "Admin key is required").Send(c)
+		}
+
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(adminKey)) != 1 {
+			return apierror.New(fiber.StatusUnauthorized, types.CodeUnauthorized, "Invalid admin key").Send(c)
+		}
+
+		return c.Next()
+	}
+}
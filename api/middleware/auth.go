@@ -2,81 +2,174 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
 
+	"nova/api/apierror"
+	"nova/api/services"
+	"nova/api/signing"
 	"nova/api/types"
 )
 
-func AuthMiddleware(db *types.Database) fiber.Handler {
-	collection := db.MongoDB.Database("nova").Collection("api_keys")
+// Credentials are what a request presents to authenticate, whichever
+// transport it came over. At most one of BearerToken, Signed and APIKey is
+// used, in that order.
+type Credentials struct {
+	HasBearer   bool
+	BearerToken string
+	Signed      *services.SignedRequest
+	APIKey      string
+
+	IP     string
+	Origin string
+}
 
+func AuthMiddleware(cfg *types.Config, keys *services.KeyService, tokens *services.JWTVerifier, now func() time.Time) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		apiKey := c.Get("X-API-Key")
-
-		if apiKey == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(types.ErrorResponse{
-				Success: false,
-				Message: "API key is required",
-			})
+		credentials := Credentials{
+			APIKey: c.Get("X-API-Key"),
+			IP:     c.IP(),
+			Origin: requestOrigin(c),
+		}
+		credentials.BearerToken, credentials.HasBearer = bearerToken(c)
+		if c.Get(signing.HeaderSignature) != "" {
+			credentials.Signed = &services.SignedRequest{
+				KeyID:     c.Get(signing.HeaderKeyID),
+				Timestamp: c.Get(signing.HeaderTimestamp),
+				Nonce:     c.Get(signing.HeaderNonce),
+				Signature: c.Get(signing.HeaderSignature),
+				Method:    c.Method(),
+				Path:      c.OriginalURL(),
+				Body:      c.Body(),
+			}
 		}
 
-		cacheKey := "api_key:" + apiKey
-		ctx := context.Background()
+		principal, authErr := Authenticate(c.UserContext(), cfg, keys, tokens, credentials, now())
+		if authErr != nil {
+			return authErr.Send(c)
+		}
 
-		redisCtx, redisCancel := context.WithTimeout(ctx, 100*time.Millisecond)
-		cached, err := db.Redis.Get(redisCtx, cacheKey).Result()
+		c.Locals("api_key", principal.KeyID)
+		c.Locals("principal", principal)
+		return c.Next()
+	}
+}
 
-		redisCancel()
+// Authenticate resolves credentials to a principal and checks that the
+// principal may make the request.
+func Authenticate(ctx context.Context, cfg *types.Config, keys *services.KeyService, tokens *services.JWTVerifier, credentials Credentials, now time.Time) (*types.Principal, *apierror.Error) {
+	var principal *types.Principal
+	var err error
 
-		if err == nil {
-			if cached == "valid" {
-				c.Locals("api_key", apiKey)
-				return c.Next()
-			}
+	switch {
+	case credentials.HasBearer:
+		if tokens == nil {
+			return nil, apierror.New(fiber.StatusUnauthorized, types.CodeUnauthorized, "Bearer tokens are not accepted by this server")
+		}
+		principal, err = tokens.Verify(ctx, credentials.BearerToken, now)
+	case credentials.Signed != nil:
+		principal, err = keys.AuthenticateSigned(ctx, *credentials.Signed, now)
+	default:
+		if credentials.APIKey == "" {
+			return nil, apierror.New(fiber.StatusUnauthorized, types.CodeUnauthorized, "API key is required")
+		}
 
-			if cached == "invalid" {
-				return c.Status(fiber.StatusUnauthorized).JSON(types.ErrorResponse{
-					Success: false,
-					Message: "Invalid API key",
-				})
-			}
+		principal, err = keys.Authenticate(ctx, credentials.APIKey)
+		if err == nil && principal.RequireSignature {
+			return nil, apierror.New(fiber.StatusUnauthorized, types.CodeUnauthorized, "Request signing is required for this API key")
 		}
+	}
 
-		mongoCtx, mongoCancel := context.WithTimeout(ctx, 1*time.Second)
-		defer mongoCancel()
-
-		var keyDoc types.APIKey
-		err = collection.FindOne(mongoCtx, bson.M{"key": apiKey, "active": true}).Decode(&keyDoc)
-
-		if err != nil {
-			if err == mongo.ErrNoDocuments {
-				go func() {
-					bgCtx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
-					defer cancel()
-					db.Redis.Set(bgCtx, cacheKey, "invalid", 5*time.Minute)
-				}()
-				return c.Status(fiber.StatusUnauthorized).JSON(types.ErrorResponse{
-					Success: false,
-					Message: "Invalid API key",
-				})
-			}
-			return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{
-				Success: false,
-				Message: "Database error",
-			})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidAPIKey):
+			return nil, apierror.New(fiber.StatusUnauthorized, types.CodeUnauthorized, "Invalid API key")
+		case errors.Is(err, services.ErrInvalidToken):
+			return nil, apierror.New(fiber.StatusUnauthorized, types.CodeUnauthorized, "Invalid bearer token")
+		case errors.Is(err, services.ErrInvalidSignature),
+			errors.Is(err, services.ErrSignatureExpired),
+			errors.Is(err, services.ErrReplayedNonce),
+			errors.Is(err, services.ErrSigningUnavailable):
+			return nil, apierror.New(fiber.StatusUnauthorized, types.CodeUnauthorized, err.Error())
 		}
+		return nil, apierror.New(fiber.StatusInternalServerError, types.CodeInternal, "Database error")
+	}
+
+	if principal.Expired(now) {
+		return nil, apierror.New(fiber.StatusUnauthorized, types.CodeKeyExpired, "API key has expired")
+	}
+
+	if !principal.AllowsNetwork(cfg.Network) {
+		return nil, apierror.New(fiber.StatusForbidden, types.CodeForbidden, "API key is not allowed on "+cfg.Network).
+			With("network", cfg.Network)
+	}
+
+	if !principal.AllowsIP(credentials.IP) {
+		return nil, apierror.New(fiber.StatusForbidden, types.CodeForbidden, "Request IP is not allowed for this API key")
+	}
+
+	if !principal.AllowsOrigin(credentials.Origin) {
+		return nil, apierror.New(fiber.StatusForbidden, types.CodeForbidden, "Request origin is not allowed for this API key")
+	}
+
+	return principal, nil
+}
 
-		go func() {
-			bgCtx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
-			defer cancel()
-			db.Redis.Set(bgCtx, cacheKey, "valid", 15*time.Minute)
-		}()
+// Recheck checks that a principal authenticated earlier, such as that of a
+// long-lived connection, is still valid: it has not expired and its key has
+// not been revoked or rotated since. Store errors are not reported, so a
+// check that cannot complete is left to the next one.
+func Recheck(ctx context.Context, keys *services.KeyService, principal *types.Principal, now time.Time) *apierror.Error {
+	if principal.Expired(now) {
+		return apierror.New(fiber.StatusUnauthorized, types.CodeKeyExpired, "API key has expired")
+	}
+
+	if errors.Is(keys.Recheck(ctx, principal), services.ErrInvalidAPIKey) {
+		return apierror.New(fiber.StatusUnauthorized, types.CodeUnauthorized, "API key has been revoked or rotated")
+	}
+	return nil
+}
+
+func RequireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, _ := c.Locals("principal").(*types.Principal)
+		if scopeErr := CheckScope(principal, scope); scopeErr != nil {
+			return scopeErr.Send(c)
+		}
 
-		c.Locals("api_key", keyDoc.Key)
 		return c.Next()
 	}
 }
+
+func CheckScope(principal *types.Principal, scope string) *apierror.Error {
+	if principal == nil || !principal.HasScope(scope) {
+		return apierror.New(fiber.StatusForbidden, types.CodeForbidden, "API key is missing the "+scope+" scope").
+			With("scope", scope)
+	}
+	return nil
+}
+
+func bearerToken(c *fiber.Ctx) (string, bool) {
+	scheme, token, ok := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func requestOrigin(c *fiber.Ctx) string {
+	if origin := c.Get(fiber.HeaderOrigin); origin != "" {
+		return origin
+	}
+
+	referer, err := url.Parse(c.Get(fiber.HeaderReferer))
+	if err != nil || referer.Host == "" {
+		return ""
+	}
+
+	return referer.Scheme + "://" + referer.Host
+}
@@ -1,39 +1,167 @@
 package middleware
 
 import (
+	"fmt"
+	"math"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/time/rate"
 
+	"nova/api/apierror"
 	"nova/api/types"
 )
 
-var (
-	rateLimiters sync.Map
-)
+// RateLimiters holds the per-IP and per-principal limiters of one server.
+type RateLimiters struct {
+	clients    sync.Map
+	principals sync.Map
+}
+
+func NewRateLimiters() *RateLimiters {
+	return &RateLimiters{}
+}
+
+// RateLimit is the state of the limiter a request was counted against.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	// RetryAfter is set when the request was rejected.
+	RetryAfter time.Duration
+}
 
-func RateLimitMiddleware() fiber.Handler {
+// RateLimitMiddleware limits requests that fail to authenticate per client
+// IP, to slow down key guessing. It runs before AuthMiddleware: an IP with
+// no failures left is rejected before any lookup, and a request takes one
+// once the rest of the chain has run without setting a principal, so
+// authenticated requests are otherwise only limited by their tier.
+func RateLimitMiddleware(limiters *RateLimiters, now func() time.Time) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		clientIP := c.IP()
+		if limit, limitErr := limiters.CheckClient(c.IP(), now()); limitErr != nil {
+			SetRateLimitHeaders(c, limit)
+			return limitErr.Send(c)
+		}
 
-		limiterInterface, _ := rateLimiters.LoadOrStore(clientIP, rate.NewLimiter(rate.Limit(10.0/60.0), 9))
-		limiter := limiterInterface.(*rate.Limiter)
+		err := c.Next()
+		if _, ok := c.Locals("principal").(*types.Principal); !ok {
+			limit, _ := limiters.AllowClient(c.IP(), now())
+			SetRateLimitHeaders(c, limit)
+		}
+		return err
+	}
+}
 
-		if !limiter.Allow() {
-			return c.Status(fiber.StatusTooManyRequests).JSON(types.ErrorResponse{
-				Success: false,
-				Message: "Ratelimit exceeded: 10 requests per minute",
-			})
+// TierRateLimitMiddleware limits each authenticated principal according to
+// its tier.
+func TierRateLimitMiddleware(limiters *RateLimiters, tiers map[string]types.RateLimitTier, now func() time.Time) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := c.Locals("principal").(*types.Principal)
+		if !ok {
+			return c.Next()
+		}
+
+		limit, limitErr := limiters.AllowPrincipal(principal, tiers, now())
+		SetRateLimitHeaders(c, limit)
+		if limitErr != nil {
+			return limitErr.Send(c)
 		}
 
 		return c.Next()
 	}
 }
 
-func ClearRateLimiters() {
-	rateLimiters.Range(func(key, value interface{}) bool {
-		rateLimiters.Delete(key)
-		return true
-	})
+// CheckClient rejects a client IP that has used up its limit of 10
+// unauthenticated requests per minute, without counting the request.
+func (l *RateLimiters) CheckClient(ip string, now time.Time) (RateLimit, *apierror.Error) {
+	limiter := l.client(ip)
+
+	tokens := limiter.TokensAt(now)
+	if tokens >= 1 {
+		return RateLimit{Limit: 10, Remaining: int(tokens)}, nil
+	}
+
+	wait := time.Duration((1 - tokens) / float64(limiter.Limit()) * float64(time.Second))
+	return RateLimit{Limit: 10, RetryAfter: wait}, clientLimitError()
+}
+
+// AllowClient counts an unauthenticated request against the limit of 10
+// per minute per client IP.
+func (l *RateLimiters) AllowClient(ip string, now time.Time) (RateLimit, *apierror.Error) {
+	limit := take(l.client(ip), now, 1, 10)
+	if limit.RetryAfter > 0 {
+		return limit, clientLimitError()
+	}
+	return limit, nil
+}
+
+func (l *RateLimiters) client(ip string) *rate.Limiter {
+	limiter, _ := l.clients.LoadOrStore(ip, rate.NewLimiter(rate.Limit(10.0/60.0), 9))
+	return limiter.(*rate.Limiter)
+}
+
+func clientLimitError() *apierror.Error {
+	return apierror.New(fiber.StatusTooManyRequests, types.CodeRateLimited, "Ratelimit exceeded: 10 unauthenticated requests per minute").
+		With("limit", 10)
+}
+
+// AllowPrincipal counts a request against the limit of the principal's
+// tier.
+func (l *RateLimiters) AllowPrincipal(principal *types.Principal, tiers map[string]types.RateLimitTier, now time.Time) (RateLimit, *apierror.Error) {
+	return l.SpendPrincipal(principal, tiers, now, 1)
+}
+
+// SpendPrincipal takes credits from the principal's tier limiter at once,
+// for requests that cost more than one, such as GraphQL queries. Nothing is
+// taken when there are not enough credits left.
+func (l *RateLimiters) SpendPrincipal(principal *types.Principal, tiers map[string]types.RateLimitTier, now time.Time, credits int) (RateLimit, *apierror.Error) {
+	tier, ok := tiers[principal.Tier]
+	if !ok {
+		tier = tiers[types.TierFree]
+	}
+
+	limiterKey := principal.UsageKey() + ":" + principal.Tier
+	limiterInterface, _ := l.principals.LoadOrStore(limiterKey,
+		rate.NewLimiter(rate.Limit(float64(tier.RequestsPerMinute)/60.0), tier.Burst))
+	limiter := limiterInterface.(*rate.Limiter)
+
+	limit := take(limiter, now, credits, tier.RequestsPerMinute)
+	if limit.RetryAfter > 0 {
+		message := fmt.Sprintf("Ratelimit exceeded: %d requests per minute for the %s tier", tier.RequestsPerMinute, principal.Tier)
+		return limit, apierror.New(fiber.StatusTooManyRequests, types.CodeRateLimited, message).
+			With("limit", tier.RequestsPerMinute).With("tier", principal.Tier)
+	}
+	return limit, nil
+}
+
+// take takes n tokens from limiter. Rejected requests get the time until
+// enough tokens free up.
+func take(limiter *rate.Limiter, now time.Time, n, perMinute int) RateLimit {
+	limit := RateLimit{Limit: perMinute}
+
+	reservation := limiter.ReserveN(now, n)
+	if !reservation.OK() {
+		limit.RetryAfter = time.Minute
+		return limit
+	}
+
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		limit.RetryAfter = delay
+		return limit
+	}
+
+	limit.Remaining = int(limiter.TokensAt(now))
+	return limit
+}
+
+// SetRateLimitHeaders reports the limit on the response: the tier's for
+// authenticated requests and the per-IP one for the rest.
+func SetRateLimitHeaders(c *fiber.Ctx, limit RateLimit) {
+	c.Set("X-RateLimit-Limit", strconv.Itoa(limit.Limit))
+	c.Set("X-RateLimit-Remaining", strconv.Itoa(limit.Remaining))
+	if limit.RetryAfter > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(limit.RetryAfter.Seconds()))))
+	}
 }
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"nova/api/apierror"
+	"nova/api/types"
+)
+
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+// RequestTimeoutMiddleware gives every request a context with a deadline of
+// limit. Clients may ask for a shorter deadline with X-Request-Timeout, given
+// as a duration such as 500ms or as a number of seconds. A limit of zero
+// only applies the header.
+func RequestTimeoutMiddleware(limit time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		timeout, timeoutErr := RequestTimeout(limit, c.Get(RequestTimeoutHeader))
+		if timeoutErr != nil {
+			return timeoutErr.Send(c)
+		}
+
+		if timeout <= 0 {
+			return c.Next()
+		}
+
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
+
+// RequestTimeout is the shorter of limit and the requested timeout, if
+// one was requested.
+func RequestTimeout(limit time.Duration, requested string) (time.Duration, *apierror.Error) {
+	if requested == "" {
+		return limit, nil
+	}
+
+	timeout, err := parseTimeout(requested)
+	if err != nil {
+		return 0, apierror.New(fiber.StatusBadRequest, types.CodeInvalidRequest, "Invalid "+RequestTimeoutHeader+" header").
+			With("header", RequestTimeoutHeader)
+	}
+	if limit > 0 && limit < timeout {
+		return limit, nil
+	}
+	return timeout, nil
+}
+
+var errInvalidTimeout = errors.New("timeout must be positive")
+
+func parseTimeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, numErr := strconv.ParseFloat(value, 64)
+		// Larger values would overflow a Duration and are capped anyway.
+		if numErr != nil || !(seconds > 0 && seconds < 1e9) {
+			return 0, err
+		}
+		timeout = time.Duration(seconds * float64(time.Second))
+	}
+
+	if timeout <= 0 {
+		return 0, errInvalidTimeout
+	}
+	return timeout, nil
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"nova/api/apierror"
+	"nova/api/services"
+	"nova/api/types"
+)
+
+func UsageMiddleware(usage *services.UsageService, logger *log.Logger, now func() time.Time) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := c.Locals("principal").(*types.Principal)
+		if !ok {
+			return c.Next()
+		}
+
+		if quotaErr := ConsumeQuota(c.UserContext(), usage, logger, principal, now()); quotaErr != nil {
+			return quotaErr.Send(c)
+		}
+
+		err := c.Next()
+
+		counters, _ := c.Locals("usage").(types.UsageCounters)
+		RecordUsage(usage, logger, principal.UsageKey(), counters, now())
+
+		return err
+	}
+}
+
+// ConsumeQuota counts a request against the principal's quotas. Errors
+// other than an exceeded quota are logged and let the request through.
+func ConsumeQuota(ctx context.Context, usage *services.UsageService, logger *log.Logger, principal *types.Principal, now time.Time) *apierror.Error {
+	err := usage.ConsumeQuota(ctx, principal, now)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, services.ErrQuotaExceeded) {
+		logger.Printf("Failed to check quota for %s: %v", principal.KeyID, err)
+		return nil
+	}
+
+	quotaErr := apierror.New(fiber.StatusTooManyRequests, types.CodeQuotaExceeded, "Quota exceeded: "+err.Error())
+	var quota *services.QuotaError
+	if errors.As(err, &quota) {
+		quotaErr.With("period", quota.Period).With("limit", quota.Limit)
+	}
+	return quotaErr
+}
+
+// RecordUsage records one request with counters in the background.
+func RecordUsage(usage *services.UsageService, logger *log.Logger, keyID string, counters types.UsageCounters, recordedAt time.Time) {
+	counters.Requests = 1
+
+	go func() {
+		bgCtx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		if err := usage.Record(bgCtx, keyID, counters, recordedAt); err != nil {
+			logger.Printf("Failed to record usage for %s: %v", keyID, err)
+		}
+	}()
+}
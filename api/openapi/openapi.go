@@ -0,0 +1,318 @@
+// Package openapi builds the OpenAPI 3.1 document of the API from the route
+// table in routes.go and the Go types the handlers send and receive, so the
+// schemas cannot fall out of step with the JSON the server produces.
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const Version = "3.1.0"
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower-case HTTP methods to their operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Security    []map[string][]string `json:"security"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+	Ref         string  `json:"$ref,omitempty"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+	Ref         string  `json:"$ref,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+	Headers         map[string]Header         `json:"headers"`
+	Parameters      map[string]Parameter      `json:"parameters"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Ref returns the schema reference of a component.
+func Ref(name string) string {
+	return "#/components/schemas/" + name
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(bson.ObjectID{})
+	rawType      = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemas collects the component schemas of the types a document uses.
+type schemas map[string]*Schema
+
+// of returns the schema of the type of value. Named structs are added to
+// the components and referenced.
+func (s schemas) of(value interface{}) *Schema {
+	return s.schema(reflect.TypeOf(value))
+}
+
+func (s schemas) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case objectIDType:
+		return &Schema{Type: "string", Pattern: "^[0-9a-f]{24}$"}
+	case rawType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		format := "int64"
+		if t.Bits() <= 32 {
+			format = "int32"
+		}
+		return &Schema{Type: "integer", Format: format}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		if _, ok := s[t.Name()]; !ok {
+			// Reserve the name first so that recursive types terminate.
+			s[t.Name()] = nil
+			s[t.Name()] = s.object(t)
+		}
+		return &Schema{Ref: Ref(t.Name())}
+	}
+
+	panic("openapi: no schema for " + t.String())
+}
+
+// object follows encoding/json: fields named "-" are skipped, embedded
+// structs are flattened and fields without omitempty are required.
+func (s schemas) object(t reflect.Type) *Schema {
+	object := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := s.object(field.Type)
+			for property, schema := range embedded.Properties {
+				object.Properties[property] = schema
+			}
+			object.Required = append(object.Required, embedded.Required...)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		object.Properties[name] = s.schema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			object.Required = append(object.Required, name)
+		}
+	}
+
+	sort.Strings(object.Required)
+	return object
+}
+
+// Build returns the document of every route in Routes.
+func Build() *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:   "Nova Solana Balance API",
+			Version: "1.0.0",
+			Description: "Routes under /api/v1 and /ws return errors as {code, message, details, request_id}. " +
+				"The older routes under /api return {success, message, code}, /graphql returns a GraphQL errors list " +
+				"and /rpc a JSON-RPC error with the code in its data.",
+		},
+		Paths:      make(map[string]PathItem),
+		Components: components(),
+	}
+
+	s := schemas(doc.Components.Schemas)
+	for _, route := range Routes {
+		path := Path(route.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(PathItem)
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = route.operation(s)
+	}
+
+	return doc
+}
+
+// Path converts a fiber path such as /api/jobs/:id to /api/jobs/{id}.
+func Path(fiberPath string) string {
+	segments := strings.Split(fiberPath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func (r Route) operation(s schemas) *Operation {
+	op := &Operation{
+		OperationID: r.ID,
+		Summary:     r.Summary,
+		Tags:        []string{r.Tag},
+		Security:    r.Auth.requirements(),
+		Responses:   make(map[string]*Response),
+	}
+	if r.Scope != "" {
+		op.Description = "Requires the " + r.Scope + " scope."
+	}
+
+	for _, segment := range strings.Split(r.Path, "/") {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"},
+			})
+		}
+	}
+	op.Parameters = append(op.Parameters, r.Params...)
+	if r.Auth != AuthAdmin && r.Auth != AuthNone {
+		op.Parameters = append(op.Parameters, Parameter{Ref: "#/components/parameters/RequestTimeout"})
+	}
+
+	if r.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: s.of(r.Request)}},
+		}
+		for contentType, body := range r.RequestAlternatives {
+			op.RequestBody.Content[contentType] = MediaType{Schema: body(s)}
+		}
+	}
+
+	success := &Response{
+		Description: r.Returns,
+		Headers:     r.Auth.headers(),
+	}
+	if r.Response != nil {
+		success.Content = map[string]MediaType{"application/json": {Schema: s.of(r.Response)}}
+	}
+	for contentType, body := range r.Alternatives {
+		success.Content[contentType] = MediaType{Schema: body(s)}
+	}
+	for name, header := range r.Headers {
+		success.Headers[name] = header
+	}
+	op.Responses[strconv.Itoa(r.Status)] = success
+
+	if r.NotModified {
+		op.Responses["304"] = &Response{
+			Description: "The ETag in If-None-Match is still current",
+			Headers:     success.Headers,
+		}
+	}
+
+	errorSchema := s.of(errorType(r.Path))
+	for _, status := range r.errors() {
+		response := &Response{
+			Description: errorDescriptions[status],
+			Headers:     r.Auth.headers(),
+			Content:     map[string]MediaType{"application/json": {Schema: errorSchema}},
+		}
+		if status == 429 {
+			response.Headers = rateLimitedHeaders()
+		}
+		op.Responses[strconv.Itoa(status)] = response
+	}
+
+	return op
+}
@@ -0,0 +1,407 @@
+package openapi
+
+import (
+	"slices"
+	"strings"
+
+	"nova/api/middleware"
+	"nova/api/signing"
+	"nova/api/types"
+)
+
+type Auth int
+
+const (
+	AuthNone Auth = iota
+	// AuthKey accepts an API key, a bearer token or a signed request.
+	AuthKey
+	AuthAdmin
+)
+
+// Body is the schema of a request or response body other than JSON.
+type Body func(s schemas) *Schema
+
+// Route documents one route registered in routes.Register. The drift test
+// fails when the two disagree.
+type Route struct {
+	Method  string
+	Path    string
+	ID      string
+	Summary string
+	Tag     string
+	Auth    Auth
+	Scope   string
+
+	Params              []Parameter
+	Request             interface{}
+	RequestAlternatives map[string]Body
+
+	Status  int
+	Returns string
+	// Response is nil for responses without a body, such as the switch to
+	// a websocket.
+	Response     interface{}
+	Alternatives map[string]Body
+	Headers      map[string]Header
+	NotModified  bool
+
+	// Errors are the statuses the route returns on top of those of its
+	// auth.
+	Errors []int
+}
+
+var Routes = []Route{
+	{
+		Method: "POST", Path: "/api/get-balance", ID: "getBalance", Tag: "balances",
+		Summary: "Look up the SOL balance of up to the tier's maximum number of wallets",
+		Auth:    AuthKey, Scope: types.ScopeBalanceRead,
+		Request: types.BalanceRequest{},
+		Status:  200, Returns: "Balances in request order. Accept application/x-ndjson or text/event-stream to receive each balance as it resolves.",
+		Response: types.BalanceResponse{},
+		Alternatives: map[string]Body{
+			mimeNDJSON:      lines(types.BalanceStreamItem{}, types.BalanceStreamEnd{}),
+			mimeEventStream: events("balance", "summary"),
+		},
+	},
+	{
+		Method: "GET", Path: "/api/usage", ID: "getUsage", Tag: "usage",
+		Summary: "Report the usage and quotas of the calling key",
+		Auth:    AuthKey,
+		Params:  []Parameter{timeQuery("from", "Defaults to 24 hours ago"), timeQuery("to", "Defaults to now")},
+		Status:  200, Returns: "The usage report", Response: types.UsageResponse{},
+	},
+	{
+		Method: "POST", Path: "/api/jobs/balances", ID: "createBalanceJob", Tag: "jobs",
+		Summary: "Start a background balance snapshot of up to 100,000 wallets",
+		Auth:    AuthKey, Scope: types.ScopeBalanceRead,
+		Request: types.BalanceRequest{}, RequestAlternatives: csvUpload,
+		Status: 202, Returns: "The queued job", Response: types.JobResponse{},
+		Headers: map[string]Header{"Location": {Description: "The URL of the job", Schema: &Schema{Type: "string"}}},
+	},
+	{
+		Method: "GET", Path: "/api/jobs/:id", ID: "getBalanceJob", Tag: "jobs",
+		Summary: "Get the progress of a balance job",
+		Auth:    AuthKey, Scope: types.ScopeBalanceRead,
+		Status: 200, Returns: "The job", Response: types.JobResponse{},
+		Errors: []int{404},
+	},
+	{
+		Method: "GET", Path: "/api/jobs/:id/results", ID: "getBalanceJobResults", Tag: "jobs",
+		Summary: "Download the results of a completed balance job",
+		Auth:    AuthKey, Scope: types.ScopeBalanceRead,
+		Params: []Parameter{formatQuery},
+		Status: 200, Returns: "Balances in submission order, as an attachment", Response: types.BalanceResponse{},
+		Alternatives: map[string]Body{
+			mimeNDJSON: lines(types.WalletBalance{}),
+			mimeCSV:    text("A header row of address,balance,error followed by one row per wallet"),
+		},
+		Errors: []int{404, 409},
+	},
+	{
+		Method: "POST", Path: "/api/v1/balances", ID: "getBalancesV1", Tag: "v1",
+		Summary: "Look up the SOL balance of up to the tier's maximum number of wallets",
+		Auth:    AuthKey, Scope: types.ScopeBalanceRead,
+		Request: types.BalanceRequest{},
+		Status:  200, Returns: "Balances in request order. Accept application/x-ndjson or text/event-stream to receive each balance as it resolves.",
+		Response: types.BalanceResultsResponse{},
+		Alternatives: map[string]Body{
+			mimeNDJSON:      lines(types.BalanceResultStreamItem{}, types.BalanceStreamEnd{}),
+			mimeEventStream: events("balance", "summary"),
+		},
+	},
+	{
+		Method: "GET", Path: "/api/v1/accounts/:address/balance", ID: "getAccountBalanceV1", Tag: "v1",
+		Summary: "Get the balance of one account, cacheable by browsers and CDNs",
+		Auth:    AuthKey, Scope: types.ScopeBalanceRead,
+		Params: []Parameter{{
+			Name: "commitment", In: "query",
+			Schema: &Schema{Type: "string", Enum: []string{"processed", "confirmed", "finalized"}, Description: "Defaults to finalized"},
+		}, {
+			Name: "If-None-Match", In: "header", Schema: &Schema{Type: "string"},
+		}},
+		Status: 200, Returns: "The balance", Response: types.AccountBalanceResponse{},
+		Headers: map[string]Header{
+			"ETag":          {Description: "Changes with the slot and lamports", Schema: &Schema{Type: "string"}},
+			"Cache-Control": {Description: "Never outlives the server's cache entry", Schema: &Schema{Type: "string"}},
+		},
+		NotModified: true,
+		Errors:      []int{502, 504},
+	},
+	{
+		Method: "GET", Path: "/api/v1/usage", ID: "getUsageV1", Tag: "v1",
+		Summary: "Report the usage and quotas of the calling key",
+		Auth:    AuthKey,
+		Params:  []Parameter{timeQuery("from", "Defaults to 24 hours ago"), timeQuery("to", "Defaults to now")},
+		Status:  200, Returns: "The usage report", Response: types.UsageResponse{},
+	},
+	{
+		Method: "POST", Path: "/api/v1/jobs/balances", ID: "createBalanceJobV1", Tag: "v1",
+		Summary: "Start a background balance snapshot of up to 100,000 wallets",
+		Auth:    AuthKey, Scope: types.ScopeBalanceRead,
+		Request: types.BalanceRequest{}, RequestAlternatives: csvUpload,
+		Status: 202, Returns: "The queued job", Response: types.JobResponse{},
+		Headers: map[string]Header{"Location": {Description: "The URL of the job", Schema: &Schema{Type: "string"}}},
+	},
+	{
+		Method: "GET", Path: "/api/v1/jobs/:id", ID: "getBalanceJobV1", Tag: "v1",
+		Summary: "Get the progress of a balance job",
+		Auth:    AuthKey, Scope: types.ScopeBalanceRead,
+		Status: 200, Returns: "The job", Response: types.JobResponse{},
+		Errors: []int{404},
+	},
+	{
+		Method: "GET", Path: "/api/v1/jobs/:id/results", ID: "getBalanceJobResultsV1", Tag: "v1",
+		Summary: "Download the results of a completed balance job",
+		Auth:    AuthKey, Scope: types.ScopeBalanceRead,
+		Params: []Parameter{formatQuery},
+		Status: 200, Returns: "Balances in submission order, as an attachment", Response: types.BalanceResultsResponse{},
+		Alternatives: map[string]Body{
+			mimeNDJSON: lines(types.BalanceResult{}),
+			mimeCSV:    text("A header row of address,balance,error followed by one row per wallet, with the error code"),
+		},
+		Errors: []int{404, 409},
+	},
+	{
+		Method: "POST", Path: "/graphql", ID: "postGraphQL", Tag: "graphql",
+		Summary: "Query accounts, token accounts, transactions and stake accounts in one request",
+		Auth:    AuthKey, Scope: types.ScopeBalanceRead,
+		Request: types.GraphQLRequest{},
+		Status:  200, Returns: "The query result. The query's price in rate-limit credits is in extensions.cost.", Response: types.GraphQLResponse{},
+	},
+	{
+		Method: "GET", Path: "/graphql", ID: "getGraphQL", Tag: "graphql",
+		Summary: "Run a GraphQL query given in the URL, typically a persisted query by hash",
+		Auth:    AuthKey, Scope: types.ScopeBalanceRead,
+		Params: []Parameter{
+			{Name: "query", In: "query", Schema: &Schema{Type: "string"}},
+			{Name: "operationName", In: "query", Schema: &Schema{Type: "string"}},
+			{Name: "variables", In: "query", Schema: &Schema{Type: "string", Description: "A JSON object"}},
+			{Name: "extensions", In: "query", Schema: &Schema{Type: "string", Description: "A JSON object, such as {\"persistedQuery\":{\"version\":1,\"sha256Hash\":\"...\"}}"}},
+		},
+		Status: 200, Returns: "The query result. The query's price in rate-limit credits is in extensions.cost.", Response: types.GraphQLResponse{},
+	},
+	{
+		Method: "POST", Path: "/rpc", ID: "callRPC", Tag: "rpc",
+		Summary: "Call the Solana JSON-RPC API, or send a batch of up to 100 calls as an array",
+		Auth:    AuthKey, Scope: types.ScopeBalanceRead,
+		Request: types.RPCRequest{},
+		Status:  200, Returns: "The JSON-RPC response, or an array of them in request order for a batch", Response: types.RPCResponse{},
+	},
+	{
+		Method: "GET", Path: "/ws", ID: "subscribeBalances", Tag: "websocket",
+		Summary: "Subscribe to finalized balance changes over a websocket",
+		Auth:    AuthKey, Scope: types.ScopeBalanceRead,
+		Status: 101,
+		Returns: "Switches to a websocket. Send {\"type\": \"subscribe\" or \"unsubscribe\", \"addresses\": [...]}; " +
+			"the server answers with subscribed and unsubscribed acknowledgements, a balance message per address on " +
+			"subscribing and on every change, and error messages with the fields of an API error.",
+		Errors: []int{426},
+	},
+	{
+		Method: "POST", Path: "/admin/keys", ID: "createAPIKey", Tag: "admin",
+		Summary: "Create an API key", Auth: AuthAdmin,
+		Request: types.CreateAPIKeyRequest{},
+		Status:  201, Returns: "The key. The raw key and signing secret are only returned here.", Response: types.APIKeyResponse{},
+		Errors: []int{400},
+	},
+	{
+		Method: "GET", Path: "/admin/keys", ID: "listAPIKeys", Tag: "admin",
+		Summary: "List API keys", Auth: AuthAdmin,
+		Params: []Parameter{
+			{Name: "page", In: "query", Schema: &Schema{Type: "integer", Description: "Defaults to 1"}},
+			{Name: "limit", In: "query", Schema: &Schema{Type: "integer", Description: "1 to 100, defaults to 50"}},
+		},
+		Status: 200, Returns: "A page of keys", Response: types.APIKeyListResponse{},
+	},
+	{
+		Method: "POST", Path: "/admin/keys/migrate", ID: "migrateAPIKeys", Tag: "admin",
+		Summary: "Hash the API keys still stored in plain text", Auth: AuthAdmin,
+		Status: 200, Returns: "The number of keys migrated", Response: types.MigrationResponse{},
+	},
+	{
+		Method: "GET", Path: "/admin/keys/:id", ID: "getAPIKey", Tag: "admin",
+		Summary: "Get an API key", Auth: AuthAdmin,
+		Status: 200, Returns: "The key", Response: types.APIKeyResponse{},
+		Errors: []int{400, 404},
+	},
+	{
+		Method: "DELETE", Path: "/admin/keys/:id", ID: "revokeAPIKey", Tag: "admin",
+		Summary: "Revoke an API key", Auth: AuthAdmin,
+		Status: 200, Returns: "The revoked key", Response: types.APIKeyResponse{},
+		Errors: []int{400, 404, 409},
+	},
+	{
+		Method: "POST", Path: "/admin/keys/:id/rotate", ID: "rotateAPIKey", Tag: "admin",
+		Summary: "Replace the secret of an API key", Auth: AuthAdmin,
+		Status: 200, Returns: "The key with its new raw key", Response: types.APIKeyResponse{},
+		Errors: []int{400, 404, 409},
+	},
+}
+
+const (
+	mimeNDJSON      = "application/x-ndjson"
+	mimeEventStream = "text/event-stream"
+	mimeCSV         = "text/csv"
+)
+
+var formatQuery = Parameter{
+	Name: "format", In: "query",
+	Schema: &Schema{Type: "string", Enum: []string{"json", "ndjson", "csv"}, Description: "Defaults to json"},
+}
+
+var csvUpload = map[string]Body{
+	mimeCSV: text("One address per row in the first column. A header row naming it address or wallet is skipped."),
+	"multipart/form-data": func(s schemas) *Schema {
+		return &Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{"file": {Type: "string", Format: "binary", Description: "A CSV file"}},
+			Required:   []string{"file"},
+		}
+	},
+}
+
+func timeQuery(name, description string) Parameter {
+	return Parameter{
+		Name: name, In: "query",
+		Schema: &Schema{Type: "string", Format: "date-time", Description: description},
+	}
+}
+
+// lines is a body of one JSON record per line.
+func lines(records ...interface{}) Body {
+	return func(s schemas) *Schema {
+		if len(records) == 1 {
+			return s.of(records[0])
+		}
+		schema := &Schema{Description: "One record per line; the last line is the summary"}
+		for _, record := range records {
+			schema.OneOf = append(schema.OneOf, s.of(record))
+		}
+		return schema
+	}
+}
+
+// events is a body of server-sent events with the same records as the
+// NDJSON form of the route.
+func events(names ...string) Body {
+	return text("Server-sent events named " + strings.Join(names, " and ") + ", with the NDJSON records as data")
+}
+
+func text(description string) Body {
+	return func(s schemas) *Schema {
+		return &Schema{Type: "string", Description: description}
+	}
+}
+
+func (a Auth) requirements() []map[string][]string {
+	switch a {
+	case AuthKey:
+		return []map[string][]string{{"apiKey": {}}, {"bearerToken": {}}, {"signedRequest": {}}}
+	case AuthAdmin:
+		return []map[string][]string{{"adminKey": {}}}
+	}
+	return []map[string][]string{}
+}
+
+// headers are the response headers set on every response of a route.
+func (a Auth) headers() map[string]Header {
+	headers := map[string]Header{"X-Request-ID": {Ref: "#/components/headers/X-Request-ID"}}
+	if a == AuthKey {
+		headers["X-RateLimit-Limit"] = Header{Ref: "#/components/headers/X-RateLimit-Limit"}
+		headers["X-RateLimit-Remaining"] = Header{Ref: "#/components/headers/X-RateLimit-Remaining"}
+	}
+	return headers
+}
+
+func rateLimitedHeaders() map[string]Header {
+	headers := AuthKey.headers()
+	headers["Retry-After"] = Header{Ref: "#/components/headers/Retry-After"}
+	return headers
+}
+
+func (r Route) errors() []int {
+	statuses := append([]int{500}, r.Errors...)
+	switch r.Auth {
+	case AuthKey:
+		statuses = append(statuses, 400, 401, 403, 429)
+	case AuthAdmin:
+		statuses = append(statuses, 401, 503)
+	}
+
+	slices.Sort(statuses)
+	return slices.Compact(statuses)
+}
+
+// errorType is the error body of a path, which depends on its API version.
+func errorType(path string) interface{} {
+	switch path {
+	case "/graphql":
+		return types.GraphQLResponse{}
+	case "/rpc":
+		return types.RPCResponse{}
+	case "/ws":
+		return types.APIError{}
+	}
+	if strings.HasPrefix(path, "/api/v1/") {
+		return types.APIError{}
+	}
+	return types.ErrorResponse{}
+}
+
+var errorDescriptions = map[int]string{
+	400: "invalid_request, invalid_address, too_many_wallets or query_too_complex",
+	401: "unauthorized or key_expired",
+	403: "forbidden",
+	404: "not_found",
+	409: "conflict",
+	426: "invalid_request: the request is not a websocket upgrade",
+	429: "rate_limited or quota_exceeded",
+	500: "internal_error",
+	502: "upstream_error",
+	503: "unavailable",
+	504: "upstream_timeout",
+}
+
+func components() Components {
+	return Components{
+		Schemas: make(map[string]*Schema),
+		SecuritySchemes: map[string]SecurityScheme{
+			"apiKey": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+			"bearerToken": {
+				Type: "http", Scheme: "bearer", BearerFormat: "JWT",
+				Description: "A JWT from the configured issuer, when bearer tokens are enabled",
+			},
+			"signedRequest": {
+				Type: "apiKey", In: "header", Name: signing.HeaderSignature,
+				Description: "An HMAC-SHA256 of the request made with the key's signing secret. " +
+					"Send it with " + signing.HeaderKeyID + ", " + signing.HeaderTimestamp + " and " + signing.HeaderNonce + ".",
+			},
+			"adminKey": {Type: "apiKey", In: "header", Name: "X-Admin-Key"},
+		},
+		Headers: map[string]Header{
+			"X-Request-ID": {
+				Description: "Identifies the request in logs and in v1 error bodies",
+				Schema:      &Schema{Type: "string"},
+			},
+			"X-RateLimit-Limit": {
+				Description: "Requests per minute allowed for the key's tier",
+				Schema:      &Schema{Type: "integer"},
+			},
+			"X-RateLimit-Remaining": {
+				Description: "Requests left before the limit applies",
+				Schema:      &Schema{Type: "integer"},
+			},
+			"Retry-After": {
+				Description: "Seconds until a request will be allowed again",
+				Schema:      &Schema{Type: "integer"},
+			},
+		},
+		Parameters: map[string]Parameter{
+			"RequestTimeout": {
+				Name: middleware.RequestTimeoutHeader, In: "header",
+				Description: "Shortens the server's deadline for the request, as a Go duration or a number of seconds",
+				Schema:      &Schema{Type: "string"},
+			},
+		},
+	}
+}
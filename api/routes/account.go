@@ -0,0 +1,86 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gofiber/fiber/v2"
+
+	"nova/api/apierror"
+	"nova/api/services"
+	"nova/api/types"
+)
+
+var commitments = map[string]rpc.CommitmentType{
+	"processed": rpc.CommitmentProcessed,
+	"confirmed": rpc.CommitmentConfirmed,
+	"finalized": rpc.CommitmentFinalized,
+}
+
+// GetAccountBalance serves one balance over GET so that browsers and CDNs
+// can cache it. The ETag changes with the slot and lamports, and max-age
+// never outlives the server's own cache entry.
+func (h *Handlers) GetAccountBalance(ctx *fiber.Ctx) error {
+	commitment, ok := commitments[ctx.Query("commitment", "finalized")]
+	if !ok {
+		return apierror.New(fiber.StatusBadRequest, types.CodeInvalidRequest, "commitment must be processed, confirmed or finalized").Send(ctx)
+	}
+
+	principal := ctx.Locals("principal").(*types.Principal)
+	account, err := h.Solana.GetAccountBalance(ctx.UserContext(), principal.KeyID, ctx.Params("address"), commitment)
+	if err != nil {
+		return accountError(ctx, err)
+	}
+
+	counters := types.UsageCounters{Wallets: 1}
+	if account.Source == types.SourceCache {
+		counters.CacheHits++
+	} else {
+		counters.RPCCalls++
+	}
+	ctx.Locals("usage", counters)
+
+	etag := fmt.Sprintf(`"%x-%x"`, account.Slot, account.Lamports)
+	maxAge := max(services.BalanceCacheTTL-time.Since(account.FetchedAt), 0)
+
+	ctx.Set(fiber.HeaderETag, etag)
+	ctx.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+
+	if etagMatches(ctx.Get(fiber.HeaderIfNoneMatch), etag) {
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+
+	return ctx.JSON(types.AccountBalanceResponse{
+		Success: true,
+		Data:    account,
+	})
+}
+
+// etagMatches applies the weak comparison If-None-Match calls for.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func accountError(ctx *fiber.Ctx, err error) error {
+	return lookupError(err).Send(ctx)
+}
+
+// lookupError is the API error of a failed single-account lookup.
+func lookupError(err error) *apierror.Error {
+	switch {
+	case errors.Is(err, services.ErrInvalidAddress):
+		return apierror.New(fiber.StatusBadRequest, types.CodeInvalidAddress, err.Error())
+	case errors.Is(err, services.ErrLookupTimeout):
+		return apierror.New(fiber.StatusGatewayTimeout, types.CodeUpstreamTimeout, "Timed out fetching balance")
+	}
+	return apierror.New(fiber.StatusBadGateway, types.CodeUpstreamError, "Failed to fetch balance")
+}
@@ -0,0 +1,125 @@
+package routes
+
+import (
+	"errors"
+
+	"nova/api/apierror"
+	"nova/api/services"
+	"nova/api/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *Handlers) CreateAPIKey(ctx *fiber.Ctx) error {
+	var request types.CreateAPIKeyRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return apierror.New(fiber.StatusBadRequest, types.CodeInvalidRequest, "Invalid request body").Send(ctx)
+	}
+
+	keyDoc, rawKey, err := h.Keys.Create(ctx.UserContext(), request)
+	if err != nil {
+		return keyError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(types.APIKeyResponse{
+		Success:       true,
+		Data:          keyDoc,
+		Key:           rawKey,
+		SigningSecret: h.Keys.RevealSigningSecret(keyDoc),
+	})
+}
+
+func (h *Handlers) ListAPIKeys(ctx *fiber.Ctx) error {
+	page := ctx.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+
+	limit := ctx.QueryInt("limit", 50)
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	keys, total, err := h.Keys.List(ctx.UserContext(), page, limit)
+	if err != nil {
+		return keyError(ctx, err)
+	}
+
+	return ctx.JSON(types.APIKeyListResponse{
+		Success: true,
+		Data:    keys,
+		Page:    page,
+		Limit:   limit,
+		Total:   total,
+	})
+}
+
+func (h *Handlers) GetAPIKey(ctx *fiber.Ctx) error {
+	keyDoc, err := h.Keys.Get(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return keyError(ctx, err)
+	}
+
+	return ctx.JSON(types.APIKeyResponse{
+		Success: true,
+		Data:    keyDoc,
+	})
+}
+
+func (h *Handlers) RevokeAPIKey(ctx *fiber.Ctx) error {
+	keyDoc, err := h.Keys.Revoke(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return keyError(ctx, err)
+	}
+
+	return ctx.JSON(types.APIKeyResponse{
+		Success: true,
+		Data:    keyDoc,
+	})
+}
+
+func (h *Handlers) RotateAPIKey(ctx *fiber.Ctx) error {
+	keyDoc, rawKey, err := h.Keys.Rotate(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return keyError(ctx, err)
+	}
+
+	return ctx.JSON(types.APIKeyResponse{
+		Success:       true,
+		Data:          keyDoc,
+		Key:           rawKey,
+		SigningSecret: h.Keys.RevealSigningSecret(keyDoc),
+	})
+}
+
+func (h *Handlers) MigrateAPIKeys(ctx *fiber.Ctx) error {
+	migrated, err := h.Keys.MigratePlaintextKeys(ctx.UserContext())
+	if err != nil {
+		return keyError(ctx, err)
+	}
+
+	return ctx.JSON(types.MigrationResponse{
+		Success:  true,
+		Migrated: migrated,
+	})
+}
+
+func keyError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrKeyNotFound):
+		return apierror.New(fiber.StatusNotFound, types.CodeNotFound, err.Error()).Send(ctx)
+	case errors.Is(err, services.ErrInvalidID),
+		errors.Is(err, services.ErrInvalidTier),
+		errors.Is(err, services.ErrInvalidExpiry),
+		errors.Is(err, services.ErrInvalidScope),
+		errors.Is(err, services.ErrInvalidNet),
+		errors.Is(err, services.ErrInvalidIPRule),
+		errors.Is(err, services.ErrInvalidOrigin),
+		errors.Is(err, services.ErrInvalidQuota),
+		errors.Is(err, services.ErrSigningUnavailable):
+		return apierror.New(fiber.StatusBadRequest, types.CodeInvalidRequest, err.Error()).Send(ctx)
+	case errors.Is(err, services.ErrKeyRevoked):
+		return apierror.New(fiber.StatusConflict, types.CodeConflict, err.Error()).Send(ctx)
+	}
+	return apierror.New(fiber.StatusInternalServerError, types.CodeInternal, "Database error").Send(ctx)
+}
@@ -1,41 +1,37 @@
 package routes
 
 import (
+	"fmt"
 	"strings"
 
-	"nova/api/services"
+	"nova/api/apierror"
 	"nova/api/types"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-var solanaService *services.SolanaService
-
-func InitSolanaService(service *services.SolanaService) {
-	solanaService = service
-}
-
-func GetBalance(ctx *fiber.Ctx) error {
+func (h *Handlers) GetBalance(ctx *fiber.Ctx) error {
 	var request types.BalanceRequest
 	if err := ctx.BodyParser(&request); err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
-			Success: false,
-			Message: "Invalid request body",
-		})
+		return apierror.New(fiber.StatusBadRequest, types.CodeInvalidRequest, "Invalid request body").Send(ctx)
 	}
 
 	if len(request.Wallets) == 0 {
-		return ctx.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
-			Success: false,
-			Message: "No wallets provided",
-		})
+		return apierror.New(fiber.StatusBadRequest, types.CodeInvalidRequest, "No wallets provided").Send(ctx)
 	}
 
-	if len(request.Wallets) > 100 {
-		return ctx.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
-			Success: false,
-			Message: "Too many wallets (max 100)",
-		})
+	principal := ctx.Locals("principal").(*types.Principal)
+	tierName := principal.Tier
+	tier, ok := h.Config.RateLimitTiers[tierName]
+	if !ok {
+		tierName = types.TierFree
+		tier = h.Config.RateLimitTiers[tierName]
+	}
+
+	if len(request.Wallets) > tier.MaxWallets {
+		message := fmt.Sprintf("Too many wallets (max %d for the %s tier)", tier.MaxWallets, tierName)
+		return apierror.New(fiber.StatusBadRequest, types.CodeTooManyWallets, message).
+			With("max", tier.MaxWallets).With("tier", tierName).Send(ctx)
 	}
 
 	validWallets := make([]string, 0, len(request.Wallets))
@@ -47,13 +43,32 @@ func GetBalance(ctx *fiber.Ctx) error {
 	}
 
 	if len(validWallets) == 0 {
-		return ctx.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
-			Success: false,
-			Message: "No valid wallets provided",
-		})
+		return apierror.New(fiber.StatusBadRequest, types.CodeInvalidRequest, "No valid wallets provided").Send(ctx)
 	}
 
-	results := solanaService.GetMultipleBalances(validWallets)
+	if accept := ctx.Accepts(fiber.MIMEApplicationJSON, mimeNDJSON, mimeEventStream); accept == mimeNDJSON || accept == mimeEventStream {
+		return h.streamBalances(ctx, principal, validWallets, accept)
+	}
+
+	results := h.Solana.GetMultipleBalances(ctx.UserContext(), principal.KeyID, validWallets)
+
+	counters := types.UsageCounters{Wallets: int64(len(results))}
+	for _, result := range results {
+		switch result.Source {
+		case types.SourceCache:
+			counters.CacheHits++
+		case types.SourceRPC:
+			counters.RPCCalls++
+		}
+	}
+	ctx.Locals("usage", counters)
+
+	if apierror.IsV1(ctx) {
+		return ctx.JSON(types.BalanceResultsResponse{
+			Success: true,
+			Data:    apierror.BalanceResults(results),
+		})
+	}
 
 	return ctx.JSON(types.BalanceResponse{
 		Success: true,
@@ -0,0 +1,247 @@
+package routes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"nova/api/apierror"
+	"nova/api/services"
+	"nova/api/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const mimeNDJSON = "application/x-ndjson"
+
+func (h *Handlers) CreateBalanceJob(ctx *fiber.Ctx) error {
+	wallets, err := jobWallets(ctx)
+	if err != nil {
+		return apierror.New(fiber.StatusBadRequest, types.CodeInvalidRequest, "Invalid request body").Send(ctx)
+	}
+
+	principal := ctx.Locals("principal").(*types.Principal)
+	job, err := h.Jobs.Create(ctx.UserContext(), principal.UsageKey(), wallets)
+	if err != nil {
+		return jobError(ctx, err)
+	}
+
+	ctx.Locals("usage", types.UsageCounters{Wallets: int64(job.Total)})
+	ctx.Location(strings.TrimSuffix(ctx.Path(), "/balances") + "/" + job.ID.Hex())
+
+	return ctx.Status(fiber.StatusAccepted).JSON(types.JobResponse{
+		Success: true,
+		Data:    job,
+	})
+}
+
+func (h *Handlers) GetBalanceJob(ctx *fiber.Ctx) error {
+	principal := ctx.Locals("principal").(*types.Principal)
+	job, err := h.Jobs.Get(ctx.UserContext(), principal.UsageKey(), ctx.Params("id"))
+	if err != nil {
+		return jobError(ctx, err)
+	}
+
+	return ctx.JSON(types.JobResponse{
+		Success: true,
+		Data:    job,
+	})
+}
+
+// GetBalanceJobResults downloads the results of a completed job as json
+// (the default), ndjson or csv, chosen with the format query parameter.
+// Results are streamed a chunk at a time, so a large job is never held in
+// memory whole.
+func (h *Handlers) GetBalanceJobResults(ctx *fiber.Ctx) error {
+	principal := ctx.Locals("principal").(*types.Principal)
+	job, err := h.Jobs.Get(ctx.UserContext(), principal.UsageKey(), ctx.Params("id"))
+	if err != nil {
+		return jobError(ctx, err)
+	}
+	if job.Status != types.JobCompleted {
+		return jobError(ctx, services.ErrJobNotReady)
+	}
+
+	v1 := apierror.IsV1(ctx)
+	format := ctx.Query("format", "json")
+	var contentType string
+	switch format {
+	case "json":
+		contentType = fiber.MIMEApplicationJSON
+	case "ndjson":
+		contentType = mimeNDJSON
+	case "csv":
+		contentType = "text/csv"
+	default:
+		return apierror.New(fiber.StatusBadRequest, types.CodeInvalidRequest, "format must be json, ndjson or csv").
+			With("format", format).Send(ctx)
+	}
+
+	ctx.Attachment("balances-" + job.ID.Hex() + "." + format)
+	ctx.Set(fiber.HeaderContentType, contentType)
+
+	streamCtx, cancel := bodyContext(ctx)
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+
+		// Each chunk is flushed once written.
+		results := func(visit func([]types.WalletBalance) error) error {
+			return h.Jobs.Results(streamCtx, job, func(chunk []types.WalletBalance) error {
+				if err := visit(chunk); err != nil {
+					return err
+				}
+				return w.Flush()
+			})
+		}
+
+		var err error
+		switch format {
+		case "json":
+			err = writeResultsJSON(w, v1, results)
+		case "ndjson":
+			encoder := json.NewEncoder(w)
+			err = results(func(chunk []types.WalletBalance) error {
+				for _, result := range chunk {
+					if err := encoder.Encode(resultRecord(v1, result)); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		case "csv":
+			writer := csv.NewWriter(w)
+			writer.Write([]string{"address", "balance", "error"})
+			err = results(func(chunk []types.WalletBalance) error {
+				for _, result := range chunk {
+					errorText := result.Error
+					if walletErr := apierror.WalletError(result); v1 && walletErr != nil {
+						errorText = walletErr.Code
+					}
+					writer.Write([]string{result.Address, strconv.FormatFloat(result.Balance, 'f', -1, 64), errorText})
+				}
+				writer.Flush()
+				return writer.Error()
+			})
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
+			h.Logger.Printf("Results download of job %s ended early: %v", job.ID.Hex(), err)
+		}
+	})
+
+	return nil
+}
+
+// writeResultsJSON writes the same shape as a BalanceResponse, or a
+// BalanceResultsResponse for v1, one chunk at a time.
+func writeResultsJSON(w io.Writer, v1 bool, results func(visit func([]types.WalletBalance) error) error) error {
+	io.WriteString(w, `{"success":true,"data":[`)
+
+	first := true
+	err := results(func(chunk []types.WalletBalance) error {
+		for _, result := range chunk {
+			encoded, err := json.Marshal(resultRecord(v1, result))
+			if err != nil {
+				return err
+			}
+			if !first {
+				io.WriteString(w, ",")
+			}
+			first = false
+			w.Write(encoded)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]}")
+	return err
+}
+
+// jobWallets reads the wallets from a JSON body, a CSV body, or a CSV file
+// uploaded as the "file" form field. CSV wallets are taken from the first
+// column and a header row naming it address or wallet is skipped.
+func jobWallets(ctx *fiber.Ctx) ([]string, error) {
+	contentType := ctx.Get(fiber.HeaderContentType)
+
+	switch {
+	case strings.HasPrefix(contentType, fiber.MIMEMultipartForm):
+		header, err := ctx.FormFile("file")
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		return readWalletCSV(file)
+	case strings.HasPrefix(contentType, "text/csv"):
+		return readWalletCSV(bytes.NewReader(ctx.Body()))
+	default:
+		var request types.BalanceRequest
+		if err := ctx.BodyParser(&request); err != nil {
+			return nil, err
+		}
+		return request.Wallets, nil
+	}
+}
+
+func readWalletCSV(r io.Reader) ([]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var wallets []string
+	for row := 0; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return wallets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		first := strings.TrimSpace(record[0])
+		if row == 0 && (strings.EqualFold(first, "address") || strings.EqualFold(first, "wallet")) {
+			continue
+		}
+		wallets = append(wallets, first)
+	}
+}
+
+// resultRecord is the JSON record of one wallet in the format of the API
+// version.
+func resultRecord(v1 bool, result types.WalletBalance) interface{} {
+	if v1 {
+		return apierror.BalanceResult(result)
+	}
+	return result
+}
+
+func jobError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrJobNotFound):
+		return apierror.New(fiber.StatusNotFound, types.CodeNotFound, err.Error()).Send(ctx)
+	case errors.Is(err, services.ErrJobEmpty):
+		return apierror.New(fiber.StatusBadRequest, types.CodeInvalidRequest, err.Error()).Send(ctx)
+	case errors.Is(err, services.ErrJobTooLarge):
+		return apierror.New(fiber.StatusBadRequest, types.CodeTooManyWallets, err.Error()).
+			With("max", services.MaxJobWallets).Send(ctx)
+	case errors.Is(err, services.ErrJobNotReady):
+		return apierror.New(fiber.StatusConflict, types.CodeConflict, err.Error()).Send(ctx)
+	}
+	return apierror.New(fiber.StatusInternalServerError, types.CodeInternal, "Database error").Send(ctx)
+}
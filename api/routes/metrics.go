@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"nova/api/metrics"
+)
+
+// Metrics exposes aggregate service metrics in the Prometheus text format.
+// Nothing here is labelled by API key.
+func (h *Handlers) Metrics(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+
+	pool := h.Solana.Pool()
+	stats := pool.Stats()
+
+	w := metrics.NewWriter(ctx)
+	w.Gauge("nova_rpc_pool_size", "Maximum concurrent Solana RPC lookups.", float64(stats.Size))
+	w.Gauge("nova_rpc_pool_active", "Solana RPC lookups in flight.", float64(stats.Active))
+	w.Gauge("nova_rpc_pool_queue_depth", "Solana RPC lookups waiting for a pool slot.", float64(stats.Queued))
+	w.Histogram("nova_rpc_pool_wait_seconds", "Time lookups waited for a pool slot.", pool.WaitTimes())
+	if h.GRPCRequests != nil {
+		w.Requests("nova_grpc", "gRPC calls", h.GRPCRequests)
+	}
+
+	return nil
+}
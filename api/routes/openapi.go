@@ -0,0 +1,56 @@
+package routes
+
+import (
+	"encoding/json"
+	"sync"
+
+	"nova/api/openapi"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var openAPIDocument = sync.OnceValues(func() ([]byte, error) {
+	return json.Marshal(openapi.Build())
+})
+
+func (h *Handlers) OpenAPI(ctx *fiber.Ctx) error {
+	document, err := openAPIDocument()
+	if err != nil {
+		return err
+	}
+
+	ctx.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return ctx.Send(document)
+}
+
+// redocBundle is a pinned release of Redoc. npm releases cannot be changed
+// once published, and the page's CSP allows no other script.
+const redocBundle = "https://cdn.jsdelivr.net/npm/redoc@2.1.5/bundles/redoc.standalone.js"
+
+// docsCSP lets Redoc run its bundle, inline styles and blob workers, and
+// nothing else.
+const docsCSP = "default-src 'none'; script-src " + redocBundle + "; worker-src blob:; " +
+	"style-src 'unsafe-inline' https://fonts.googleapis.com; font-src https://fonts.gstatic.com; " +
+	"img-src 'self' data:; connect-src 'self'; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+
+// Docs renders /openapi.json with a pinned Redoc release from its CDN.
+func (h *Handlers) Docs(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	ctx.Set(fiber.HeaderContentSecurityPolicy, docsCSP)
+	ctx.Set(fiber.HeaderReferrerPolicy, "no-referrer")
+	return ctx.SendString(docsPage)
+}
+
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+	<title>Nova API</title>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+</head>
+<body>
+	<redoc spec-url="/openapi.json"></redoc>
+	<script src="` + redocBundle + `" crossorigin="anonymous"></script>
+</body>
+</html>
+`
@@ -1,17 +1,103 @@
 package routes
 
 import (
+	"log"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 
+	"nova/api/graphqlapi"
+	"nova/api/metrics"
 	"nova/api/middleware"
+	"nova/api/services"
 	"nova/api/types"
 )
 
-func InitRoutes(app *fiber.App, db *types.Database) {
-	api := app.Group("/api")
+// Deps are the services the handlers are built from. JWT may be nil when
+// bearer tokens are not configured. GRPCRequests are the calls of the gRPC
+// server, exposed on /metrics. GraphQL serves /graphql and Subscriptions
+// the balance updates of /ws.
+type Deps struct {
+	Config        *types.Config
+	Solana        *services.SolanaService
+	Keys          *services.KeyService
+	JWT           *services.JWTVerifier
+	Usage         *services.UsageService
+	Jobs          *services.JobService
+	Limiters      *middleware.RateLimiters
+	GRPCRequests  *metrics.Requests
+	GraphQL       *graphqlapi.Server
+	Subscriptions *services.SubscriptionService
+	Logger        *log.Logger
+	Now           func() time.Time
+}
+
+type Handlers struct {
+	Deps
+}
+
+func New(deps Deps) *Handlers {
+	return &Handlers{Deps: deps}
+}
+
+func (h *Handlers) Register(app *fiber.App) {
+	cfg := h.Config
+
+	app.Get("/metrics", h.Metrics)
+	app.Get("/openapi.json", h.OpenAPI)
+	if cfg.DocsUI {
+		app.Get("/docs", h.Docs)
+	}
+
+	// The chain of every authenticated route: timeouts, limits, credentials
+	// and quotas.
+	chain := []fiber.Handler{
+		middleware.RequestTimeoutMiddleware(cfg.RequestTimeout),
+		middleware.RateLimitMiddleware(h.Limiters, h.Now),
+		middleware.AuthMiddleware(cfg, h.Keys, h.JWT, h.Now),
+		middleware.TierRateLimitMiddleware(h.Limiters, cfg.RateLimitTiers, h.Now),
+		middleware.UsageMiddleware(h.Usage, h.Logger, h.Now),
+	}
+
+	graphQL := app.Group("/graphql", chain...)
+	graphQL.Get("", middleware.RequireScope(types.ScopeBalanceRead), h.GraphQL.Handle)
+	graphQL.Post("", middleware.RequireScope(types.ScopeBalanceRead), h.GraphQL.Handle)
+
+	rpc := app.Group("/rpc", chain...)
+	rpc.Post("", middleware.RequireScope(types.ScopeBalanceRead), h.RPC)
+
+	// Connections outlive any request timeout.
+	ws := app.Group("/ws", chain[1:]...)
+	ws.Get("", middleware.RequireScope(types.ScopeBalanceRead), websocket.New(h.Subscribe))
+
+	api := app.Group("/api", chain...)
+
+	api.Post("/get-balance", middleware.RequireScope(types.ScopeBalanceRead), h.GetBalance)
+	api.Get("/usage", h.GetUsage)
+
+	api.Post("/jobs/balances", middleware.RequireScope(types.ScopeBalanceRead), h.CreateBalanceJob)
+	api.Get("/jobs/:id", middleware.RequireScope(types.ScopeBalanceRead), h.GetBalanceJob)
+	api.Get("/jobs/:id/results", middleware.RequireScope(types.ScopeBalanceRead), h.GetBalanceJobResults)
+
+	// v1 shares the handlers above. Its errors and per-wallet results carry
+	// the codes in types/errors.go.
+	v1 := api.Group("/v1")
+
+	v1.Post("/balances", middleware.RequireScope(types.ScopeBalanceRead), h.GetBalance)
+	v1.Get("/accounts/:address/balance", middleware.RequireScope(types.ScopeBalanceRead), h.GetAccountBalance)
+	v1.Get("/usage", h.GetUsage)
+
+	v1.Post("/jobs/balances", middleware.RequireScope(types.ScopeBalanceRead), h.CreateBalanceJob)
+	v1.Get("/jobs/:id", middleware.RequireScope(types.ScopeBalanceRead), h.GetBalanceJob)
+	v1.Get("/jobs/:id/results", middleware.RequireScope(types.ScopeBalanceRead), h.GetBalanceJobResults)
 
-	api.Use(middleware.RateLimitMiddleware())
-	api.Use(middleware.AuthMiddleware(db))
+	admin := app.Group("/admin", middleware.AdminAuthMiddleware(cfg.AdminAPIKey))
 
-	api.Post("/get-balance", GetBalance)
+	admin.Post("/keys", h.CreateAPIKey)
+	admin.Get("/keys", h.ListAPIKeys)
+	admin.Post("/keys/migrate", h.MigrateAPIKeys)
+	admin.Get("/keys/:id", h.GetAPIKey)
+	admin.Delete("/keys/:id", h.RevokeAPIKey)
+	admin.Post("/keys/:id/rotate", h.RotateAPIKey)
 }
@@ -0,0 +1,142 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/gofiber/fiber/v2"
+
+	"nova/api/apierror"
+	"nova/api/middleware"
+	"nova/api/services"
+	"nova/api/types"
+)
+
+// maxRPCBatch bounds the calls in one POST /rpc body.
+const maxRPCBatch = 100
+
+// RPC serves POST /rpc, a Solana JSON-RPC endpoint for standard SDKs. Every
+// call of a batch takes a rate-limit credit. Calls outside
+// services.RPCMethods or the key's scopes are answered with errors, like
+// any other failed call of the batch.
+func (h *Handlers) RPC(ctx *fiber.Ctx) error {
+	body := bytes.TrimSpace(ctx.Body())
+	batch := len(body) > 0 && body[0] == '['
+
+	// Batch elements are decoded by callRPC, so that a bad element gets
+	// its own Invalid Request rather than failing the whole batch.
+	requests := []json.RawMessage{body}
+	var err error
+	if batch {
+		err = json.Unmarshal(body, &requests)
+	} else if !json.Valid(body) {
+		err = errors.New("invalid JSON")
+	}
+	if err != nil {
+		return ctx.JSON(types.RPCResponse{
+			JSONRPC: "2.0",
+			Error:   &types.RPCError{Code: types.RPCParseError, Message: "Parse error"},
+		})
+	}
+
+	if len(requests) == 0 {
+		return ctx.JSON(types.RPCResponse{
+			JSONRPC: "2.0",
+			Error:   &types.RPCError{Code: types.RPCInvalidRequest, Message: "Invalid request: empty batch"},
+		})
+	}
+	if len(requests) > maxRPCBatch {
+		message := fmt.Sprintf("Too many calls in one batch (max %d)", maxRPCBatch)
+		return apierror.New(fiber.StatusBadRequest, types.CodeInvalidRequest, message).With("max", maxRPCBatch).Send(ctx)
+	}
+
+	principal := ctx.Locals("principal").(*types.Principal)
+	if len(requests) > 1 {
+		limit, limitErr := h.Limiters.SpendPrincipal(principal, h.Config.RateLimitTiers, h.Now(), len(requests)-1)
+		middleware.SetRateLimitHeaders(ctx, limit)
+		if limitErr != nil {
+			return limitErr.With("calls", len(requests)).Send(ctx)
+		}
+	}
+
+	requestID := ctx.GetRespHeader(fiber.HeaderXRequestID)
+	responses := make([]types.RPCResponse, len(requests))
+	sources := make([]string, len(requests))
+
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i], sources[i] = h.callRPC(ctx.UserContext(), principal, requests[i], requestID)
+		}()
+	}
+	wg.Wait()
+
+	var counters types.UsageCounters
+	for _, source := range sources {
+		switch source {
+		case types.SourceCache:
+			counters.CacheHits++
+		case types.SourceRPC:
+			counters.RPCCalls++
+		}
+	}
+	ctx.Locals("usage", counters)
+
+	if !batch {
+		return ctx.JSON(responses[0])
+	}
+	return ctx.JSON(responses)
+}
+
+// callRPC answers one call of a /rpc request. The source is empty for
+// calls rejected before reaching the cache or the node.
+func (h *Handlers) callRPC(ctx context.Context, principal *types.Principal, raw json.RawMessage, requestID string) (types.RPCResponse, string) {
+	var request types.RPCRequest
+	decodeErr := json.Unmarshal(raw, &request)
+	response := types.RPCResponse{JSONRPC: "2.0", ID: request.ID}
+
+	if decodeErr != nil || request.JSONRPC != "2.0" || request.Method == "" {
+		response.Error = &types.RPCError{Code: types.RPCInvalidRequest, Message: "Invalid request"}
+		return response, ""
+	}
+
+	scope, ok := services.RPCMethods[request.Method]
+	if !ok {
+		response.Error = &types.RPCError{Code: types.RPCMethodNotFound, Message: "Method not found"}
+		return response, ""
+	}
+	if scope != "" {
+		if scopeErr := middleware.CheckScope(principal, scope); scopeErr != nil {
+			response.Error = scopeErr.RPC(requestID)
+			return response, ""
+		}
+	}
+
+	var params []json.RawMessage
+	if len(request.Params) > 0 && json.Unmarshal(request.Params, &params) != nil {
+		response.Error = &types.RPCError{Code: types.RPCInvalidParams, Message: "Invalid params: expected an array"}
+		return response, ""
+	}
+
+	result, source, err := h.Solana.CallRPC(ctx, principal.KeyID, request.Method, params)
+
+	var rpcErr *jsonrpc.RPCError
+	switch {
+	case errors.As(err, &rpcErr):
+		response.Error = &types.RPCError{Code: rpcErr.Code, Message: rpcErr.Message, Data: rpcErr.Data}
+	case errors.Is(err, services.ErrLookupTimeout):
+		response.Error = apierror.New(fiber.StatusGatewayTimeout, types.CodeUpstreamTimeout, "Timed out waiting for the Solana RPC").RPC(requestID)
+	case err != nil:
+		response.Error = apierror.New(fiber.StatusBadGateway, types.CodeUpstreamError, "The Solana RPC could not complete the call").RPC(requestID)
+	default:
+		response.Result = result
+	}
+	return response, source
+}
@@ -0,0 +1,116 @@
+package routes
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"nova/api/apierror"
+	"nova/api/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const mimeEventStream = "text/event-stream"
+
+// streamBalances writes each balance as soon as it resolves, as NDJSON or
+// as server-sent events, and ends with a summary. Every record is flushed
+// on its own, so a client that reads slowly holds back the lookups rather
+// than having results buffered for it.
+func (h *Handlers) streamBalances(ctx *fiber.Ctx, principal *types.Principal, wallets []string, contentType string) error {
+	streamCtx, cancel := bodyContext(ctx)
+
+	ctx.Set(fiber.HeaderContentType, contentType)
+	ctx.Set(fiber.HeaderCacheControl, "no-cache")
+	// Stops nginx from buffering the stream.
+	ctx.Set("X-Accel-Buffering", "no")
+
+	start := h.Now()
+	keyID := principal.KeyID
+	v1 := apierror.IsV1(ctx)
+
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+
+		summary := types.BalanceStreamSummary{Wallets: len(wallets)}
+		err := h.Solana.StreamBalances(streamCtx, keyID, wallets, func(index int, balance types.WalletBalance) error {
+			switch {
+			case balance.ErrorCode == types.CodeUpstreamTimeout:
+				summary.Timeouts++
+			case balance.Error != "":
+				summary.Errors++
+			default:
+				summary.Succeeded++
+			}
+			switch balance.Source {
+			case types.SourceCache:
+				summary.CacheHits++
+			case types.SourceRPC:
+				summary.RPCCalls++
+			}
+
+			if v1 {
+				return writeStreamRecord(w, contentType, "balance", types.BalanceResultStreamItem{Index: index, BalanceResult: apierror.BalanceResult(balance)})
+			}
+			return writeStreamRecord(w, contentType, "balance", types.BalanceStreamItem{Index: index, WalletBalance: balance})
+		})
+
+		if err == nil {
+			summary.ElapsedMS = h.Now().Sub(start).Milliseconds()
+			err = writeStreamRecord(w, contentType, "summary", types.BalanceStreamEnd{Summary: summary})
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
+			h.Logger.Printf("Balance stream for %s ended early: %v", keyID, err)
+		}
+
+		h.recordStreamUsage(principal.UsageKey(), summary)
+	})
+
+	return nil
+}
+
+// bodyContext is the context of a body written with SetBodyStreamWriter.
+// The body is written after the handler returns, when the request context
+// has already been cancelled, so only its deadline is kept.
+func bodyContext(ctx *fiber.Ctx) (context.Context, context.CancelFunc) {
+	if deadline, ok := ctx.UserContext().Deadline(); ok {
+		return context.WithDeadline(context.WithoutCancel(ctx.UserContext()), deadline)
+	}
+	return context.WithCancel(context.WithoutCancel(ctx.UserContext()))
+}
+
+func writeStreamRecord(w *bufio.Writer, contentType, event string, record interface{}) error {
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	if contentType == mimeEventStream {
+		w.WriteString("event: " + event + "\ndata: ")
+		w.Write(encoded)
+		w.WriteString("\n\n")
+	} else {
+		w.Write(encoded)
+		w.WriteByte('\n')
+	}
+
+	return w.Flush()
+}
+
+// recordStreamUsage counts the wallets of a stream once it has ended. The
+// usage middleware has already counted the request itself.
+func (h *Handlers) recordStreamUsage(usageKey string, summary types.BalanceStreamSummary) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	counters := types.UsageCounters{
+		Wallets:   int64(summary.Wallets),
+		RPCCalls:  int64(summary.RPCCalls),
+		CacheHits: int64(summary.CacheHits),
+	}
+	if err := h.Usage.Record(ctx, usageKey, counters, h.Now()); err != nil {
+		h.Logger.Printf("Failed to record usage for %s: %v", usageKey, err)
+	}
+}
@@ -0,0 +1,310 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+
+	"nova/api/apierror"
+	"nova/api/middleware"
+	"nova/api/services"
+	"nova/api/types"
+)
+
+const (
+	wsPingInterval = 30 * time.Second
+	wsReadTimeout  = 2 * wsPingInterval
+	wsWriteTimeout = 10 * time.Second
+	// How often a connection's principal is checked again when no key
+	// change has been announced, for changes a missed announcement hid.
+	wsRecheckInterval = time.Minute
+	// Room for a subscribe of the largest tier's MaxWallets.
+	wsReadLimit = 1 << 20
+)
+
+// errWSClosed ends a session that has already been closed with a reason.
+var errWSClosed = errors.New("websocket session closed")
+
+// wsSession is the state of one /ws connection. Only the handler's
+// goroutine writes to the connection.
+type wsSession struct {
+	conn      *websocket.Conn
+	principal *types.Principal
+	sub       *services.Subscriber
+	requestID string
+	// slots are the last slot sent per address, so that a snapshot racing
+	// a push never moves a balance backwards.
+	slots map[string]uint64
+}
+
+// Subscribe serves /ws. Clients send subscribe and unsubscribe messages
+// with a list of addresses, and get each address's finalized balance when
+// it is subscribed and whenever it changes. A connection holds at most the
+// tier's MaxWallets addresses, and every message takes a rate-limit
+// credit. Clients that do not keep up with their updates are disconnected,
+// and so are clients whose key is revoked, rotated or expires while they
+// are connected.
+func (h *Handlers) Subscribe(conn *websocket.Conn) {
+	principal := conn.Locals("principal").(*types.Principal)
+	requestID, _ := conn.Locals("requestid").(string)
+
+	session := &wsSession{
+		conn:      conn,
+		principal: principal,
+		sub:       h.Subscriptions.NewSubscriber(h.tier(principal).MaxWallets),
+		requestID: requestID,
+		slots:     make(map[string]uint64),
+	}
+	defer session.sub.Close()
+
+	// The reader owns the underlying connection until it returns, so it is
+	// waited for before the connection goes back to its pool.
+	messages := make(chan []byte)
+	stopped := make(chan struct{})
+	var reader sync.WaitGroup
+	reader.Add(1)
+	defer func() {
+		close(stopped)
+		conn.Close()
+		reader.Wait()
+	}()
+
+	conn.SetReadLimit(wsReadLimit)
+	conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+	})
+
+	go func() {
+		defer reader.Done()
+		defer close(messages)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+			select {
+			case messages <- data:
+			case <-stopped:
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	recheck := time.NewTicker(wsRecheckInterval)
+	defer recheck.Stop()
+
+	var expired <-chan time.Time
+	if principal.ExpiresAt != nil {
+		expiry := time.NewTimer(principal.ExpiresAt.Sub(h.Now()))
+		defer expiry.Stop()
+		expired = expiry.C
+	}
+	changed := h.Keys.Changed()
+
+	for {
+		var err error
+		select {
+		case data, ok := <-messages:
+			if !ok {
+				return
+			}
+			err = h.handleWSMessage(session, data)
+		case update := <-session.sub.Updates():
+			err = session.sendBalance(update)
+		case <-session.sub.Done():
+			code := websocket.CloseGoingAway
+			apiErr := apierror.New(fiber.StatusServiceUnavailable, types.CodeUnavailable, "The server is shutting down")
+			if errors.Is(session.sub.Err(), services.ErrSlowSubscriber) {
+				code = websocket.ClosePolicyViolation
+				apiErr = apierror.New(fiber.StatusTooManyRequests, types.CodeRateLimited, "Updates were not read fast enough")
+			}
+			session.close(code, apiErr)
+			return
+		case <-changed:
+			changed = h.Keys.Changed()
+			err = h.recheckWS(session)
+		case <-recheck.C:
+			err = h.recheckWS(session)
+		case <-expired:
+			err = h.recheckWS(session)
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (h *Handlers) handleWSMessage(session *wsSession, data []byte) error {
+	if err := h.recheckWS(session); err != nil {
+		return err
+	}
+
+	var request types.WSRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return session.sendError(apierror.New(fiber.StatusBadRequest, types.CodeInvalidRequest, "Messages must be JSON objects"))
+	}
+
+	if _, limitErr := h.Limiters.AllowPrincipal(session.principal, h.Config.RateLimitTiers, h.Now()); limitErr != nil {
+		return session.sendError(limitErr)
+	}
+
+	switch request.Type {
+	case types.WSSubscribe:
+		return h.subscribeWS(session, request.Addresses)
+	case types.WSUnsubscribe:
+		removed := session.sub.Unsubscribe(request.Addresses)
+		for _, address := range removed {
+			delete(session.slots, address)
+		}
+		return session.send(types.WSAck{Type: types.WSUnsubscribed, Addresses: removed})
+	}
+	return session.sendError(apierror.New(fiber.StatusBadRequest, types.CodeInvalidRequest, "Unknown message type").
+		With("type", request.Type))
+}
+
+// subscribeWS subscribes addresses and sends their current balances. Each
+// subscribe counts as a request towards quotas and usage.
+func (h *Handlers) subscribeWS(session *wsSession, requested []string) error {
+	if len(requested) == 0 {
+		return session.sendError(apierror.New(fiber.StatusBadRequest, types.CodeInvalidRequest, "No addresses provided"))
+	}
+
+	if quotaErr := middleware.ConsumeQuota(context.Background(), h.Usage, h.Logger, session.principal, h.Now()); quotaErr != nil {
+		return session.sendError(quotaErr)
+	}
+
+	addresses, err := session.sub.Subscribe(requested)
+	switch {
+	case errors.Is(err, services.ErrTooManySubscriptions):
+		tier := h.tier(session.principal)
+		message := fmt.Sprintf("Too many subscribed addresses (max %d per connection)", tier.MaxWallets)
+		return session.sendError(apierror.New(fiber.StatusBadRequest, types.CodeTooManyWallets, message).With("max", tier.MaxWallets))
+	case errors.Is(err, services.ErrInvalidAddress):
+		return session.sendError(lookupError(err))
+	case err != nil:
+		// The subscriber has ended; Done says why.
+		return nil
+	}
+
+	if err := session.send(types.WSAck{Type: types.WSSubscribed, Addresses: addresses}); err != nil {
+		return err
+	}
+
+	// Addresses are subscribed before their snapshot is read, so a change
+	// in between is pushed rather than missed.
+	ctx, cancel := context.WithTimeout(context.Background(), h.Config.RequestTimeout)
+	defer cancel()
+
+	accounts := make([]*types.AccountBalance, len(addresses))
+	errs := make([]error, len(addresses))
+	var wg sync.WaitGroup
+	for i, address := range addresses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			accounts[i], errs[i] = h.Solana.GetAccountBalance(ctx, session.principal.KeyID, address, rpc.CommitmentFinalized)
+		}()
+	}
+	wg.Wait()
+
+	counters := types.UsageCounters{Wallets: int64(len(addresses))}
+	defer func() {
+		middleware.RecordUsage(h.Usage, h.Logger, session.principal.UsageKey(), counters, h.Now())
+	}()
+
+	for i, account := range accounts {
+		if errs[i] != nil {
+			counters.RPCCalls++
+			if err := session.sendError(lookupError(errs[i]).With("address", addresses[i])); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if account.Source == types.SourceCache {
+			counters.CacheHits++
+		} else {
+			counters.RPCCalls++
+		}
+		err := session.sendBalance(types.BalanceUpdate{
+			Type:     types.WSBalance,
+			Address:  account.Address,
+			Balance:  account.Balance,
+			Lamports: account.Lamports,
+			Slot:     account.Slot,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recheckWS closes the session with an auth error once its principal is no
+// longer valid.
+func (h *Handlers) recheckWS(session *wsSession) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.Config.RequestTimeout)
+	defer cancel()
+
+	if authErr := middleware.Recheck(ctx, h.Keys, session.principal, h.Now()); authErr != nil {
+		session.close(websocket.ClosePolicyViolation, authErr)
+		return errWSClosed
+	}
+	return nil
+}
+
+// close tells the client why the session ends and closes the connection.
+func (s *wsSession) close(code int, apiErr *apierror.Error) {
+	if s.sendError(apiErr) == nil {
+		message := websocket.FormatCloseMessage(code, apiErr.Message)
+		s.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(wsWriteTimeout))
+	}
+}
+
+func (s *wsSession) send(message interface{}) error {
+	s.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return s.conn.WriteJSON(message)
+}
+
+func (s *wsSession) sendBalance(update types.BalanceUpdate) error {
+	if !s.sub.Subscribed(update.Address) || update.Slot < s.slots[update.Address] {
+		return nil
+	}
+	s.slots[update.Address] = update.Slot
+	return s.send(update)
+}
+
+func (s *wsSession) sendError(apiErr *apierror.Error) error {
+	return s.send(types.WSErrorMessage{
+		Type: types.WSError,
+		APIError: types.APIError{
+			Code:      apiErr.Code,
+			Message:   apiErr.Message,
+			Details:   apiErr.Details,
+			RequestID: s.requestID,
+		},
+	})
+}
+
+// tier is the rate-limit tier of principal, falling back to the free tier
+// for unknown names.
+func (h *Handlers) tier(principal *types.Principal) types.RateLimitTier {
+	tier, ok := h.Config.RateLimitTiers[principal.Tier]
+	if !ok {
+		tier = h.Config.RateLimitTiers[types.TierFree]
+	}
+	return tier
+}
@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"time"
+
+	"nova/api/apierror"
+	"nova/api/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *Handlers) GetUsage(ctx *fiber.Ctx) error {
+	principal := ctx.Locals("principal").(*types.Principal)
+	now := h.Now().UTC()
+
+	from, err := parseTimeQuery(ctx, "from", now.Add(-24*time.Hour))
+	if err != nil {
+		return apierror.New(fiber.StatusBadRequest, types.CodeInvalidRequest, "Invalid from timestamp (expected RFC 3339)").Send(ctx)
+	}
+
+	to, err := parseTimeQuery(ctx, "to", now.Add(time.Minute))
+	if err != nil {
+		return apierror.New(fiber.StatusBadRequest, types.CodeInvalidRequest, "Invalid to timestamp (expected RFC 3339)").Send(ctx)
+	}
+
+	if !from.Before(to) {
+		return apierror.New(fiber.StatusBadRequest, types.CodeInvalidRequest, "from must be before to").Send(ctx)
+	}
+
+	report, err := h.Usage.Report(ctx.UserContext(), principal, from, to, now)
+	if err != nil {
+		return apierror.New(fiber.StatusInternalServerError, types.CodeInternal, "Database error").Send(ctx)
+	}
+
+	return ctx.JSON(types.UsageResponse{
+		Success: true,
+		Data:    report,
+	})
+}
+
+func parseTimeQuery(ctx *fiber.Ctx, name string, fallback time.Time) (time.Time, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return fallback, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"google.golang.org/grpc"
+
+	"nova/api/apierror"
+	"nova/api/graphqlapi"
+	"nova/api/grpcapi"
+	"nova/api/metrics"
+	"nova/api/middleware"
+	"nova/api/routes"
+	"nova/api/services"
+	"nova/api/store"
+	"nova/api/types"
+)
+
+var ErrMissingConfig = errors.New("a config is required to build the server")
+
+type Option func(*Server)
+
+func WithConfig(cfg *types.Config) Option {
+	return func(s *Server) { s.cfg = cfg }
+}
+
+// WithStores defaults to in-memory stores when not given.
+func WithStores(stores *store.Stores) Option {
+	return func(s *Server) { s.stores = stores }
+}
+
+// WithSolanaClient defaults to an RPC client for the configured URL.
+func WithSolanaClient(client services.RPCClient) Option {
+	return func(s *Server) { s.rpc = client }
+}
+
+func WithLogger(logger *log.Logger) Option {
+	return func(s *Server) { s.logger = logger }
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) { s.now = now }
+}
+
+// Server is one self-contained instance of the API. Several servers can run
+// in the same process without sharing rate limiters or caches.
+type Server struct {
+	cfg    *types.Config
+	stores *store.Stores
+	rpc    services.RPCClient
+	logger *log.Logger
+	now    func() time.Time
+
+	solana *services.SolanaService
+	keys   *services.KeyService
+	usage  *services.UsageService
+	jobs   *services.JobService
+	subs   *services.SubscriptionService
+	app    *fiber.App
+	grpc   *grpc.Server
+}
+
+func NewServer(opts ...Option) (*Server, error) {
+	s := &Server{
+		logger: log.Default(),
+		now:    time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.cfg == nil {
+		return nil, ErrMissingConfig
+	}
+	if s.stores == nil {
+		s.stores = store.NewMemory()
+	}
+	if s.rpc == nil {
+		s.rpc = rpc.New(s.cfg.SolaanRPCURL)
+	}
+
+	s.solana = services.NewSolanaServiceWithClient(s.rpc, s.stores.Balances, services.NewLookupPool(s.cfg.RPCConcurrency))
+	keys, err := services.NewKeyService(s.cfg, s.stores)
+	if err != nil {
+		return nil, err
+	}
+	s.keys = keys
+	s.usage = services.NewUsageService(s.stores)
+	s.jobs = services.NewJobService(s.stores.Jobs, s.solana, s.usage, s.logger, s.now)
+	s.subs = services.NewSubscriptionService(s.cfg.SolanaWSURL, s.solana, s.logger)
+
+	s.app = fiber.New(fiber.Config{
+		DisableStartupMessage: true,
+		JSONEncoder:           json.Marshal,
+		JSONDecoder:           json.Unmarshal,
+		// The client IP is read from ProxyHeader only on requests from
+		// TrustedProxies, so clients cannot spoof it to dodge IP limits.
+		ProxyHeader:             s.cfg.ProxyHeader,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          s.cfg.TrustedProxies,
+		// Large enough for a balance job of MaxJobWallets addresses.
+		BodyLimit:    8 * 1024 * 1024,
+		ErrorHandler: apierror.Handler,
+	})
+
+	jwt := services.NewJWTVerifier(s.cfg)
+	limiters := middleware.NewRateLimiters()
+	grpcRequests := metrics.NewRequests()
+
+	s.app.Use(requestid.New())
+	s.app.Use(recover.New())
+	s.app.Use(logger.New(logger.Config{Output: s.logger.Writer()}))
+
+	routes.New(routes.Deps{
+		Config:       s.cfg,
+		Solana:       s.solana,
+		Keys:         s.keys,
+		JWT:          jwt,
+		Usage:        s.usage,
+		Jobs:         s.jobs,
+		Limiters:     limiters,
+		GRPCRequests: grpcRequests,
+		GraphQL: graphqlapi.NewServer(graphqlapi.Deps{
+			Config:   s.cfg,
+			Solana:   s.solana,
+			Limiters: limiters,
+			Now:      s.now,
+		}),
+		Subscriptions: s.subs,
+		Logger:        s.logger,
+		Now:           s.now,
+	}).Register(s.app)
+
+	// The gRPC server shares the limiters, so a client has one budget
+	// across both.
+	s.grpc = grpcapi.NewServer(grpcapi.Deps{
+		Config:   s.cfg,
+		Solana:   s.solana,
+		Keys:     s.keys,
+		JWT:      jwt,
+		Usage:    s.usage,
+		Limiters: limiters,
+		Metrics:  grpcRequests,
+		Logger:   s.logger,
+		Now:      s.now,
+	})
+
+	return s, nil
+}
+
+func (s *Server) App() *fiber.App {
+	return s.app
+}
+
+// Handler adapts the server to net/http for embedding in other servers.
+func (s *Server) Handler() http.Handler {
+	return adaptor.FiberApp(s.app)
+}
+
+// GRPC is the gRPC balance service, for serving on a listener of the
+// caller's choosing.
+func (s *Server) GRPC() *grpc.Server {
+	return s.grpc
+}
+
+func (s *Server) Solana() *services.SolanaService {
+	return s.solana
+}
+
+func (s *Server) Keys() *services.KeyService {
+	return s.keys
+}
+
+func (s *Server) Usage() *services.UsageService {
+	return s.usage
+}
+
+func (s *Server) Jobs() *services.JobService {
+	return s.jobs
+}
+
+// Run listens for key revocations, flushes usage and processes balance jobs
+// until ctx is done.
+func (s *Server) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	go func() {
+		defer wg.Done()
+		s.keys.ListenForRevocations(ctx)
+	}()
+
+	go func() {
+		defer wg.Done()
+		s.usage.Run(ctx)
+	}()
+
+	go func() {
+		defer wg.Done()
+		s.jobs.Run(ctx)
+	}()
+
+	wg.Wait()
+}
+
+func (s *Server) Listen(addr string) error {
+	return s.app.Listen(addr)
+}
+
+func (s *Server) ListenGRPC(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.grpc.Serve(listener)
+}
+
+// Shutdown also ends every /ws connection, which the HTTP server hands off
+// once upgraded and does not wait for.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.grpc.GracefulStop()
+	s.subs.Close()
+	return s.app.ShutdownWithContext(ctx)
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"nova/api/types"
+)
+
+const (
+	// MaxSignatures is the most signatures getSignaturesForAddress returns
+	// in one call.
+	MaxSignatures = 1000
+
+	// withdrawerOffset is where the withdraw authority sits in a stake
+	// account: after the 4-byte state tag, the 8-byte rent reserve and the
+	// 32-byte staker.
+	withdrawerOffset = 44
+)
+
+// GetAccounts reads addresses with getMultipleAccounts, one call per chunk
+// of balanceChunkSize, and returns a result for each address in order
+// together with the number of calls made. Invalid addresses and failed
+// chunks are reported on their results.
+func (s *SolanaService) GetAccounts(ctx context.Context, keyID string, addresses []string) ([]types.AccountInfo, int) {
+	results := make([]types.AccountInfo, len(addresses))
+	fail := func(index int, err error) {
+		if ctx.Err() != nil {
+			err = ErrLookupTimeout
+		}
+		results[index].Error = err.Error()
+		results[index].ErrorCode = ErrorCode(err)
+	}
+
+	keys := make([]solana.PublicKey, 0, len(addresses))
+	indexes := make([]int, 0, len(addresses))
+	for i, address := range addresses {
+		results[i].Address = address
+		key, err := parseAddress(address)
+		if err != nil {
+			fail(i, err)
+			continue
+		}
+		keys = append(keys, key)
+		indexes = append(indexes, i)
+	}
+
+	calls := 0
+	for start := 0; start < len(keys); start += balanceChunkSize {
+		end := min(start+balanceChunkSize, len(keys))
+		chunk := indexes[start:end]
+
+		if err := s.pool.Acquire(ctx, keyID); err != nil {
+			for _, index := range chunk {
+				fail(index, ErrLookupTimeout)
+			}
+			continue
+		}
+		out, err := s.fetchAccounts(ctx, keys[start:end])
+		s.pool.Release()
+		calls++
+
+		if err == nil && len(out.Value) != len(chunk) {
+			err = fmt.Errorf("getMultipleAccounts returned %d accounts for %d addresses", len(out.Value), len(chunk))
+		}
+		for i, index := range chunk {
+			if err != nil {
+				fail(index, err)
+				continue
+			}
+
+			account := out.Value[i]
+			if account == nil {
+				continue
+			}
+			results[index].Lamports = account.Lamports
+			results[index].Balance = lamportsToSOL(account.Lamports)
+			results[index].Owner = account.Owner.String()
+			results[index].Executable = account.Executable
+			results[index].Exists = true
+		}
+	}
+
+	return results, calls
+}
+
+func (s *SolanaService) fetchAccounts(ctx context.Context, keys []solana.PublicKey) (*rpc.GetMultipleAccountsResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// Only the lamports and owner are needed, so skip the data.
+	zero := uint64(0)
+	out, err := s.client.GetMultipleAccountsWithOpts(ctx, keys, &rpc.GetMultipleAccountsOpts{
+		Commitment: rpc.CommitmentFinalized,
+		Encoding:   solana.EncodingBase64,
+		DataSlice:  &rpc.DataSlice{Offset: &zero, Length: &zero},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %d accounts: %w", len(keys), err)
+	}
+	return out, nil
+}
+
+// GetSignatures returns up to limit of the address's most recent
+// transaction signatures, newest first, starting before the given
+// signature when it is not empty.
+func (s *SolanaService) GetSignatures(ctx context.Context, keyID, address string, limit int, before string) ([]types.Signature, error) {
+	pubKey, err := parseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &rpc.GetSignaturesForAddressOpts{
+		Limit:      &limit,
+		Commitment: rpc.CommitmentFinalized,
+	}
+	if before != "" {
+		opts.Before, err = solana.SignatureFromBase58(before)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidCursor, before)
+		}
+	}
+
+	if err := s.pool.Acquire(ctx, keyID); err != nil {
+		return nil, ErrLookupTimeout
+	}
+	rpcCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	out, err := s.client.GetSignaturesForAddressWithOpts(rpcCtx, pubKey, opts)
+	cancel()
+	s.pool.Release()
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ErrLookupTimeout
+		}
+		return nil, fmt.Errorf("failed to get signatures for %s: %w", address, err)
+	}
+
+	signatures := make([]types.Signature, len(out))
+	for i, signature := range out {
+		signatures[i] = types.Signature{
+			Signature: signature.Signature.String(),
+			Slot:      signature.Slot,
+			Failed:    signature.Err != nil,
+		}
+		if signature.BlockTime != nil {
+			blockTime := signature.BlockTime.Time().UTC()
+			signatures[i].BlockTime = &blockTime
+		}
+		if signature.Memo != nil {
+			signatures[i].Memo = *signature.Memo
+		}
+	}
+
+	return signatures, nil
+}
+
+type parsedStakeAccount struct {
+	Parsed struct {
+		Type string `json:"type"`
+		Info struct {
+			Meta struct {
+				Authorized struct {
+					Staker     string `json:"staker"`
+					Withdrawer string `json:"withdrawer"`
+				} `json:"authorized"`
+			} `json:"meta"`
+			Stake *struct {
+				Delegation struct {
+					Voter             string `json:"voter"`
+					Stake             string `json:"stake"`
+					ActivationEpoch   string `json:"activationEpoch"`
+					DeactivationEpoch string `json:"deactivationEpoch"`
+				} `json:"delegation"`
+			} `json:"stake"`
+		} `json:"info"`
+	} `json:"parsed"`
+}
+
+// GetStakeAccounts returns the stake accounts the address can withdraw
+// from.
+func (s *SolanaService) GetStakeAccounts(ctx context.Context, keyID, address string) ([]types.StakeAccount, error) {
+	pubKey, err := parseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.pool.Acquire(ctx, keyID); err != nil {
+		return nil, ErrLookupTimeout
+	}
+	rpcCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	out, err := s.client.GetProgramAccountsWithOpts(rpcCtx, solana.StakeProgramID, &rpc.GetProgramAccountsOpts{
+		Commitment: rpc.CommitmentFinalized,
+		Encoding:   solana.EncodingJSONParsed,
+		Filters: []rpc.RPCFilter{{
+			Memcmp: &rpc.RPCFilterMemcmp{Offset: withdrawerOffset, Bytes: solana.Base58(pubKey.Bytes())},
+		}},
+	})
+	cancel()
+	s.pool.Release()
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ErrLookupTimeout
+		}
+		return nil, fmt.Errorf("failed to get stake accounts for %s: %w", address, err)
+	}
+
+	stakes := make([]types.StakeAccount, 0, len(out))
+	for _, keyed := range out {
+		if keyed.Account == nil || keyed.Account.Data == nil {
+			continue
+		}
+
+		var parsed parsedStakeAccount
+		if err := json.Unmarshal(keyed.Account.Data.GetRawJSON(), &parsed); err != nil {
+			return nil, fmt.Errorf("failed to parse stake account %s: %w", keyed.Pubkey.String(), err)
+		}
+
+		info := parsed.Parsed.Info
+		stake := types.StakeAccount{
+			Address:    keyed.Pubkey.String(),
+			Lamports:   keyed.Account.Lamports,
+			Balance:    lamportsToSOL(keyed.Account.Lamports),
+			State:      parsed.Parsed.Type,
+			Staker:     info.Meta.Authorized.Staker,
+			Withdrawer: info.Meta.Authorized.Withdrawer,
+		}
+		if info.Stake != nil {
+			delegation := info.Stake.Delegation
+			stake.Voter = delegation.Voter
+			stake.DelegatedStake, _ = strconv.ParseUint(delegation.Stake, 10, 64)
+			stake.ActivationEpoch = parseEpoch(delegation.ActivationEpoch)
+			stake.DeactivationEpoch = parseEpoch(delegation.DeactivationEpoch)
+		}
+		stakes = append(stakes, stake)
+	}
+
+	return stakes, nil
+}
+
+// parseEpoch returns nil for an unset epoch, which the stake program
+// stores as the largest uint64.
+func parseEpoch(value string) *uint64 {
+	epoch, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || epoch == ^uint64(0) {
+		return nil
+	}
+	return &epoch
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"context"
+)
+
+// PurgeBalanceCache drops every cached wallet balance, locally and in the
+// shared cache.
+func (s *SolanaService) PurgeBalanceCache(ctx context.Context) (int, error) {
+	s.cache.Range(func(key, value interface{}) bool {
+		s.cache.Delete(key)
+		return true
+	})
+
+	return s.balances.Purge(ctx)
+}
+
+// PurgeKeyCache drops every cached API key status and tells the other
+// instances to evict theirs.
+func (s *KeyService) PurgeKeyCache(ctx context.Context) (int, error) {
+	s.clearLocal()
+
+	purged, err := s.cache.Purge(ctx)
+	for _, cacheKey := range purged {
+		s.cache.Publish(ctx, cacheKey)
+	}
+
+	return len(purged), err
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+
+	"nova/api/types"
+)
+
+// RPCMethods are the methods POST /rpc forwards, with the scope each needs
+// on top of balance:read. getProgramAccounts and getLargestAccounts are
+// left out because one call can scan a whole program or the whole ledger.
+var RPCMethods = map[string]string{
+	"getAccountInfo":                    "",
+	"getBalance":                        "",
+	"getBlock":                          "",
+	"getBlockCommitment":                "",
+	"getBlockHeight":                    "",
+	"getBlockProduction":                "",
+	"getBlockTime":                      "",
+	"getBlocks":                         "",
+	"getBlocksWithLimit":                "",
+	"getClusterNodes":                   "",
+	"getEpochInfo":                      "",
+	"getEpochSchedule":                  "",
+	"getFeeForMessage":                  "",
+	"getFirstAvailableBlock":            "",
+	"getGenesisHash":                    "",
+	"getHealth":                         "",
+	"getHighestSnapshotSlot":            "",
+	"getIdentity":                       "",
+	"getInflationGovernor":              "",
+	"getInflationRate":                  "",
+	"getInflationReward":                "",
+	"getLatestBlockhash":                "",
+	"getLeaderSchedule":                 "",
+	"getMinimumBalanceForRentExemption": "",
+	"getMultipleAccounts":               "",
+	"getRecentPerformanceSamples":       "",
+	"getRecentPrioritizationFees":       "",
+	"getSignatureStatuses":              "",
+	"getSignaturesForAddress":           "",
+	"getSlot":                           "",
+	"getSlotLeader":                     "",
+	"getSlotLeaders":                    "",
+	"getStakeMinimumDelegation":         "",
+	"getSupply":                         "",
+	"getTransaction":                    "",
+	"getTransactionCount":               "",
+	"getVersion":                        "",
+	"getVoteAccounts":                   "",
+	"isBlockhashValid":                  "",
+	"minimumLedgerSlot":                 "",
+	"simulateTransaction":               "",
+	"getTokenAccountBalance":            types.ScopeTokensRead,
+	"getTokenAccountsByDelegate":        types.ScopeTokensRead,
+	"getTokenAccountsByOwner":           types.ScopeTokensRead,
+	"getTokenLargestAccounts":           types.ScopeTokensRead,
+	"getTokenSupply":                    types.ScopeTokensRead,
+	"sendTransaction":                   types.ScopeTxSend,
+}
+
+// cachedRPCMethods are served from cache. Finalized account state is kept
+// as long as balances; anything else moves with every slot. getBalance is
+// served from the balance cache instead, see rpcGetBalance.
+var cachedRPCMethods = map[string]bool{
+	"getAccountInfo":      true,
+	"getMultipleAccounts": true,
+	"getSlot":             true,
+}
+
+const (
+	slotCacheTTL = 400 * time.Millisecond
+
+	// maxRPCResults bounds the cached /rpc results. Keys come from client
+	// params, so once it is reached new results are not cached until
+	// cleanup frees room.
+	maxRPCResults = 10_000
+)
+
+type rpcCacheEntry struct {
+	result  json.RawMessage
+	expires time.Time
+}
+
+// CallRPC makes one JSON-RPC call through the lookup pool and returns its
+// raw result. Results of cachedRPCMethods are cached by method, commitment
+// and params; the source says whether the result came from the cache.
+// Errors returned by the node are *jsonrpc.RPCError.
+func (s *SolanaService) CallRPC(ctx context.Context, keyID, method string, params []json.RawMessage) (json.RawMessage, string, error) {
+	s.cleanupIfNeeded()
+
+	if method == "getBalance" {
+		if address, commitment, ok := balanceParams(params); ok {
+			return s.rpcGetBalance(ctx, keyID, address, commitment)
+		}
+	}
+
+	key, ttl, cacheable := rpcCacheKey(method, params)
+	if cacheable {
+		if cached, ok := s.rpcResults.Load(key); ok {
+			entry := cached.(*rpcCacheEntry)
+			if time.Now().Before(entry.expires) {
+				return entry.result, types.SourceCache, nil
+			}
+		}
+	}
+
+	args := make([]interface{}, len(params))
+	for i, param := range params {
+		args[i] = param
+	}
+
+	if err := s.pool.Acquire(ctx, keyID); err != nil {
+		return nil, "", ErrLookupTimeout
+	}
+	rpcCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	var result json.RawMessage
+	err := s.client.RPCCallForInto(rpcCtx, &result, method, args)
+	cancel()
+	s.pool.Release()
+
+	if err != nil {
+		var rpcErr *jsonrpc.RPCError
+		if errors.As(err, &rpcErr) {
+			return nil, types.SourceRPC, rpcErr
+		}
+		if ctx.Err() != nil {
+			return nil, types.SourceRPC, ErrLookupTimeout
+		}
+		return nil, types.SourceRPC, fmt.Errorf("failed to call %s: %w", method, err)
+	}
+
+	if cacheable {
+		s.storeRPCResult(key, &rpcCacheEntry{result: result, expires: time.Now().Add(ttl)})
+	}
+	return result, types.SourceRPC, nil
+}
+
+// rpcGetBalance answers getBalance through GetAccountBalance, so that /rpc
+// shares balances with the REST, gRPC and WebSocket lookups.
+func (s *SolanaService) rpcGetBalance(ctx context.Context, keyID, address string, commitment rpc.CommitmentType) (json.RawMessage, string, error) {
+	account, err := s.GetAccountBalance(ctx, keyID, address, commitment)
+	if err != nil {
+		return nil, types.SourceRPC, err
+	}
+
+	var out rpc.GetBalanceResult
+	out.Context.Slot = account.Slot
+	out.Value = account.Lamports
+	result, err := json.Marshal(out)
+	if err != nil {
+		return nil, "", err
+	}
+	return result, account.Source, nil
+}
+
+// balanceParams reads the address and commitment of getBalance params. It
+// reports false for params it does not understand, which are forwarded as
+// they are and left for the node to reject.
+func balanceParams(params []json.RawMessage) (string, rpc.CommitmentType, bool) {
+	var address string
+	if len(params) == 0 || len(params) > 2 || json.Unmarshal(params[0], &address) != nil {
+		return "", "", false
+	}
+	if _, err := parseAddress(address); err != nil {
+		return "", "", false
+	}
+
+	commitment := rpc.CommitmentFinalized
+	if len(params) == 2 {
+		var config map[string]json.RawMessage
+		if json.Unmarshal(params[1], &config) != nil {
+			return "", "", false
+		}
+		for name, value := range config {
+			if name != "commitment" || json.Unmarshal(value, &commitment) != nil {
+				return "", "", false
+			}
+		}
+	}
+
+	switch commitment {
+	case rpc.CommitmentFinalized, rpc.CommitmentConfirmed, rpc.CommitmentProcessed:
+		return address, commitment, true
+	case "":
+		return address, rpc.CommitmentFinalized, true
+	}
+	return "", "", false
+}
+
+// storeRPCResult caches a result unless maxRPCResults are already cached.
+func (s *SolanaService) storeRPCResult(key string, entry *rpcCacheEntry) {
+	if _, ok := s.rpcResults.Load(key); !ok && s.rpcResultCount.Load() >= maxRPCResults {
+		return
+	}
+	if _, loaded := s.rpcResults.Swap(key, entry); !loaded {
+		s.rpcResultCount.Add(1)
+	}
+}
+
+func (s *SolanaService) deleteRPCResult(key interface{}) {
+	if _, loaded := s.rpcResults.LoadAndDelete(key); loaded {
+		s.rpcResultCount.Add(-1)
+	}
+}
+
+// rpcCacheKey keys a call by its method, commitment and the rest of its
+// params, so that calls differing only in how they spell the default
+// commitment share an entry.
+func rpcCacheKey(method string, params []json.RawMessage) (string, time.Duration, bool) {
+	if !cachedRPCMethods[method] {
+		return "", 0, false
+	}
+
+	commitment := string(rpc.CommitmentFinalized)
+	parts := make([]string, len(params))
+	for i, param := range params {
+		var config map[string]interface{}
+		if json.Unmarshal(param, &config) != nil || config == nil {
+			var compact bytes.Buffer
+			if json.Compact(&compact, param) != nil {
+				return "", 0, false
+			}
+			parts[i] = compact.String()
+			continue
+		}
+
+		if value, ok := config["commitment"]; ok {
+			name, ok := value.(string)
+			if !ok {
+				return "", 0, false
+			}
+			commitment = name
+			delete(config, "commitment")
+		}
+		canonical, _ := json.Marshal(config)
+		parts[i] = string(canonical)
+	}
+	for len(parts) > 0 && parts[len(parts)-1] == "{}" {
+		parts = parts[:len(parts)-1]
+	}
+
+	ttl := slotCacheTTL
+	if method != "getSlot" && commitment == string(rpc.CommitmentFinalized) {
+		ttl = BalanceCacheTTL
+	}
+	return method + ":" + commitment + ":" + strings.Join(parts, ","), ttl, true
+}
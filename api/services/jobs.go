@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"nova/api/store"
+	"nova/api/types"
+)
+
+const (
+	MaxJobWallets = 100_000
+	jobLease      = time.Minute
+	jobPollEvery  = time.Second
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobEmpty    = errors.New("no wallets provided")
+	ErrJobTooLarge = fmt.Errorf("jobs are limited to %d wallets", MaxJobWallets)
+	ErrJobNotReady = errors.New("job has not completed yet")
+)
+
+// JobService runs balance jobs in the background through the SolanaService
+// and its lookup pool. Results and usage are stored after every chunk, so a
+// job cut short by a restart resumes where it stopped once its lease runs
+// out.
+type JobService struct {
+	jobs   store.JobStore
+	solana *SolanaService
+	usage  *UsageService
+	logger *log.Logger
+	now    func() time.Time
+	owner  string
+	wake   chan struct{}
+}
+
+func NewJobService(jobs store.JobStore, solana *SolanaService, usage *UsageService, logger *log.Logger, now func() time.Time) *JobService {
+	hostname, _ := os.Hostname()
+
+	return &JobService{
+		jobs:   jobs,
+		solana: solana,
+		usage:  usage,
+		logger: logger,
+		now:    now,
+		owner:  fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), bson.NewObjectID().Hex()),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+func (s *JobService) Create(ctx context.Context, keyID string, addresses []string) (*types.BalanceJob, error) {
+	wallets := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		if address = strings.TrimSpace(address); address != "" {
+			wallets = append(wallets, address)
+		}
+	}
+
+	if len(wallets) == 0 {
+		return nil, ErrJobEmpty
+	}
+	if len(wallets) > MaxJobWallets {
+		return nil, ErrJobTooLarge
+	}
+
+	job := &types.BalanceJob{
+		ID:        bson.NewObjectID(),
+		KeyID:     keyID,
+		Status:    types.JobQueued,
+		Total:     len(wallets),
+		CreatedAt: s.now().UTC(),
+	}
+
+	var chunks []types.JobChunk
+	for start := 0; start < len(wallets); start += balanceChunkSize {
+		chunks = append(chunks, types.JobChunk{
+			JobID:     job.ID,
+			Index:     len(chunks),
+			Addresses: wallets[start:min(start+balanceChunkSize, len(wallets))],
+			CreatedAt: job.CreatedAt,
+		})
+	}
+	job.Chunks = len(chunks)
+
+	if err := s.jobs.Create(ctx, job, chunks); err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return job, nil
+}
+
+// Get only returns jobs created by keyID.
+func (s *JobService) Get(ctx context.Context, keyID, id string) (*types.BalanceJob, error) {
+	objectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrJobNotFound
+	}
+
+	job, err := s.jobs.Get(ctx, objectID)
+	if err == store.ErrNotFound || (err == nil && job.KeyID != keyID) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	return job, nil
+}
+
+// Results visits the results of a completed job chunk by chunk, in the
+// order the wallets were submitted.
+func (s *JobService) Results(ctx context.Context, job *types.BalanceJob, visit func([]types.WalletBalance) error) error {
+	if job.Status != types.JobCompleted {
+		return ErrJobNotReady
+	}
+	return s.jobs.Results(ctx, job.ID, visit)
+}
+
+// Run processes jobs one at a time until ctx is done.
+func (s *JobService) Run(ctx context.Context) {
+	ticker := time.NewTicker(jobPollEvery)
+	defer ticker.Stop()
+
+	for {
+		for s.runNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// runNext reports whether it finished a job, in which case there may be
+// more waiting.
+func (s *JobService) runNext(ctx context.Context) bool {
+	now := s.now()
+	job, err := s.jobs.Claim(ctx, s.owner, now, now.Add(jobLease))
+	if err != nil {
+		if err != store.ErrNotFound && ctx.Err() == nil {
+			s.logger.Printf("Failed to claim balance job: %v", err)
+		}
+		return false
+	}
+
+	if err := s.process(ctx, job); err != nil {
+		if ctx.Err() == nil {
+			s.logger.Printf("Balance job %s stopped: %v", job.ID.Hex(), err)
+		}
+		return false
+	}
+
+	return true
+}
+
+func (s *JobService) process(ctx context.Context, job *types.BalanceJob) error {
+	chunks, err := s.jobs.PendingChunks(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range chunks {
+		results := s.solana.GetMultipleBalances(ctx, job.KeyID, chunk.Addresses)
+		if ctx.Err() != nil {
+			// Lookups cut short by shutdown are timeouts, not results.
+			return ctx.Err()
+		}
+		s.recordUsage(ctx, job, results)
+
+		if err := s.jobs.CompleteChunk(ctx, job.ID, s.owner, chunk.Index, results); err != nil {
+			return err
+		}
+		if err := s.jobs.Extend(ctx, job.ID, s.owner, s.now().Add(jobLease)); err != nil {
+			return err
+		}
+	}
+
+	return s.jobs.Finish(ctx, job.ID, s.owner, s.now().UTC())
+}
+
+// recordUsage counts the lookups of one chunk for the job's key. The
+// request that created the job already counted its wallets.
+func (s *JobService) recordUsage(ctx context.Context, job *types.BalanceJob, results []types.WalletBalance) {
+	var counters types.UsageCounters
+	for _, result := range results {
+		switch result.Source {
+		case types.SourceCache:
+			counters.CacheHits++
+		case types.SourceRPC:
+			counters.RPCCalls++
+		}
+	}
+
+	if err := s.usage.Record(ctx, job.KeyID, counters, s.now()); err != nil {
+		s.logger.Printf("Failed to record usage of balance job %s: %v", job.ID.Hex(), err)
+	}
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+
+	"nova/api/types"
+)
+
+const (
+	jwksRefreshInterval = 10 * time.Minute
+	jwksMinRefetch      = 30 * time.Second
+)
+
+var ErrInvalidToken = errors.New("invalid bearer token")
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type JWTVerifier struct {
+	jwksFile   string
+	jwksURL    string
+	issuer     string
+	audience   string
+	tierClaim  string
+	httpClient *http.Client
+
+	// refresh collapses concurrent refreshes into one fetch.
+	refresh singleflight.Group
+
+	mu        sync.RWMutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+	// attemptedAt is the time of the last fetch, successful or not.
+	attemptedAt time.Time
+}
+
+// NewJWTVerifier returns nil when neither a JWKS file nor URL is configured,
+// in which case bearer tokens are not accepted.
+func NewJWTVerifier(cfg *types.Config) *JWTVerifier {
+	if cfg.JWKSFile == "" && cfg.JWKSURL == "" {
+		return nil
+	}
+
+	tierClaim := cfg.JWTTierClaim
+	if tierClaim == "" {
+		tierClaim = "nova_tier"
+	}
+
+	return &JWTVerifier{
+		jwksFile:   cfg.JWKSFile,
+		jwksURL:    cfg.JWKSURL,
+		issuer:     cfg.JWTIssuer,
+		audience:   cfg.JWTAudience,
+		tierClaim:  tierClaim,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (v *JWTVerifier) Verify(ctx context.Context, token string, now time.Time) (*types.Principal, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+	}
+	if v.issuer != "" {
+		options = append(options, jwt.WithIssuer(v.issuer))
+	}
+	if v.audience != "" {
+		options = append(options, jwt.WithAudience(v.audience))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.key(ctx, kid)
+	}, options...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing sub claim", ErrInvalidToken)
+	}
+
+	principal := &types.Principal{
+		KeyID:  "jwt:" + subject,
+		Owner:  subject,
+		Tier:   types.TierFree,
+		Scopes: scopesFromClaims(claims),
+	}
+
+	if tier, ok := claims[v.tierClaim].(string); ok && types.IsValidTier(tier) {
+		principal.Tier = tier
+	}
+
+	if expiresAt, err := claims.GetExpirationTime(); err == nil && expiresAt != nil {
+		principal.ExpiresAt = &expiresAt.Time
+	}
+
+	return principal, nil
+}
+
+// scopesFromClaims accepts both the OAuth space-separated "scope" claim and
+// array-valued "scp" / "scopes" claims. Unknown scopes are ignored.
+func scopesFromClaims(claims jwt.MapClaims) []string {
+	var raw []string
+
+	if scope, ok := claims["scope"].(string); ok {
+		raw = append(raw, strings.Fields(scope)...)
+	}
+
+	for _, name := range []string{"scp", "scopes"} {
+		if values, ok := claims[name].([]interface{}); ok {
+			for _, value := range values {
+				if scope, ok := value.(string); ok {
+					raw = append(raw, scope)
+				}
+			}
+		}
+	}
+
+	scopes := make([]string, 0, len(raw))
+	for _, scope := range raw {
+		if types.IsValidScope(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if len(scopes) == 0 {
+		return types.DefaultScopes
+	}
+
+	return scopes
+}
+
+func (v *JWTVerifier) key(ctx context.Context, kid string) (interface{}, error) {
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	stale := time.Since(v.fetchedAt) > jwksRefreshInterval
+	recent := time.Since(v.attemptedAt) < jwksMinRefetch
+	v.mu.RUnlock()
+
+	if ok && (!stale || recent) {
+		return key, nil
+	}
+
+	// Unknown key ids trigger a refetch so that signing key rotation is picked
+	// up, but no more often than jwksMinRefetch, whether or not the last
+	// fetch succeeded.
+	if !ok && recent {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	if err := v.Refresh(ctx); err != nil {
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// Refresh reloads the signing keys. Keys that cannot be used, such as those
+// of an unsupported type or curve, are skipped; it fails only when none are
+// left. Concurrent calls share one fetch.
+func (v *JWTVerifier) Refresh(ctx context.Context) error {
+	_, err, _ := v.refresh.Do("jwks", func() (interface{}, error) {
+		// One caller giving up must not fail the others.
+		return nil, v.load(context.WithoutCancel(ctx))
+	})
+	return err
+}
+
+func (v *JWTVerifier) load(ctx context.Context) error {
+	data, err := v.fetch(ctx)
+
+	v.mu.Lock()
+	v.attemptedAt = time.Now()
+	v.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("failed to load JWKS: %w", err)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	var errs []error
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("JWK %q: %w", jwk.Kid, err))
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	if len(keys) == 0 && len(errs) > 0 {
+		return fmt.Errorf("JWKS has no usable signing keys: %w", errors.Join(errs...))
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS has no signing keys")
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+
+	return nil
+}
+
+func (v *JWTVerifier) fetch(ctx context.Context) ([]byte, error) {
+	if v.jwksFile != "" {
+		return os.ReadFile(v.jwksFile)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
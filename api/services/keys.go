@@ -0,0 +1,635 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"nova/api/signing"
+	"nova/api/store"
+	"nova/api/types"
+)
+
+const (
+	APIKeyPrefix = "nova_live_"
+
+	validKeyTTL   = 15 * time.Minute
+	invalidKeyTTL = 5 * time.Minute
+	localKeyTTL   = 1 * time.Minute
+)
+
+var (
+	ErrKeyNotFound   = errors.New("API key not found")
+	ErrKeyRevoked    = errors.New("API key is revoked")
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	ErrInvalidTier   = errors.New("invalid tier")
+	ErrInvalidID     = errors.New("invalid key id")
+	ErrInvalidExpiry = errors.New("expires_at must be in the future")
+	ErrInvalidScope  = errors.New("invalid scope")
+	ErrInvalidNet    = errors.New("invalid network")
+	ErrInvalidIPRule = errors.New("invalid IP or CIDR in allowed_ips")
+	ErrInvalidOrigin = errors.New("invalid origin in allowed_origins")
+	ErrInvalidQuota  = errors.New("quotas must not be negative")
+
+	ErrInvalidSignature = errors.New("invalid request signature")
+	ErrSignatureExpired = errors.New("request timestamp is outside the allowed clock skew")
+	ErrReplayedNonce    = errors.New("request nonce has already been used")
+)
+
+type KeyService struct {
+	keys    store.KeyStore
+	cache   store.KeyCache
+	secrets *SecretBox
+	maxSkew time.Duration
+	local   sync.Map
+
+	changedMu sync.Mutex
+	changed   chan struct{}
+}
+
+type SignedRequest struct {
+	KeyID     string
+	Timestamp string
+	Nonce     string
+	Signature string
+	Method    string
+	Path      string
+	Body      []byte
+}
+
+type localKeyEntry struct {
+	principal *types.Principal
+	expiresAt time.Time
+}
+
+func NewKeyService(cfg *types.Config, stores *store.Stores) (*KeyService, error) {
+	service := &KeyService{
+		keys:    stores.Keys,
+		cache:   stores.KeyCache,
+		maxSkew: cfg.SignatureMaxSkew,
+	}
+
+	if cfg.SigningEncryptionKey != "" {
+		secrets, err := NewSecretBox(cfg.SigningEncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signing encryption key: %w", err)
+		}
+		service.secrets = secrets
+	}
+
+	return service, nil
+}
+
+// GenerateAPIKey returns a new key in the nova_live_<id>_<secret> format
+// along with its public id.
+func GenerateAPIKey() (string, string, error) {
+	keyID, err := randomHex(8)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	return keyID, APIKeyPrefix + keyID + "_" + secret, nil
+}
+
+func ParseAPIKey(rawKey string) (string, bool) {
+	rest, ok := strings.CutPrefix(rawKey, APIKeyPrefix)
+	if !ok {
+		return "", false
+	}
+
+	keyID, secret, ok := strings.Cut(rest, "_")
+	if !ok || keyID == "" || secret == "" {
+		return "", false
+	}
+
+	return keyID, true
+}
+
+func HashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// Legacy keys predate the prefixed format and have no public id, so they are
+// cached under their hash instead.
+func APIKeyCacheKey(rawKey string) string {
+	if keyID, ok := ParseAPIKey(rawKey); ok {
+		return "api_key:" + keyID
+	}
+	return "api_key:legacy:" + HashAPIKey(rawKey)
+}
+
+func cacheKeyFor(keyDoc *types.APIKey) string {
+	if keyDoc.Legacy {
+		return "api_key:legacy:" + keyDoc.KeyHash
+	}
+	return "api_key:" + keyDoc.KeyID
+}
+
+func randomHex(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+func (s *KeyService) Authenticate(ctx context.Context, rawKey string) (*types.Principal, error) {
+	hash := HashAPIKey(rawKey)
+
+	principal, err := s.resolve(ctx, APIKeyCacheKey(rawKey), func(ctx context.Context) (*types.APIKey, error) {
+		return s.lookup(ctx, rawKey, hash)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(principal.KeyHash), []byte(hash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	return principal, nil
+}
+
+// Recheck reports whether the key a principal authenticated with is still
+// active, returning ErrInvalidAPIKey once it has been revoked or rotated.
+// Principals without a key, such as bearer tokens, are left to their
+// expiry.
+func (s *KeyService) Recheck(ctx context.Context, principal *types.Principal) error {
+	if principal.KeyHash == "" {
+		return nil
+	}
+
+	cacheKey := "api_key:" + principal.KeyID
+	load := func(ctx context.Context) (*types.APIKey, error) {
+		return s.keys.FindActiveByKeyID(ctx, principal.KeyID)
+	}
+	if principal.Legacy {
+		cacheKey = "api_key:legacy:" + principal.KeyHash
+		load = func(ctx context.Context) (*types.APIKey, error) {
+			return s.keys.FindActiveLegacy(ctx, principal.KeyHash)
+		}
+	}
+
+	current, err := s.resolve(ctx, cacheKey, load)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(current.KeyHash), []byte(principal.KeyHash)) != 1 {
+		return ErrInvalidAPIKey
+	}
+	return nil
+}
+
+// Changed returns a channel that is closed the next time a key is revoked
+// or rotated, on this instance or, through ListenForRevocations, another.
+// Holders of a principal should Recheck it then.
+func (s *KeyService) Changed() <-chan struct{} {
+	s.changedMu.Lock()
+	defer s.changedMu.Unlock()
+
+	if s.changed == nil {
+		s.changed = make(chan struct{})
+	}
+	return s.changed
+}
+
+func (s *KeyService) notifyChanged() {
+	s.changedMu.Lock()
+	defer s.changedMu.Unlock()
+
+	if s.changed != nil {
+		close(s.changed)
+		s.changed = nil
+	}
+}
+
+// AuthenticateSigned verifies an HMAC-signed request made with the key's
+// signing secret instead of the key itself.
+func (s *KeyService) AuthenticateSigned(ctx context.Context, req SignedRequest, now time.Time) (*types.Principal, error) {
+	if s.secrets == nil {
+		return nil, ErrSigningUnavailable
+	}
+
+	unix, err := strconv.ParseInt(req.Timestamp, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	if skew := now.Sub(time.Unix(unix, 0)); skew > s.maxSkew || skew < -s.maxSkew {
+		return nil, ErrSignatureExpired
+	}
+
+	if req.KeyID == "" || req.Nonce == "" || len(req.Nonce) > 128 {
+		return nil, ErrInvalidSignature
+	}
+
+	principal, err := s.resolve(ctx, "api_key:"+req.KeyID, func(ctx context.Context) (*types.APIKey, error) {
+		keyDoc, err := s.keys.FindActiveByKeyID(ctx, req.KeyID)
+		if err == nil && keyDoc.Legacy {
+			return nil, store.ErrNotFound
+		}
+		return keyDoc, err
+	})
+	if err == ErrInvalidAPIKey || (err == nil && principal.SigningSecret == "") {
+		return nil, ErrInvalidSignature
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := s.secrets.Open(principal.SigningSecret)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	if !signing.Verify(secret, req.Method, req.Path, req.Timestamp, req.Nonce, req.Body, req.Signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	claimed, err := s.cache.ClaimNonce(ctx, "nonce:"+req.KeyID+":"+req.Nonce, 2*s.maxSkew)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrReplayedNonce
+	}
+
+	return principal, nil
+}
+
+func (s *KeyService) resolve(ctx context.Context, cacheKey string, load func(context.Context) (*types.APIKey, error)) (*types.Principal, error) {
+	if entry, ok := s.local.Load(cacheKey); ok {
+		local := entry.(*localKeyEntry)
+		if time.Now().Before(local.expiresAt) {
+			return local.principal, nil
+		}
+		s.local.Delete(cacheKey)
+	}
+
+	cacheCtx, cacheCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	cached, err := s.cache.Get(cacheCtx, cacheKey)
+	cacheCancel()
+
+	if err == nil {
+		if cached == "invalid" {
+			return nil, ErrInvalidAPIKey
+		}
+
+		var principal types.Principal
+		if err := json.Unmarshal([]byte(cached), &principal); err == nil {
+			s.storeLocal(cacheKey, &principal)
+			return &principal, nil
+		}
+	}
+
+	storeCtx, storeCancel := context.WithTimeout(ctx, 1*time.Second)
+	defer storeCancel()
+
+	keyDoc, err := load(storeCtx)
+	if err == store.ErrNotFound {
+		s.cacheStatus(cacheKey, "invalid", invalidKeyTTL)
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	principal := keyDoc.Principal()
+	if encoded, err := json.Marshal(principal); err == nil {
+		s.cacheStatus(cacheKey, string(encoded), validKeyTTL)
+	}
+	s.storeLocal(cacheKey, principal)
+
+	return principal, nil
+}
+
+func (s *KeyService) lookup(ctx context.Context, rawKey, hash string) (*types.APIKey, error) {
+	if keyID, ok := ParseAPIKey(rawKey); ok {
+		return s.keys.FindActiveByKeyID(ctx, keyID)
+	}
+
+	keyDoc, err := s.keys.FindActiveLegacy(ctx, hash)
+	if err != store.ErrNotFound {
+		return keyDoc, err
+	}
+
+	// Keys issued before hashing was introduced are migrated the first time
+	// they are used, in case the bulk migration has not run yet.
+	keyDoc, err = s.keys.FindActivePlaintext(ctx, rawKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.migrateKey(ctx, keyDoc)
+	if errors.Is(err, store.ErrNotFound) {
+		// A concurrent request migrated the key first, under a key id of
+		// its own, so read back what it stored.
+		return s.keys.FindActiveLegacy(ctx, hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return keyDoc, nil
+}
+
+func (s *KeyService) storeLocal(cacheKey string, principal *types.Principal) {
+	s.local.Store(cacheKey, &localKeyEntry{
+		principal: principal,
+		expiresAt: time.Now().Add(localKeyTTL),
+	})
+}
+
+// cacheStatus writes before the request completes so a revocation that
+// follows it cannot be overwritten by a late write of the old status.
+func (s *KeyService) cacheStatus(cacheKey, value string, ttl time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	s.cache.Set(ctx, cacheKey, value, ttl)
+}
+
+func (s *KeyService) Create(ctx context.Context, req types.CreateAPIKeyRequest) (*types.APIKey, string, error) {
+	tier := req.Tier
+	if tier == "" {
+		tier = types.TierFree
+	}
+
+	if !types.IsValidTier(tier) {
+		return nil, "", ErrInvalidTier
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, "", ErrInvalidExpiry
+	}
+
+	if err := validateRestrictions(req); err != nil {
+		return nil, "", err
+	}
+
+	if req.RequireSignature && s.secrets == nil {
+		return nil, "", ErrSigningUnavailable
+	}
+
+	keyID, rawKey, err := GenerateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	signingSecret, err := s.newSigningSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	keyDoc := &types.APIKey{
+		ID:        bson.NewObjectID(),
+		KeyID:     keyID,
+		KeyHash:   HashAPIKey(rawKey),
+		Name:      req.Name,
+		Owner:     req.Owner,
+		Tier:      tier,
+		Active:    true,
+		CreatedAt: time.Now(),
+		ExpiresAt: req.ExpiresAt,
+
+		Scopes:         req.Scopes,
+		Networks:       req.Networks,
+		AllowedIPs:     req.AllowedIPs,
+		AllowedOrigins: req.AllowedOrigins,
+
+		DailyQuota:   req.DailyQuota,
+		MonthlyQuota: req.MonthlyQuota,
+
+		SigningSecret:    signingSecret,
+		RequireSignature: req.RequireSignature,
+	}
+
+	if err := s.keys.Insert(ctx, keyDoc); err != nil {
+		return nil, "", fmt.Errorf("failed to insert API key: %w", err)
+	}
+
+	return keyDoc, rawKey, nil
+}
+
+// newSigningSecret returns a sealed signing secret, or an empty string when
+// signing is not configured.
+func (s *KeyService) newSigningSecret() (string, error) {
+	if s.secrets == nil {
+		return "", nil
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate signing secret: %w", err)
+	}
+
+	return s.secrets.Seal("nova_sig_" + secret)
+}
+
+func (s *KeyService) RevealSigningSecret(keyDoc *types.APIKey) string {
+	if s.secrets == nil || keyDoc.SigningSecret == "" {
+		return ""
+	}
+
+	secret, err := s.secrets.Open(keyDoc.SigningSecret)
+	if err != nil {
+		log.Printf("Failed to open signing secret for %s: %v", keyDoc.KeyID, err)
+		return ""
+	}
+
+	return secret
+}
+
+func validateRestrictions(req types.CreateAPIKeyRequest) error {
+	if req.DailyQuota < 0 || req.MonthlyQuota < 0 {
+		return ErrInvalidQuota
+	}
+
+	for _, scope := range req.Scopes {
+		if !types.IsValidScope(scope) {
+			return fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+
+	for _, network := range req.Networks {
+		if !types.IsValidNetwork(network) {
+			return fmt.Errorf("%w: %s", ErrInvalidNet, network)
+		}
+	}
+
+	for _, rule := range req.AllowedIPs {
+		if !types.IsValidIPRule(rule) {
+			return fmt.Errorf("%w: %s", ErrInvalidIPRule, rule)
+		}
+	}
+
+	for _, origin := range req.AllowedOrigins {
+		parsed, err := url.Parse(origin)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("%w: %s", ErrInvalidOrigin, origin)
+		}
+	}
+
+	return nil
+}
+
+func (s *KeyService) List(ctx context.Context, page, limit int) ([]types.APIKey, int64, error) {
+	keys, total, err := s.keys.List(ctx, (page-1)*limit, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	return keys, total, nil
+}
+
+func (s *KeyService) Get(ctx context.Context, id string) (*types.APIKey, error) {
+	objectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	keyDoc, err := s.keys.Get(ctx, objectID)
+	if err == store.ErrNotFound {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	return keyDoc, nil
+}
+
+func (s *KeyService) Revoke(ctx context.Context, id string) (*types.APIKey, error) {
+	keyDoc, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.keys.Revoke(ctx, keyDoc.ID, now); err != nil {
+		return nil, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	s.purgeCache(ctx, keyDoc)
+
+	keyDoc.Active = false
+	keyDoc.RevokedAt = &now
+	return keyDoc, nil
+}
+
+func (s *KeyService) Rotate(ctx context.Context, id string) (*types.APIKey, string, error) {
+	keyDoc, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !keyDoc.Active {
+		return nil, "", ErrKeyRevoked
+	}
+
+	keyID, rawKey, err := GenerateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	signingSecret, err := s.newSigningSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	err = s.keys.Rotate(ctx, keyDoc.ID, store.KeyRotation{
+		KeyID:             keyID,
+		KeyHash:           HashAPIKey(rawKey),
+		SigningSecret:     signingSecret,
+		RotatedAt:         now,
+		RetiredCacheKey:   cacheKeyFor(keyDoc),
+		DropRetiredBefore: now.Add(-validKeyTTL),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to rotate API key: %w", err)
+	}
+
+	s.purgeCache(ctx, keyDoc)
+
+	keyDoc.KeyID = keyID
+	keyDoc.KeyHash = HashAPIKey(rawKey)
+	keyDoc.SigningSecret = signingSecret
+	keyDoc.Key = ""
+	keyDoc.Legacy = false
+	keyDoc.RotatedAt = &now
+	return keyDoc, rawKey, nil
+}
+
+// MigratePlaintextKeys hashes every key still stored in plaintext. Migrated
+// keys keep working with their original value and are flagged as legacy.
+func (s *KeyService) MigratePlaintextKeys(ctx context.Context) (int, error) {
+	keys, err := s.keys.ListPlaintext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find plaintext API keys: %w", err)
+	}
+
+	migrated := 0
+	for i := range keys {
+		err := s.migrateKey(ctx, &keys[i])
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+
+	return migrated, nil
+}
+
+func (s *KeyService) migrateKey(ctx context.Context, keyDoc *types.APIKey) error {
+	keyID, err := randomHex(8)
+	if err != nil {
+		return fmt.Errorf("failed to generate key id: %w", err)
+	}
+
+	hash := HashAPIKey(keyDoc.Key)
+	if err := s.keys.MigratePlaintext(ctx, keyDoc.ID, keyDoc.Key, keyID, hash); err != nil {
+		return fmt.Errorf("failed to migrate API key: %w", err)
+	}
+
+	keyDoc.KeyID = keyID
+	keyDoc.KeyHash = hash
+	keyDoc.Key = ""
+	keyDoc.Legacy = true
+	return nil
+}
+
+func (s *KeyService) purgeCache(ctx context.Context, keyDoc *types.APIKey) {
+	cacheKey := cacheKeyFor(keyDoc)
+	s.local.Delete(cacheKey)
+	defer s.notifyChanged()
+
+	if err := s.cache.Delete(ctx, cacheKey); err != nil {
+		log.Printf("Failed to purge cached API key status: %v", err)
+	}
+
+	if err := s.cache.Publish(ctx, cacheKey); err != nil {
+		log.Printf("Failed to publish API key revocation: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"nova/api/metrics"
+)
+
+// LookupPool bounds how many RPC lookups run at once across the service.
+// Lookups that have to wait are queued per API key and granted round robin
+// between keys, so one key sending thousands of wallets cannot starve the
+// others.
+type LookupPool struct {
+	size int
+
+	mu     sync.Mutex
+	active int
+	queued int
+	queues map[string][]*poolWaiter
+	keys   []string
+
+	waits *metrics.Histogram
+}
+
+type poolWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+type PoolStats struct {
+	Size   int
+	Active int
+	Queued int
+}
+
+// NewLookupPool falls back to the default concurrency when size is not
+// positive.
+func NewLookupPool(size int) *LookupPool {
+	if size <= 0 {
+		size = defaultRPCConcurrency
+	}
+
+	return &LookupPool{
+		size:   size,
+		queues: make(map[string][]*poolWaiter),
+		waits:  metrics.NewHistogram(metrics.DurationBuckets),
+	}
+}
+
+// Acquire blocks until a slot is free or ctx is done. Every successful
+// Acquire must be paired with a Release.
+func (p *LookupPool) Acquire(ctx context.Context, key string) error {
+	start := time.Now()
+
+	p.mu.Lock()
+	if p.active < p.size && p.queued == 0 {
+		p.active++
+		p.mu.Unlock()
+		p.waits.Observe(0)
+		return nil
+	}
+
+	waiter := &poolWaiter{ready: make(chan struct{})}
+	if len(p.queues[key]) == 0 {
+		p.keys = append(p.keys, key)
+	}
+	p.queues[key] = append(p.queues[key], waiter)
+	p.queued++
+	p.mu.Unlock()
+
+	select {
+	case <-waiter.ready:
+		p.waits.Observe(time.Since(start).Seconds())
+		return nil
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	if waiter.granted {
+		// The slot was handed over while ctx was being cancelled.
+		p.mu.Unlock()
+		p.Release()
+		return ctx.Err()
+	}
+	p.remove(key, waiter)
+	p.mu.Unlock()
+
+	return ctx.Err()
+}
+
+// Release hands the slot to the next queued lookup, if any.
+func (p *LookupPool) Release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if waiter := p.next(); waiter != nil {
+		waiter.granted = true
+		close(waiter.ready)
+		return
+	}
+	p.active--
+}
+
+func (p *LookupPool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PoolStats{Size: p.size, Active: p.active, Queued: p.queued}
+}
+
+func (p *LookupPool) WaitTimes() *metrics.Histogram {
+	return p.waits
+}
+
+// next pops the oldest waiter of the key at the head of the rotation and
+// moves that key to the back.
+func (p *LookupPool) next() *poolWaiter {
+	if len(p.keys) == 0 {
+		return nil
+	}
+
+	key := p.keys[0]
+	p.keys = p.keys[1:]
+
+	queue := p.queues[key]
+	waiter := queue[0]
+	if len(queue) == 1 {
+		delete(p.queues, key)
+	} else {
+		p.queues[key] = queue[1:]
+		p.keys = append(p.keys, key)
+	}
+
+	p.queued--
+	return waiter
+}
+
+func (p *LookupPool) remove(key string, waiter *poolWaiter) {
+	queue := p.queues[key]
+	for i := range queue {
+		if queue[i] == waiter {
+			queue = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+	p.queued--
+
+	if len(queue) > 0 {
+		p.queues[key] = queue
+		return
+	}
+
+	delete(p.queues, key)
+	for i := range p.keys {
+		if p.keys[i] == key {
+			p.keys = append(p.keys[:i], p.keys[i+1:]...)
+			break
+		}
+	}
+}
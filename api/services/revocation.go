@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"nova/api/store"
+)
+
+const RevocationChannel = store.RevocationChannel
+
+// ListenForRevocations evicts cached principals as soon as any instance
+// revokes or rotates a key. Every (re)subscription triggers a full resync, so
+// revocations published while this instance was disconnected still apply.
+func (s *KeyService) ListenForRevocations(ctx context.Context) {
+	backoff := 100 * time.Millisecond
+
+	for {
+		err := s.cache.Subscribe(ctx, func() {
+			backoff = 100 * time.Millisecond
+
+			if err := s.Resync(ctx); err != nil {
+				log.Printf("Failed to resync revoked API keys: %v", err)
+			}
+		}, func(cacheKey string) {
+			s.local.Delete(cacheKey)
+			s.notifyChanged()
+		})
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("Revocation subscription error: %v", err)
+		s.clearLocal()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, 10*time.Second)
+	}
+}
+
+// Resync drops every locally cached principal and purges the shared cache
+// entries of keys revoked or rotated within the cache lifetime.
+func (s *KeyService) Resync(ctx context.Context) error {
+	s.clearLocal()
+
+	since := time.Now().Add(-validKeyTTL)
+	keys, err := s.keys.ChangedSince(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	cacheKeys := make([]string, 0, len(keys))
+	for i := range keys {
+		if !keys[i].Active {
+			cacheKeys = append(cacheKeys, cacheKeyFor(&keys[i]))
+		}
+		for _, retired := range keys[i].RetiredCacheKeys {
+			if !retired.RetiredAt.Before(since) {
+				cacheKeys = append(cacheKeys, retired.CacheKey)
+			}
+		}
+	}
+
+	if len(cacheKeys) == 0 {
+		return nil
+	}
+
+	return s.cache.Delete(ctx, cacheKeys...)
+}
+
+func (s *KeyService) clearLocal() {
+	s.local.Range(func(key, value interface{}) bool {
+		s.local.Delete(key)
+		return true
+	})
+	s.notifyChanged()
+}
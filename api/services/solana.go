@@ -2,146 +2,449 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
-	"github.com/redis/go-redis/v9"
 
+	"nova/api/store"
 	"nova/api/types"
 )
 
+// RPCClient is the part of the Solana JSON-RPC API the service depends on.
+type RPCClient interface {
+	GetBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error)
+	GetTokenAccountsByOwner(ctx context.Context, owner solana.PublicKey, conf *rpc.GetTokenAccountsConfig, opts *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error)
+	GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error)
+	GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error)
+	GetProgramAccountsWithOpts(ctx context.Context, publicKey solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error)
+	RPCCallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error
+}
+
+const (
+	balanceChunkSize      = 100
+	defaultRPCConcurrency = 64
+
+	// BalanceCacheTTL is how long a fetched balance is served from cache.
+	BalanceCacheTTL = 10 * time.Second
+)
+
+var (
+	// ErrLookupTimeout is reported for wallets whose lookup did not finish
+	// before the request context was done.
+	ErrLookupTimeout  = errors.New("timeout")
+	ErrInvalidAddress = errors.New("invalid wallet address format")
+	// ErrInvalidCursor is reported for a before signature that is not a
+	// base58 transaction signature.
+	ErrInvalidCursor = errors.New("invalid transaction signature")
+
+	errEmptyAddress = errors.New("empty wallet address")
+)
+
+// ErrorCode classifies a lookup error with one of the types.Code constants.
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidAddress), errors.Is(err, errEmptyAddress):
+		return types.CodeInvalidAddress
+	case errors.Is(err, ErrInvalidCursor):
+		return types.CodeInvalidRequest
+	case errors.Is(err, ErrLookupTimeout), errors.Is(err, context.DeadlineExceeded):
+		return types.CodeUpstreamTimeout
+	default:
+		return types.CodeUpstreamError
+	}
+}
+
 type SolanaService struct {
-	client      *rpc.Client
-	redisClient *redis.Client
-	cache       sync.Map
-	lastCleanup time.Time
+	client     RPCClient
+	pool       *LookupPool
+	balances   store.BalanceCache
+	cache      sync.Map
+	accounts   sync.Map
+	rpcResults sync.Map
+	// rpcResultCount is the number of entries in rpcResults.
+	rpcResultCount atomic.Int64
+	// lastCleanup is the UnixNano time of the last cleanup.
+	lastCleanup atomic.Int64
 }
 
-func NewSolanaService(rpcURL string, redisClient *redis.Client) *SolanaService {
-	return &SolanaService{
-		client:      rpc.New(rpcURL),
-		redisClient: redisClient,
-		lastCleanup: time.Now(),
+func NewSolanaService(rpcURL string, balances store.BalanceCache) *SolanaService {
+	return NewSolanaServiceWithClient(rpc.New(rpcURL), balances, NewLookupPool(defaultRPCConcurrency))
+}
+
+func NewSolanaServiceWithClient(client RPCClient, balances store.BalanceCache, pool *LookupPool) *SolanaService {
+	service := &SolanaService{
+		client:   client,
+		pool:     pool,
+		balances: balances,
 	}
+	service.lastCleanup.Store(time.Now().UnixNano())
+	return service
 }
 
-func (s *SolanaService) GetBalance(address string) (float64, error) {
+func (s *SolanaService) Pool() *LookupPool {
+	return s.pool
+}
+
+func (s *SolanaService) GetBalance(ctx context.Context, address string) (float64, error) {
+	balance, _, err := s.lookupBalance(ctx, "", address)
+	return balance, err
+}
+
+// lookupBalance waits for a pool slot under keyID before calling the RPC.
+// Cache hits never wait.
+func (s *SolanaService) lookupBalance(ctx context.Context, keyID, address string) (float64, string, error) {
 	s.cleanupIfNeeded()
 
-	if cachedBalance, valid := s.getCachedBalance(address); valid {
-		return cachedBalance, nil
+	if ctx.Err() != nil {
+		return 0, "", ErrLookupTimeout
 	}
 
-	balance, err := s.fetchSolanaBalance(address)
+	if cachedBalance, valid := s.getCachedBalance(ctx, address); valid {
+		return cachedBalance, types.SourceCache, nil
+	}
 
+	pubKey, err := parseAddress(address)
 	if err != nil {
-		return 0, err
+		return 0, "", err
 	}
 
-	s.setCachedBalance(address, balance)
+	if err := s.pool.Acquire(ctx, keyID); err != nil {
+		return 0, "", ErrLookupTimeout
+	}
+	balance, err := s.fetchSolanaBalance(ctx, pubKey)
+	s.pool.Release()
 
-	return balance, nil
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ErrLookupTimeout
+		}
+		return 0, types.SourceRPC, err
+	}
+
+	s.setCachedBalance(ctx, address, balance)
+
+	return balance, types.SourceRPC, nil
 }
 
-func (s *SolanaService) getCachedBalance(address string) (float64, bool) {
+func (s *SolanaService) getCachedBalance(ctx context.Context, address string) (float64, bool) {
 	if entryInterface, exists := s.cache.Load(address); exists {
 		entry := entryInterface.(*types.CacheEntry)
-		if time.Since(entry.Timestamp) < 10*time.Second {
+		if time.Since(entry.Timestamp) < BalanceCacheTTL {
 			return entry.Balance, true
 		}
 	}
 
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+
+	// Another instance may have fetched the balance recently.
+	if balance, err := s.balances.Get(ctx, address); err == nil {
+		s.cache.Store(address, &types.CacheEntry{
+			Balance:   balance,
+			Timestamp: time.Now(),
+		})
+		return balance, true
+	}
+
 	return 0, false
 }
 
-func (s *SolanaService) setCachedBalance(address string, balance float64) {
+func (s *SolanaService) setCachedBalance(ctx context.Context, address string, balance float64) {
 	s.cache.Store(address, &types.CacheEntry{
 		Balance:   balance,
 		Timestamp: time.Now(),
 	})
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	s.redisClient.Set(ctx, fmt.Sprintf("balance:%s", address), balance, 10*time.Second)
+	s.balances.Set(ctx, address, balance, BalanceCacheTTL)
 }
 
-func (s *SolanaService) fetchSolanaBalance(address string) (float64, error) {
+func parseAddress(address string) (solana.PublicKey, error) {
 	address = strings.TrimSpace(address)
 	if address == "" {
-		return 0, fmt.Errorf("empty wallet address")
+		return solana.PublicKey{}, errEmptyAddress
 	}
 
 	pubKey, err := solana.PublicKeyFromBase58(address)
 
 	if err != nil {
-		return 0, fmt.Errorf("invalid wallet address format: %s", address)
+		return solana.PublicKey{}, fmt.Errorf("%w: %s", ErrInvalidAddress, address)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	return pubKey, nil
+}
 
-	defer cancel()
+func (s *SolanaService) fetchSolanaBalance(ctx context.Context, pubKey solana.PublicKey) (float64, error) {
+	out, err := s.fetchAccount(ctx, pubKey, rpc.CommitmentFinalized)
+	if err != nil {
+		return 0, err
+	}
 
-	out, err := s.client.GetBalance(ctx, pubKey, rpc.CommitmentFinalized)
+	return lamportsToSOL(out.Value), nil
+}
+
+func (s *SolanaService) fetchAccount(ctx context.Context, pubKey solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
+	out, err := s.client.GetBalance(ctx, pubKey, commitment)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get balance for %s: %v", address, err)
+		return nil, fmt.Errorf("failed to get balance for %s: %w", pubKey.String(), err)
 	}
 
-	lamportsOnAccount := new(big.Float).SetUint64(uint64(out.Value))
+	return out, nil
+}
+
+func lamportsToSOL(lamports uint64) float64 {
+	lamportsOnAccount := new(big.Float).SetUint64(lamports)
 	solBalance := new(big.Float).Quo(lamportsOnAccount, new(big.Float).SetUint64(solana.LAMPORTS_PER_SOL))
 
 	balance, _ := solBalance.Float64()
+	return balance
+}
 
-	return balance, nil
+// GetAccountBalance reads one account at commitment together with the slot
+// it was read at. Results are cached in process per commitment for
+// BalanceCacheTTL; FetchedAt tells the caller how old a cached result is.
+func (s *SolanaService) GetAccountBalance(ctx context.Context, keyID, address string, commitment rpc.CommitmentType) (*types.AccountBalance, error) {
+	s.cleanupIfNeeded()
+
+	pubKey, err := parseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKey := string(commitment) + ":" + pubKey.String()
+	if cached, ok := s.accounts.Load(cacheKey); ok {
+		account := *cached.(*types.AccountBalance)
+		if time.Since(account.FetchedAt) < BalanceCacheTTL {
+			account.Source = types.SourceCache
+			return &account, nil
+		}
+	}
+
+	if err := s.pool.Acquire(ctx, keyID); err != nil {
+		return nil, ErrLookupTimeout
+	}
+	out, err := s.fetchAccount(ctx, pubKey, commitment)
+	s.pool.Release()
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ErrLookupTimeout
+		}
+		return nil, err
+	}
+
+	account := &types.AccountBalance{
+		Address:    pubKey.String(),
+		Balance:    lamportsToSOL(out.Value),
+		Lamports:   out.Value,
+		Slot:       out.Context.Slot,
+		Commitment: string(commitment),
+		FetchedAt:  time.Now(),
+		Source:     types.SourceRPC,
+	}
+	s.accounts.Store(cacheKey, account)
+
+	if commitment == rpc.CommitmentFinalized {
+		s.setCachedBalance(ctx, account.Address, account.Balance)
+	}
+
+	result := *account
+	return &result, nil
 }
 
-func (s *SolanaService) GetMultipleBalances(addresses []string) []types.WalletBalance {
-	var wg sync.WaitGroup
+// GetMultipleBalances looks up each distinct address once and returns a
+// result for every entry of addresses, duplicates included, in order.
+// keyID identifies the caller for fair scheduling in the lookup pool.
+// Wallets not looked up by the time ctx is done get a timeout error.
+func (s *SolanaService) GetMultipleBalances(ctx context.Context, keyID string, addresses []string) []types.WalletBalance {
+	unique, positions := dedupeAddresses(addresses)
 	results := make([]types.WalletBalance, len(addresses))
 
-	for i, address := range addresses {
-		wg.Add(1)
-		go func(index int, addr string) {
-			defer wg.Done()
-			balance, err := s.GetBalance(addr)
-			if err != nil {
-				results[index] = types.WalletBalance{
-					Address: addr,
-					Balance: 0,
-					Error:   err.Error(),
-				}
-			} else {
-				results[index] = types.WalletBalance{
-					Address: addr,
-					Balance: balance,
-				}
+	s.lookupBalances(ctx, keyID, unique, func(index int, balance types.WalletBalance) {
+		for n, position := range positions[index] {
+			results[position] = balance
+			if n > 0 {
+				results[position].Source = ""
 			}
-		}(i, address)
+		}
+	})
+
+	return results
+}
+
+// StreamBalances is GetMultipleBalances for callers that want each result
+// as soon as it resolves. emit receives the result and its index in
+// addresses, and is never called concurrently. While emit blocks, finished
+// lookups wait for it and the next chunk does not start, so a slow reader
+// slows the lookups down instead of piling results up in memory. The first
+// error from emit cancels the remaining lookups and is returned.
+func (s *SolanaService) StreamBalances(ctx context.Context, keyID string, addresses []string, emit func(index int, balance types.WalletBalance) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type resolved struct {
+		index   int
+		balance types.WalletBalance
 	}
 
-	wg.Wait()
+	unique, positions := dedupeAddresses(addresses)
+	results := make(chan resolved)
+	// Closed once emit fails. Results are still delivered after a deadline
+	// so that callers see the timeouts.
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(results)
+		s.lookupBalances(ctx, keyID, unique, func(index int, balance types.WalletBalance) {
+			select {
+			case results <- resolved{index, balance}:
+			case <-stopped:
+			}
+		})
+	}()
 
-	return results
+	var emitErr error
+	for result := range results {
+		if emitErr != nil {
+			continue
+		}
+
+		for n, position := range positions[result.index] {
+			balance := result.balance
+			if n > 0 {
+				balance.Source = ""
+			}
+			if emitErr = emit(position, balance); emitErr != nil {
+				cancel()
+				close(stopped)
+				break
+			}
+		}
+	}
+
+	return emitErr
+}
+
+// dedupeAddresses returns the distinct trimmed addresses in order of first
+// appearance, and for each of them its positions in addresses.
+func dedupeAddresses(addresses []string) ([]string, [][]int) {
+	unique := make([]string, 0, len(addresses))
+	positions := make([][]int, 0, len(addresses))
+	seen := make(map[string]int, len(addresses))
+
+	for i, address := range addresses {
+		address = strings.TrimSpace(address)
+
+		index, ok := seen[address]
+		if !ok {
+			index = len(unique)
+			seen[address] = index
+			unique = append(unique, address)
+			positions = append(positions, nil)
+		}
+		positions[index] = append(positions[index], i)
+	}
+
+	return unique, positions
+}
+
+// lookupBalances fans out in chunks of balanceChunkSize so one large
+// request never has more than a chunk of lookups in flight. emit is called
+// with each result and its index in addresses, from several goroutines at
+// once.
+func (s *SolanaService) lookupBalances(ctx context.Context, keyID string, addresses []string, emit func(index int, balance types.WalletBalance)) {
+	for start := 0; start < len(addresses); start += balanceChunkSize {
+		end := min(start+balanceChunkSize, len(addresses))
+
+		var wg sync.WaitGroup
+		for i := start; i < end; i++ {
+			wg.Add(1)
+			go func(index int, addr string) {
+				defer wg.Done()
+				balance, source, err := s.lookupBalance(ctx, keyID, addr)
+				if err != nil {
+					emit(index, types.WalletBalance{
+						Address:   addr,
+						Balance:   0,
+						Error:     err.Error(),
+						ErrorCode: ErrorCode(err),
+						Source:    source,
+					})
+				} else {
+					emit(index, types.WalletBalance{
+						Address: addr,
+						Balance: balance,
+						Source:  source,
+					})
+				}
+			}(i, addresses[i])
+		}
+		wg.Wait()
+	}
 }
 
 func (s *SolanaService) cleanupIfNeeded() {
-	if time.Since(s.lastCleanup) < 5*time.Minute {
+	// Lookups call this concurrently; only the one that claims the
+	// cleanup runs it.
+	last := s.lastCleanup.Load()
+	now := time.Now().UnixNano()
+	if time.Duration(now-last) < 5*time.Minute || !s.lastCleanup.CompareAndSwap(last, now) {
 		return
 	}
 
-	s.lastCleanup = time.Now()
-
 	s.cache.Range(func(key, value interface{}) bool {
 		entry := value.(*types.CacheEntry)
-		if time.Since(entry.Timestamp) > 10*time.Second {
+		if time.Since(entry.Timestamp) > BalanceCacheTTL {
 			s.cache.Delete(key)
 		}
 		return true
 	})
+
+	s.accounts.Range(func(key, value interface{}) bool {
+		if time.Since(value.(*types.AccountBalance).FetchedAt) > BalanceCacheTTL {
+			s.accounts.Delete(key)
+		}
+		return true
+	})
+
+	s.rpcResults.Range(func(key, value interface{}) bool {
+		if time.Now().After(value.(*rpcCacheEntry).expires) {
+			s.deleteRPCResult(key)
+		}
+		return true
+	})
+}
+
+// StorePushedBalance caches a finalized balance pushed by an account
+// subscription as if it had just been fetched, unless the cache already
+// holds a later slot.
+func (s *SolanaService) StorePushedBalance(ctx context.Context, address string, lamports, slot uint64) {
+	cacheKey := string(rpc.CommitmentFinalized) + ":" + address
+	if cached, ok := s.accounts.Load(cacheKey); ok && cached.(*types.AccountBalance).Slot > slot {
+		return
+	}
+
+	account := &types.AccountBalance{
+		Address:    address,
+		Balance:    lamportsToSOL(lamports),
+		Lamports:   lamports,
+		Slot:       slot,
+		Commitment: string(rpc.CommitmentFinalized),
+		FetchedAt:  time.Now(),
+		Source:     types.SourceRPC,
+	}
+	s.accounts.Store(cacheKey, account)
+	s.setCachedBalance(ctx, address, account.Balance)
 }
@@ -0,0 +1,521 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"nova/api/types"
+)
+
+const (
+	subscriberBuffer = 256
+
+	pubsubPingInterval = 30 * time.Second
+	pubsubReadTimeout  = 2 * pubsubPingInterval
+	pubsubWriteTimeout = 5 * time.Second
+
+	minReconnectBackoff = 100 * time.Millisecond
+	maxReconnectBackoff = 30 * time.Second
+)
+
+var (
+	// ErrTooManySubscriptions is returned when a subscribe would take a
+	// subscriber over its address limit.
+	ErrTooManySubscriptions = errors.New("too many subscribed addresses")
+	// ErrSlowSubscriber ends a subscriber whose updates were not read fast
+	// enough to keep its buffer from filling up.
+	ErrSlowSubscriber = errors.New("subscriber fell too far behind")
+	// ErrSubscriptionsClosed ends every subscriber when the service closes.
+	ErrSubscriptionsClosed = errors.New("subscription service closed")
+)
+
+// SubscriptionService pushes finalized balance changes to subscribers. All
+// subscribers share one upstream websocket carrying one accountSubscribe
+// per address, opened with the first subscription and closed after the
+// last unsubscribe. A dropped connection is redialed with backoff and every
+// address subscribed again. Pushed balances also refresh the balance
+// cache.
+type SubscriptionService struct {
+	url    string
+	solana *SolanaService
+	logger *log.Logger
+	dialer *websocket.Dialer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu          sync.Mutex
+	addresses   map[string]*accountSubscription
+	subscribers map[*Subscriber]struct{}
+	// upstream holds confirmed subscriptions by their upstream id, pending
+	// unconfirmed ones by request id.
+	upstream map[uint64]*accountSubscription
+	pending  map[uint64]*accountSubscription
+	conn     *websocket.Conn
+	nextID   uint64
+	running  bool
+}
+
+type accountSubscription struct {
+	address     string
+	subscribers map[*Subscriber]struct{}
+	confirmed   bool
+	id          uint64
+}
+
+// Subscriber receives the updates of the addresses it subscribed to, until
+// it is closed or Done.
+type Subscriber struct {
+	service   *SubscriptionService
+	limit     int
+	updates   chan types.BalanceUpdate
+	done      chan struct{}
+	err       error
+	addresses map[string]struct{}
+}
+
+type pubsubRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type pubsubMessage struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Method string `json:"method"`
+	Params struct {
+		Subscription uint64 `json:"subscription"`
+		Result       struct {
+			Context struct {
+				Slot uint64 `json:"slot"`
+			} `json:"context"`
+			// Value is null once the account is closed.
+			Value *struct {
+				Lamports uint64 `json:"lamports"`
+			} `json:"value"`
+		} `json:"result"`
+	} `json:"params"`
+}
+
+func NewSubscriptionService(url string, solana *SolanaService, logger *log.Logger) *SubscriptionService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SubscriptionService{
+		url:         url,
+		solana:      solana,
+		logger:      logger,
+		dialer:      &websocket.Dialer{HandshakeTimeout: 10 * time.Second},
+		ctx:         ctx,
+		cancel:      cancel,
+		addresses:   make(map[string]*accountSubscription),
+		subscribers: make(map[*Subscriber]struct{}),
+		upstream:    make(map[uint64]*accountSubscription),
+		pending:     make(map[uint64]*accountSubscription),
+	}
+}
+
+// NewSubscriber returns a subscriber of at most limit addresses.
+func (s *SubscriptionService) NewSubscriber(limit int) *Subscriber {
+	sub := &Subscriber{
+		service:   s,
+		limit:     limit,
+		updates:   make(chan types.BalanceUpdate, subscriberBuffer),
+		done:      make(chan struct{}),
+		addresses: make(map[string]struct{}),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		sub.err = ErrSubscriptionsClosed
+		close(sub.done)
+		return sub
+	}
+	s.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Updates delivers pushed balances. Updates may repeat a slot or arrive
+// for an address just unsubscribed.
+func (sub *Subscriber) Updates() <-chan types.BalanceUpdate {
+	return sub.updates
+}
+
+// Done is closed when the subscriber is ended by the service; Err then
+// says why.
+func (sub *Subscriber) Done() <-chan struct{} {
+	return sub.done
+}
+
+func (sub *Subscriber) Err() error {
+	sub.service.mu.Lock()
+	defer sub.service.mu.Unlock()
+	return sub.err
+}
+
+// Subscribed reports whether the subscriber holds address.
+func (sub *Subscriber) Subscribed(address string) bool {
+	sub.service.mu.Lock()
+	defer sub.service.mu.Unlock()
+	_, ok := sub.addresses[address]
+	return ok
+}
+
+// Subscribe adds addresses to the subscriber and returns them normalized,
+// without duplicates. Nothing is subscribed if any address is invalid or
+// the limit would be exceeded.
+func (sub *Subscriber) Subscribe(addresses []string) ([]string, error) {
+	s := sub.service
+
+	unique, _ := dedupeAddresses(addresses)
+	normalized := make([]string, 0, len(unique))
+	seen := make(map[string]bool, len(unique))
+	for _, address := range unique {
+		pubKey, err := parseAddress(address)
+		if errors.Is(err, errEmptyAddress) {
+			return nil, fmt.Errorf("%w: empty address", ErrInvalidAddress)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !seen[pubKey.String()] {
+			seen[pubKey.String()] = true
+			normalized = append(normalized, pubKey.String())
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sub.err != nil {
+		return nil, sub.err
+	}
+
+	added := 0
+	for _, address := range normalized {
+		if _, ok := sub.addresses[address]; !ok {
+			added++
+		}
+	}
+	if len(sub.addresses)+added > sub.limit {
+		return nil, fmt.Errorf("%w: at most %d", ErrTooManySubscriptions, sub.limit)
+	}
+
+	for _, address := range normalized {
+		if _, ok := sub.addresses[address]; ok {
+			continue
+		}
+		sub.addresses[address] = struct{}{}
+
+		entry, ok := s.addresses[address]
+		if !ok {
+			entry = &accountSubscription{address: address, subscribers: make(map[*Subscriber]struct{})}
+			s.addresses[address] = entry
+			s.subscribeLocked(entry)
+		}
+		entry.subscribers[sub] = struct{}{}
+	}
+
+	if !s.running && len(s.addresses) > 0 {
+		s.running = true
+		go s.run()
+	}
+	return normalized, nil
+}
+
+// Unsubscribe removes addresses from the subscriber and returns the ones it
+// was subscribed to.
+func (sub *Subscriber) Unsubscribe(addresses []string) []string {
+	s := sub.service
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		pubKey, err := parseAddress(address)
+		if err != nil {
+			continue
+		}
+		if _, ok := sub.addresses[pubKey.String()]; ok {
+			s.removeLocked(sub, pubKey.String())
+			removed = append(removed, pubKey.String())
+		}
+	}
+	return removed
+}
+
+// Close unsubscribes every address of the subscriber.
+func (sub *Subscriber) Close() {
+	s := sub.service
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for address := range sub.addresses {
+		s.removeLocked(sub, address)
+	}
+	delete(s.subscribers, sub)
+}
+
+// Close ends every subscriber and the upstream connection.
+func (s *SubscriptionService) Close() {
+	s.cancel()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for sub := range s.subscribers {
+		s.endLocked(sub, ErrSubscriptionsClosed)
+	}
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+// removeLocked drops one address of sub, and the upstream subscription if
+// sub was its last subscriber. The connection is closed along with the
+// last subscription.
+func (s *SubscriptionService) removeLocked(sub *Subscriber, address string) {
+	delete(sub.addresses, address)
+
+	entry, ok := s.addresses[address]
+	if !ok {
+		return
+	}
+	delete(entry.subscribers, sub)
+	if len(entry.subscribers) > 0 {
+		return
+	}
+
+	delete(s.addresses, address)
+	if entry.confirmed {
+		delete(s.upstream, entry.id)
+		s.writeLocked("accountUnsubscribe", entry.id)
+	}
+
+	if len(s.addresses) == 0 && s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+// endLocked unsubscribes everything of sub and closes its Done channel.
+func (s *SubscriptionService) endLocked(sub *Subscriber, err error) {
+	if sub.err != nil {
+		return
+	}
+	for address := range sub.addresses {
+		s.removeLocked(sub, address)
+	}
+	delete(s.subscribers, sub)
+	sub.err = err
+	close(sub.done)
+}
+
+// subscribeLocked sends the accountSubscribe of entry if connected. Entries
+// subscribed while disconnected are sent once the connection is up.
+func (s *SubscriptionService) subscribeLocked(entry *accountSubscription) {
+	if s.conn == nil {
+		return
+	}
+
+	id := s.writeLocked("accountSubscribe", entry.address, map[string]string{
+		"commitment": string(rpc.CommitmentFinalized),
+		"encoding":   "base64",
+	})
+	s.pending[id] = entry
+}
+
+// writeLocked sends a request and returns its id. A failed write closes
+// the connection, so that run reconnects and subscribes again.
+func (s *SubscriptionService) writeLocked(method string, params ...interface{}) uint64 {
+	s.nextID++
+	if s.conn == nil {
+		return s.nextID
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(pubsubWriteTimeout))
+	err := s.conn.WriteJSON(pubsubRequest{JSONRPC: "2.0", ID: s.nextID, Method: method, Params: params})
+	if err != nil {
+		s.logger.Printf("Failed to send %s to the Solana websocket: %v", method, err)
+		s.conn.Close()
+	}
+	return s.nextID
+}
+
+// run keeps the upstream connection up for as long as any address is
+// subscribed.
+func (s *SubscriptionService) run() {
+	backoff := minReconnectBackoff
+
+	for {
+		s.mu.Lock()
+		if s.ctx.Err() != nil || len(s.addresses) == 0 {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Unlock()
+
+		conn, _, err := s.dialer.DialContext(s.ctx, s.url, nil)
+		if err == nil {
+			s.mu.Lock()
+			s.conn = conn
+			for _, entry := range s.addresses {
+				s.subscribeLocked(entry)
+			}
+			s.mu.Unlock()
+
+			var received bool
+			received, err = s.read(conn)
+
+			s.mu.Lock()
+			s.conn = nil
+			s.upstream = make(map[uint64]*accountSubscription)
+			s.pending = make(map[uint64]*accountSubscription)
+			for _, entry := range s.addresses {
+				entry.confirmed = false
+			}
+			idle := len(s.addresses) == 0
+			s.mu.Unlock()
+			conn.Close()
+
+			if idle || s.ctx.Err() != nil {
+				continue
+			}
+			if received {
+				backoff = minReconnectBackoff
+			}
+		}
+
+		s.logger.Printf("Solana websocket connection lost, reconnecting in %v: %v", backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-s.ctx.Done():
+		}
+		backoff = min(2*backoff, maxReconnectBackoff)
+	}
+}
+
+// read handles messages until the connection fails, pinging it meanwhile
+// so that a dead connection is noticed. received reports whether any
+// message arrived.
+func (s *SubscriptionService) read(conn *websocket.Conn) (received bool, err error) {
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		ticker := time.NewTicker(pubsubPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(pubsubWriteTimeout))
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(pubsubReadTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pubsubReadTimeout))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return received, err
+		}
+		received = true
+		conn.SetReadDeadline(time.Now().Add(pubsubReadTimeout))
+
+		var message pubsubMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			s.logger.Printf("Ignoring an unreadable Solana websocket message: %v", err)
+			continue
+		}
+		if message.Method == "accountNotification" {
+			s.notify(message)
+		} else {
+			s.confirm(message)
+		}
+	}
+}
+
+// confirm records the upstream id of a subscription, or unsubscribes it
+// again if its address was dropped while the request was in flight.
+func (s *SubscriptionService) confirm(message pubsubMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.pending[message.ID]
+	if !ok {
+		return
+	}
+	delete(s.pending, message.ID)
+
+	if message.Error != nil {
+		s.logger.Printf("Solana websocket refused accountSubscribe for %s: %s", entry.address, message.Error.Message)
+		return
+	}
+	var id uint64
+	if err := json.Unmarshal(message.Result, &id); err != nil {
+		s.logger.Printf("Unexpected accountSubscribe result for %s: %v", entry.address, err)
+		return
+	}
+
+	if s.addresses[entry.address] != entry {
+		s.writeLocked("accountUnsubscribe", id)
+		return
+	}
+	entry.confirmed = true
+	entry.id = id
+	s.upstream[id] = entry
+}
+
+// notify caches a pushed balance and passes it on. A subscriber whose
+// buffer is full is ended rather than allowed to hold the others up.
+func (s *SubscriptionService) notify(message pubsubMessage) {
+	s.mu.Lock()
+	entry, ok := s.upstream[message.Params.Subscription]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	update := types.BalanceUpdate{
+		Type:    types.WSBalance,
+		Address: entry.address,
+		Slot:    message.Params.Result.Context.Slot,
+	}
+	if value := message.Params.Result.Value; value != nil {
+		update.Lamports = value.Lamports
+	}
+	update.Balance = lamportsToSOL(update.Lamports)
+
+	s.solana.StorePushedBalance(s.ctx, update.Address, update.Lamports, update.Slot)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range entry.subscribers {
+		select {
+		case sub.updates <- update:
+		default:
+			s.endLocked(sub, ErrSlowSubscriber)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"nova/api/types"
+)
+
+// tokenPrograms are the programs whose accounts hold SPL tokens.
+var tokenPrograms = []solana.PublicKey{solana.TokenProgramID, solana.Token2022ProgramID}
+
+type parsedTokenAccount struct {
+	Parsed struct {
+		Info struct {
+			Mint        string `json:"mint"`
+			TokenAmount struct {
+				Amount   string `json:"amount"`
+				Decimals uint8  `json:"decimals"`
+			} `json:"tokenAmount"`
+		} `json:"info"`
+	} `json:"parsed"`
+}
+
+// GetTokenBalances lists the token accounts of each owner, only those of
+// mint when it is not empty. Like GetMultipleBalances, a failed owner is
+// reported on its result; the error is for an invalid mint.
+func (s *SolanaService) GetTokenBalances(ctx context.Context, keyID string, owners []string, mint string) ([]types.OwnerTokenBalances, error) {
+	var mintKey *solana.PublicKey
+	if mint != "" {
+		key, err := parseAddress(mint)
+		if err != nil {
+			return nil, err
+		}
+		mintKey = &key
+	}
+
+	results := make([]types.OwnerTokenBalances, len(owners))
+	for start := 0; start < len(owners); start += balanceChunkSize {
+		end := min(start+balanceChunkSize, len(owners))
+
+		var wg sync.WaitGroup
+		for i := start; i < end; i++ {
+			wg.Add(1)
+			go func(index int) {
+				defer wg.Done()
+				results[index] = s.lookupTokens(ctx, keyID, owners[index], mintKey)
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	return results, nil
+}
+
+func (s *SolanaService) lookupTokens(ctx context.Context, keyID, owner string, mint *solana.PublicKey) types.OwnerTokenBalances {
+	result := types.OwnerTokenBalances{Owner: owner, Tokens: []types.TokenBalance{}}
+	fail := func(err error) types.OwnerTokenBalances {
+		if ctx.Err() != nil {
+			err = ErrLookupTimeout
+		}
+		result.Tokens = []types.TokenBalance{}
+		result.Error = err.Error()
+		result.ErrorCode = ErrorCode(err)
+		return result
+	}
+
+	ownerKey, err := parseAddress(owner)
+	if err != nil {
+		return fail(err)
+	}
+
+	filters := make([]*rpc.GetTokenAccountsConfig, 0, len(tokenPrograms))
+	if mint != nil {
+		filters = append(filters, &rpc.GetTokenAccountsConfig{Mint: mint})
+	} else {
+		for i := range tokenPrograms {
+			filters = append(filters, &rpc.GetTokenAccountsConfig{ProgramId: &tokenPrograms[i]})
+		}
+	}
+
+	for _, filter := range filters {
+		if err := s.pool.Acquire(ctx, keyID); err != nil {
+			return fail(ErrLookupTimeout)
+		}
+		tokens, err := s.fetchTokenAccounts(ctx, ownerKey, filter)
+		s.pool.Release()
+
+		result.RPCCalls++
+		if err != nil {
+			return fail(err)
+		}
+		result.Tokens = append(result.Tokens, tokens...)
+	}
+
+	return result
+}
+
+func (s *SolanaService) fetchTokenAccounts(ctx context.Context, owner solana.PublicKey, filter *rpc.GetTokenAccountsConfig) ([]types.TokenBalance, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	out, err := s.client.GetTokenAccountsByOwner(ctx, owner, filter, &rpc.GetTokenAccountsOpts{
+		Commitment: rpc.CommitmentFinalized,
+		Encoding:   solana.EncodingJSONParsed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token accounts for %s: %w", owner.String(), err)
+	}
+
+	tokens := make([]types.TokenBalance, 0, len(out.Value))
+	for _, account := range out.Value {
+		if account.Account.Data == nil {
+			continue
+		}
+
+		var parsed parsedTokenAccount
+		if err := json.Unmarshal(account.Account.Data.GetRawJSON(), &parsed); err != nil {
+			return nil, fmt.Errorf("failed to parse token account %s: %w", account.Pubkey.String(), err)
+		}
+
+		info := parsed.Parsed.Info
+		tokens = append(tokens, types.TokenBalance{
+			Mint:     info.Mint,
+			Account:  account.Pubkey.String(),
+			Amount:   info.TokenAmount.Amount,
+			Decimals: info.TokenAmount.Decimals,
+			UIAmount: uiAmount(info.TokenAmount.Amount, info.TokenAmount.Decimals),
+		})
+	}
+
+	return tokens, nil
+}
+
+func uiAmount(amount string, decimals uint8) float64 {
+	units, ok := new(big.Float).SetString(amount)
+	if !ok {
+		return 0
+	}
+
+	scale := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+	value, _ := new(big.Float).Quo(units, scale).Float64()
+	return value
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"nova/api/store"
+	"nova/api/types"
+)
+
+const (
+	usageFlushEvery   = 30 * time.Second
+	maxUsageHistories = 1440
+)
+
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+type QuotaError struct {
+	Period string
+	Limit  int64
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s quota of %d requests exceeded", e.Period, e.Limit)
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+type UsageService struct {
+	history store.UsageStore
+	cache   store.UsageCache
+}
+
+func NewUsageService(stores *store.Stores) *UsageService {
+	return &UsageService{
+		history: stores.Usage,
+		cache:   stores.UsageCache,
+	}
+}
+
+func startOfDay(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func startOfMonth(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// ConsumeQuota counts one request against the principal's daily and monthly
+// quotas. Rejected requests are not counted.
+func (s *UsageService) ConsumeQuota(ctx context.Context, principal *types.Principal, now time.Time) error {
+	daily, monthly, err := s.cache.IncrementQuota(ctx, principal.UsageKey(), now)
+	if err != nil {
+		return err
+	}
+
+	var quotaErr *QuotaError
+	if principal.DailyQuota > 0 && daily > principal.DailyQuota {
+		quotaErr = &QuotaError{Period: "daily", Limit: principal.DailyQuota}
+	} else if principal.MonthlyQuota > 0 && monthly > principal.MonthlyQuota {
+		quotaErr = &QuotaError{Period: "monthly", Limit: principal.MonthlyQuota}
+	}
+
+	if quotaErr != nil {
+		s.cache.DecrementQuota(ctx, principal.UsageKey(), now)
+		return quotaErr
+	}
+
+	return nil
+}
+
+func (s *UsageService) Record(ctx context.Context, keyID string, counters types.UsageCounters, now time.Time) error {
+	return s.cache.Record(ctx, keyID, now.UTC().Truncate(time.Minute), counters)
+}
+
+// Flush moves every completed per-minute bucket from the usage cache into
+// the usage store. Buckets for the current minute are left to keep
+// accumulating.
+func (s *UsageService) Flush(ctx context.Context, now time.Time) (int, error) {
+	buckets, err := s.cache.Pending(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	currentMinute := now.UTC().Truncate(time.Minute)
+	flushed := 0
+
+	for _, bucket := range buckets {
+		if !bucket.Minute.Before(currentMinute) {
+			continue
+		}
+
+		counters, err := s.cache.Take(ctx, bucket)
+		if err != nil {
+			return flushed, err
+		}
+
+		if err := s.history.Add(ctx, bucket.KeyID, bucket.Minute, counters); err != nil {
+			// Put the counters back so the next flush retries them.
+			s.cache.Record(ctx, bucket.KeyID, bucket.Minute, counters)
+			return flushed, fmt.Errorf("failed to flush usage for %s: %w", bucket.KeyID, err)
+		}
+
+		flushed++
+	}
+
+	return flushed, nil
+}
+
+func (s *UsageService) Run(ctx context.Context) {
+	ticker := time.NewTicker(usageFlushEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if _, err := s.Flush(flushCtx, time.Now().Add(time.Minute)); err != nil {
+				log.Printf("Failed to flush usage: %v", err)
+			}
+			cancel()
+			return
+		case now := <-ticker.C:
+			if _, err := s.Flush(ctx, now); err != nil {
+				log.Printf("Failed to flush usage: %v", err)
+			}
+		}
+	}
+}
+
+func (s *UsageService) Report(ctx context.Context, principal *types.Principal, from, to, now time.Time) (*types.UsageReport, error) {
+	dailyUsed, monthlyUsed, err := s.cache.QuotaUsed(ctx, principal.UsageKey(), now)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := s.cache.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &types.UsageReport{
+		KeyID: principal.KeyID,
+		From:  from,
+		To:    to,
+		Daily: types.QuotaUsage{
+			Used:     dailyUsed,
+			Limit:    principal.DailyQuota,
+			ResetsAt: startOfDay(now).AddDate(0, 0, 1),
+		},
+		Monthly: types.QuotaUsage{
+			Used:     monthlyUsed,
+			Limit:    principal.MonthlyQuota,
+			ResetsAt: startOfMonth(now).AddDate(0, 1, 0),
+		},
+	}
+
+	report.History, err = s.history.History(ctx, principal.UsageKey(), from, to, maxUsageHistories)
+	if err != nil {
+		return nil, err
+	}
+
+	// Include minutes that have not been flushed to the usage store yet.
+	for _, bucket := range pending {
+		if bucket.KeyID != principal.UsageKey() || bucket.Minute.Before(from) || !bucket.Minute.Before(to) {
+			continue
+		}
+
+		counters, ok, err := s.cache.Peek(ctx, bucket)
+		if err != nil || !ok {
+			continue
+		}
+
+		report.History = append(report.History, types.UsageRecord{
+			KeyID:         bucket.KeyID,
+			Minute:        bucket.Minute,
+			UsageCounters: counters,
+		})
+	}
+
+	sort.Slice(report.History, func(i, j int) bool {
+		return report.History[i].Minute.Before(report.History[j].Minute)
+	})
+
+	for _, record := range report.History {
+		report.Totals.Add(record.UsageCounters)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,40 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	HeaderKeyID     = "X-Nova-Key-Id"
+	HeaderTimestamp = "X-Nova-Timestamp"
+	HeaderNonce     = "X-Nova-Nonce"
+	HeaderSignature = "X-Nova-Signature"
+)
+
+// CanonicalString is the message covered by a request signature: the method,
+// the path including its query string, the timestamp, the nonce and the hex
+// SHA-256 of the body, separated by newlines.
+func CanonicalString(method, path, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+}
+
+func Sign(secret, method, path, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(CanonicalString(method, path, timestamp, nonce, body)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func Verify(secret, method, path, timestamp, nonce string, body []byte, signature string) bool {
+	expected := Sign(secret, method, path, timestamp, nonce, body)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
@@ -0,0 +1,695 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"nova/api/types"
+)
+
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[bson.ObjectID]*types.APIKey
+}
+
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{keys: make(map[bson.ObjectID]*types.APIKey)}
+}
+
+func cloneKey(key *types.APIKey) *types.APIKey {
+	clone := *key
+	clone.RetiredCacheKeys = slices.Clone(key.RetiredCacheKeys)
+	clone.Scopes = slices.Clone(key.Scopes)
+	clone.Networks = slices.Clone(key.Networks)
+	clone.AllowedIPs = slices.Clone(key.AllowedIPs)
+	clone.AllowedOrigins = slices.Clone(key.AllowedOrigins)
+	return &clone
+}
+
+func (s *MemoryKeyStore) findOne(match func(*types.APIKey) bool) (*types.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		if match(key) {
+			return cloneKey(key), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryKeyStore) filter(match func(*types.APIKey) bool) []types.APIKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := []types.APIKey{}
+	for _, key := range s.keys {
+		if match(key) {
+			keys = append(keys, *cloneKey(key))
+		}
+	}
+	return keys
+}
+
+func (s *MemoryKeyStore) update(id bson.ObjectID, apply func(*types.APIKey) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	clone := cloneKey(key)
+	if apply(clone) {
+		s.keys[id] = clone
+	}
+	return nil
+}
+
+func (s *MemoryKeyStore) Insert(ctx context.Context, key *types.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key.ID] = cloneKey(key)
+	return nil
+}
+
+func (s *MemoryKeyStore) Get(ctx context.Context, id bson.ObjectID) (*types.APIKey, error) {
+	return s.findOne(func(key *types.APIKey) bool { return key.ID == id })
+}
+
+func (s *MemoryKeyStore) List(ctx context.Context, offset, limit int) ([]types.APIKey, int64, error) {
+	keys := s.filter(func(*types.APIKey) bool { return true })
+	total := int64(len(keys))
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+
+	if offset >= len(keys) {
+		return []types.APIKey{}, total, nil
+	}
+
+	keys = keys[offset:]
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys, total, nil
+}
+
+func (s *MemoryKeyStore) FindActiveByKeyID(ctx context.Context, keyID string) (*types.APIKey, error) {
+	return s.findOne(func(key *types.APIKey) bool { return key.Active && key.KeyID == keyID })
+}
+
+func (s *MemoryKeyStore) FindActiveLegacy(ctx context.Context, hash string) (*types.APIKey, error) {
+	return s.findOne(func(key *types.APIKey) bool { return key.Active && key.Legacy && key.KeyHash == hash })
+}
+
+func (s *MemoryKeyStore) FindActivePlaintext(ctx context.Context, rawKey string) (*types.APIKey, error) {
+	return s.findOne(func(key *types.APIKey) bool { return key.Active && key.Key != "" && key.Key == rawKey })
+}
+
+func (s *MemoryKeyStore) Revoke(ctx context.Context, id bson.ObjectID, at time.Time) error {
+	return s.update(id, func(key *types.APIKey) bool {
+		key.Active = false
+		key.RevokedAt = &at
+		return true
+	})
+}
+
+func (s *MemoryKeyStore) Rotate(ctx context.Context, id bson.ObjectID, rotation KeyRotation) error {
+	return s.update(id, func(key *types.APIKey) bool {
+		key.KeyID = rotation.KeyID
+		key.KeyHash = rotation.KeyHash
+		key.SigningSecret = rotation.SigningSecret
+		key.RotatedAt = &rotation.RotatedAt
+		key.Key = ""
+		key.Legacy = false
+		key.RetiredCacheKeys = slices.DeleteFunc(key.RetiredCacheKeys, func(retired types.RetiredCacheKey) bool {
+			return retired.RetiredAt.Before(rotation.DropRetiredBefore)
+		})
+		key.RetiredCacheKeys = append(key.RetiredCacheKeys, types.RetiredCacheKey{
+			CacheKey:  rotation.RetiredCacheKey,
+			RetiredAt: rotation.RotatedAt,
+		})
+		return true
+	})
+}
+
+func (s *MemoryKeyStore) ListPlaintext(ctx context.Context) ([]types.APIKey, error) {
+	return s.filter(func(key *types.APIKey) bool { return key.Key != "" }), nil
+}
+
+func (s *MemoryKeyStore) MigratePlaintext(ctx context.Context, id bson.ObjectID, rawKey, keyID, hash string) error {
+	migrated := false
+	err := s.update(id, func(key *types.APIKey) bool {
+		if key.Key != rawKey {
+			return false
+		}
+
+		key.KeyID = keyID
+		key.KeyHash = hash
+		key.Legacy = true
+		key.Key = ""
+		migrated = true
+		return true
+	})
+	if err == nil && !migrated {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *MemoryKeyStore) ChangedSince(ctx context.Context, since time.Time) ([]types.APIKey, error) {
+	return s.filter(func(key *types.APIKey) bool {
+		return (key.RevokedAt != nil && !key.RevokedAt.Before(since)) ||
+			(key.RotatedAt != nil && !key.RotatedAt.Before(since))
+	}), nil
+}
+
+type memoryEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// memoryCacheSweep is how often writes to a memoryCache also drop its
+// expired entries, so that keys never read again do not pile up.
+const memoryCacheSweep = time.Minute
+
+// memoryCache is a string cache with per entry expiry, shared by the
+// in-memory caches below.
+type memoryCache struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{entries: make(map[string]memoryEntry), lastSweep: time.Now()}
+}
+
+// sweepLocked drops expired entries, at most once per memoryCacheSweep.
+// c.mu must be held.
+func (c *memoryCache) sweepLocked(now time.Time) {
+	if now.Sub(c.lastSweep) < memoryCacheSweep {
+		return
+	}
+	c.lastSweep = now
+
+	for key, entry := range c.entries {
+		if !entry.expiresAt.IsZero() && now.After(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *memoryCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		return "", false
+	}
+
+	return entry.value, true
+}
+
+func (c *memoryCache) set(key, value string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.sweepLocked(now)
+
+	entry := memoryEntry{value: value}
+	if ttl > 0 {
+		entry.expiresAt = now.Add(ttl)
+	}
+	c.entries[key] = entry
+}
+
+// setNX stores value only if key is absent or expired.
+func (c *memoryCache) setNX(key, value string, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.sweepLocked(now)
+
+	if entry, ok := c.entries[key]; ok && (entry.expiresAt.IsZero() || now.Before(entry.expiresAt)) {
+		return false
+	}
+
+	c.entries[key] = memoryEntry{value: value, expiresAt: now.Add(ttl)}
+	return true
+}
+
+func (c *memoryCache) delete(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+}
+
+func (c *memoryCache) deletePrefix(prefix string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var deleted []string
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+			deleted = append(deleted, key)
+		}
+	}
+	return deleted
+}
+
+type MemoryKeyCache struct {
+	cache *memoryCache
+
+	mu          sync.Mutex
+	nextID      int
+	subscribers map[int]func(string)
+}
+
+func NewMemoryKeyCache() *MemoryKeyCache {
+	return &MemoryKeyCache{
+		cache:       newMemoryCache(),
+		subscribers: make(map[int]func(string)),
+	}
+}
+
+func (c *MemoryKeyCache) Get(ctx context.Context, cacheKey string) (string, error) {
+	if value, ok := c.cache.get(cacheKey); ok {
+		return value, nil
+	}
+	return "", ErrCacheMiss
+}
+
+func (c *MemoryKeyCache) Set(ctx context.Context, cacheKey, value string, ttl time.Duration) error {
+	c.cache.set(cacheKey, value, ttl)
+	return nil
+}
+
+func (c *MemoryKeyCache) Delete(ctx context.Context, cacheKeys ...string) error {
+	c.cache.delete(cacheKeys...)
+	return nil
+}
+
+func (c *MemoryKeyCache) Purge(ctx context.Context) ([]string, error) {
+	return c.cache.deletePrefix(strings.TrimSuffix(keyCachePattern, "*")), nil
+}
+
+func (c *MemoryKeyCache) ClaimNonce(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	return c.cache.setNX(nonce, "1", ttl), nil
+}
+
+func (c *MemoryKeyCache) Publish(ctx context.Context, cacheKey string) error {
+	c.mu.Lock()
+	subscribers := make([]func(string), 0, len(c.subscribers))
+	for _, revoked := range c.subscribers {
+		subscribers = append(subscribers, revoked)
+	}
+	c.mu.Unlock()
+
+	for _, revoked := range subscribers {
+		revoked(cacheKey)
+	}
+	return nil
+}
+
+func (c *MemoryKeyCache) Subscribe(ctx context.Context, subscribed func(), revoked func(cacheKey string)) error {
+	c.mu.Lock()
+	id := c.nextID
+	c.nextID++
+	c.subscribers[id] = revoked
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.subscribers, id)
+		c.mu.Unlock()
+	}()
+
+	subscribed()
+	<-ctx.Done()
+	return nil
+}
+
+type MemoryBalanceCache struct {
+	cache *memoryCache
+}
+
+func NewMemoryBalanceCache() *MemoryBalanceCache {
+	return &MemoryBalanceCache{cache: newMemoryCache()}
+}
+
+func (c *MemoryBalanceCache) Get(ctx context.Context, address string) (float64, error) {
+	value, ok := c.cache.get(address)
+	if !ok {
+		return 0, ErrCacheMiss
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func (c *MemoryBalanceCache) Set(ctx context.Context, address string, balance float64, ttl time.Duration) error {
+	c.cache.set(address, strconv.FormatFloat(balance, 'f', -1, 64), ttl)
+	return nil
+}
+
+func (c *MemoryBalanceCache) Purge(ctx context.Context) (int, error) {
+	return len(c.cache.deletePrefix("")), nil
+}
+
+type MemoryUsageCache struct {
+	mu     sync.Mutex
+	quotas map[string]int64
+	// quotaDay is the day quotas holds counters for; counters of earlier
+	// days and months are dropped when it changes.
+	quotaDay string
+	buckets  map[string]*memoryBucket
+}
+
+type memoryBucket struct {
+	bucket   UsageBucket
+	counters types.UsageCounters
+}
+
+func NewMemoryUsageCache() *MemoryUsageCache {
+	return &MemoryUsageCache{
+		quotas:  make(map[string]int64),
+		buckets: make(map[string]*memoryBucket),
+	}
+}
+
+func (c *MemoryUsageCache) IncrementQuota(ctx context.Context, keyID string, now time.Time) (int64, int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.dropPastQuotasLocked(now)
+	c.quotas[dailyQuotaKey(keyID, now)]++
+	c.quotas[monthlyQuotaKey(keyID, now)]++
+	return c.quotas[dailyQuotaKey(keyID, now)], c.quotas[monthlyQuotaKey(keyID, now)], nil
+}
+
+// dropPastQuotasLocked drops the counters of days and months before now,
+// once per day. c.mu must be held.
+func (c *MemoryUsageCache) dropPastQuotasLocked(now time.Time) {
+	day := now.UTC().Format("20060102")
+	if day == c.quotaDay {
+		return
+	}
+	c.quotaDay = day
+
+	month := now.UTC().Format("200601")
+	for key := range c.quotas {
+		if !strings.HasSuffix(key, ":d:"+day) && !strings.HasSuffix(key, ":m:"+month) {
+			delete(c.quotas, key)
+		}
+	}
+}
+
+func (c *MemoryUsageCache) DecrementQuota(ctx context.Context, keyID string, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.quotas[dailyQuotaKey(keyID, now)]--
+	c.quotas[monthlyQuotaKey(keyID, now)]--
+	return nil
+}
+
+func (c *MemoryUsageCache) QuotaUsed(ctx context.Context, keyID string, now time.Time) (int64, int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.quotas[dailyQuotaKey(keyID, now)], c.quotas[monthlyQuotaKey(keyID, now)], nil
+}
+
+func (c *MemoryUsageCache) Record(ctx context.Context, keyID string, minute time.Time, counters types.UsageCounters) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	bucket := UsageBucket{KeyID: keyID, Minute: minute.UTC()}
+	key := usageBucketKey(bucket)
+
+	entry, ok := c.buckets[key]
+	if !ok {
+		entry = &memoryBucket{bucket: bucket}
+		c.buckets[key] = entry
+	}
+	entry.counters.Add(counters)
+	return nil
+}
+
+func (c *MemoryUsageCache) Pending(ctx context.Context) ([]UsageBucket, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	buckets := make([]UsageBucket, 0, len(c.buckets))
+	for _, entry := range c.buckets {
+		buckets = append(buckets, entry.bucket)
+	}
+	return buckets, nil
+}
+
+func (c *MemoryUsageCache) Take(ctx context.Context, bucket UsageBucket) (types.UsageCounters, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := usageBucketKey(bucket)
+	entry, ok := c.buckets[key]
+	if !ok {
+		return types.UsageCounters{}, nil
+	}
+
+	delete(c.buckets, key)
+	return entry.counters, nil
+}
+
+func (c *MemoryUsageCache) Peek(ctx context.Context, bucket UsageBucket) (types.UsageCounters, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.buckets[usageBucketKey(bucket)]
+	if !ok {
+		return types.UsageCounters{}, false, nil
+	}
+	return entry.counters, true, nil
+}
+
+type MemoryUsageStore struct {
+	mu      sync.Mutex
+	records map[string]*types.UsageRecord
+}
+
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{records: make(map[string]*types.UsageRecord)}
+}
+
+func (s *MemoryUsageStore) Add(ctx context.Context, keyID string, minute time.Time, counters types.UsageCounters) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := usageBucketKey(UsageBucket{KeyID: keyID, Minute: minute})
+	record, ok := s.records[key]
+	if !ok {
+		record = &types.UsageRecord{KeyID: keyID, Minute: minute.UTC()}
+		s.records[key] = record
+	}
+	record.UsageCounters.Add(counters)
+	return nil
+}
+
+func (s *MemoryUsageStore) History(ctx context.Context, keyID string, from, to time.Time, limit int) ([]types.UsageRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := []types.UsageRecord{}
+	for _, record := range s.records {
+		if record.KeyID == keyID && !record.Minute.Before(from) && record.Minute.Before(to) {
+			records = append(records, *record)
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Minute.Before(records[j].Minute)
+	})
+
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+type MemoryJobStore struct {
+	mu     sync.Mutex
+	jobs   map[bson.ObjectID]*types.BalanceJob
+	chunks map[bson.ObjectID][]types.JobChunk
+}
+
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{
+		jobs:   make(map[bson.ObjectID]*types.BalanceJob),
+		chunks: make(map[bson.ObjectID][]types.JobChunk),
+	}
+}
+
+func (s *MemoryJobStore) Create(ctx context.Context, job *types.BalanceJob, chunks []types.JobChunk) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clone := *job
+	s.jobs[job.ID] = &clone
+	s.chunks[job.ID] = slices.Clone(chunks)
+	return nil
+}
+
+func (s *MemoryJobStore) Get(ctx context.Context, id bson.ObjectID) (*types.BalanceJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	clone := *job
+	return &clone, nil
+}
+
+func (s *MemoryJobStore) Claim(ctx context.Context, owner string, now, until time.Time) (*types.BalanceJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var claimed *types.BalanceJob
+	for _, job := range s.jobs {
+		waiting := job.Status == types.JobQueued ||
+			(job.Status == types.JobRunning && job.LeasedUntil != nil && job.LeasedUntil.Before(now))
+		if waiting && (claimed == nil || job.CreatedAt.Before(claimed.CreatedAt)) {
+			claimed = job
+		}
+	}
+	if claimed == nil {
+		return nil, ErrNotFound
+	}
+
+	claimed.Status = types.JobRunning
+	claimed.LeaseOwner = owner
+	claimed.LeasedUntil = &until
+	if claimed.StartedAt == nil {
+		claimed.StartedAt = &now
+	}
+
+	clone := *claimed
+	return &clone, nil
+}
+
+// leased returns the job while owner holds its lease. The caller must hold
+// s.mu.
+func (s *MemoryJobStore) leased(id bson.ObjectID, owner string) (*types.BalanceJob, error) {
+	job, ok := s.jobs[id]
+	if !ok || job.Status != types.JobRunning || job.LeaseOwner != owner {
+		return nil, ErrLeaseLost
+	}
+	return job, nil
+}
+
+func (s *MemoryJobStore) Extend(ctx context.Context, id bson.ObjectID, owner string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, err := s.leased(id, owner)
+	if err != nil {
+		return err
+	}
+	job.LeasedUntil = &until
+	return nil
+}
+
+func (s *MemoryJobStore) PendingChunks(ctx context.Context, id bson.ObjectID) ([]types.JobChunk, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := []types.JobChunk{}
+	for _, chunk := range s.chunks[id] {
+		if !chunk.Done {
+			pending = append(pending, chunk)
+		}
+	}
+	return pending, nil
+}
+
+func (s *MemoryJobStore) CompleteChunk(ctx context.Context, id bson.ObjectID, owner string, index int, results []types.WalletBalance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, err := s.leased(id, owner)
+	if err != nil {
+		return err
+	}
+
+	chunks := s.chunks[id]
+	if index < 0 || index >= len(chunks) || chunks[index].Done {
+		return nil
+	}
+
+	chunks[index].Results = slices.Clone(results)
+	chunks[index].Done = true
+	job.Processed += len(results)
+	job.Failed += countFailed(results)
+	return nil
+}
+
+func (s *MemoryJobStore) Finish(ctx context.Context, id bson.ObjectID, owner string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, err := s.leased(id, owner)
+	if err != nil {
+		return err
+	}
+
+	job.Processed, job.Failed = 0, 0
+	for _, chunk := range s.chunks[id] {
+		job.Processed += len(chunk.Results)
+		job.Failed += countFailed(chunk.Results)
+	}
+
+	job.Status = types.JobCompleted
+	job.CompletedAt = &at
+	job.LeaseOwner = ""
+	job.LeasedUntil = nil
+	return nil
+}
+
+func (s *MemoryJobStore) Results(ctx context.Context, id bson.ObjectID, visit func([]types.WalletBalance) error) error {
+	s.mu.Lock()
+	results := make([][]types.WalletBalance, 0, len(s.chunks[id]))
+	for _, chunk := range s.chunks[id] {
+		results = append(results, chunk.Results)
+	}
+	s.mu.Unlock()
+
+	for _, chunk := range results {
+		if err := visit(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,334 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"nova/api/types"
+)
+
+type MongoKeyStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoKeyStore(collection *mongo.Collection) *MongoKeyStore {
+	return &MongoKeyStore{collection: collection}
+}
+
+func (s *MongoKeyStore) findOne(ctx context.Context, filter bson.M) (*types.APIKey, error) {
+	var keyDoc types.APIKey
+	err := s.collection.FindOne(ctx, filter).Decode(&keyDoc)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &keyDoc, nil
+}
+
+func (s *MongoKeyStore) find(ctx context.Context, filter bson.M, opts ...options.Lister[options.FindOptions]) ([]types.APIKey, error) {
+	cursor, err := s.collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []types.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s *MongoKeyStore) Insert(ctx context.Context, key *types.APIKey) error {
+	_, err := s.collection.InsertOne(ctx, key)
+	return err
+}
+
+func (s *MongoKeyStore) Get(ctx context.Context, id bson.ObjectID) (*types.APIKey, error) {
+	return s.findOne(ctx, bson.M{"_id": id})
+}
+
+func (s *MongoKeyStore) List(ctx context.Context, offset, limit int) ([]types.APIKey, int64, error) {
+	total, err := s.collection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+
+	keys, err := s.find(ctx, bson.M{}, opts)
+	return keys, total, err
+}
+
+func (s *MongoKeyStore) FindActiveByKeyID(ctx context.Context, keyID string) (*types.APIKey, error) {
+	return s.findOne(ctx, bson.M{"key_id": keyID, "active": true})
+}
+
+func (s *MongoKeyStore) FindActiveLegacy(ctx context.Context, hash string) (*types.APIKey, error) {
+	return s.findOne(ctx, bson.M{"key_hash": hash, "legacy": true, "active": true})
+}
+
+func (s *MongoKeyStore) FindActivePlaintext(ctx context.Context, rawKey string) (*types.APIKey, error) {
+	return s.findOne(ctx, bson.M{"key": rawKey, "active": true})
+}
+
+func (s *MongoKeyStore) Revoke(ctx context.Context, id bson.ObjectID, at time.Time) error {
+	result, err := s.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"active": false, "revoked_at": at},
+	})
+	if err == nil && result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *MongoKeyStore) Rotate(ctx context.Context, id bson.ObjectID, rotation KeyRotation) error {
+	retired := bson.M{"cache_key": rotation.RetiredCacheKey, "retired_at": rotation.RotatedAt}
+
+	// A pipeline, so that the retired cache keys can be filtered and
+	// appended to in one update.
+	result, err := s.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.A{
+		bson.M{"$set": bson.M{
+			"key_id":         rotation.KeyID,
+			"key_hash":       rotation.KeyHash,
+			"signing_secret": rotation.SigningSecret,
+			"rotated_at":     rotation.RotatedAt,
+			"retired_cache_keys": bson.M{"$concatArrays": bson.A{
+				bson.M{"$filter": bson.M{
+					"input": bson.M{"$ifNull": bson.A{"$retired_cache_keys", bson.A{}}},
+					"cond":  bson.M{"$gte": bson.A{"$$this.retired_at", rotation.DropRetiredBefore}},
+				}},
+				bson.A{retired},
+			}},
+		}},
+		bson.M{"$unset": bson.A{"key", "legacy"}},
+	})
+	if err == nil && result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *MongoKeyStore) ListPlaintext(ctx context.Context) ([]types.APIKey, error) {
+	return s.find(ctx, bson.M{"key": bson.M{"$exists": true, "$ne": ""}})
+}
+
+func (s *MongoKeyStore) MigratePlaintext(ctx context.Context, id bson.ObjectID, rawKey, keyID, hash string) error {
+	result, err := s.collection.UpdateOne(ctx, bson.M{"_id": id, "key": rawKey}, bson.M{
+		"$set":   bson.M{"key_id": keyID, "key_hash": hash, "legacy": true},
+		"$unset": bson.M{"key": ""},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *MongoKeyStore) ChangedSince(ctx context.Context, since time.Time) ([]types.APIKey, error) {
+	return s.find(ctx, bson.M{"$or": bson.A{
+		bson.M{"revoked_at": bson.M{"$gte": since}},
+		bson.M{"rotated_at": bson.M{"$gte": since}},
+	}})
+}
+
+type MongoUsageStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoUsageStore(collection *mongo.Collection) *MongoUsageStore {
+	return &MongoUsageStore{collection: collection}
+}
+
+func (s *MongoUsageStore) Add(ctx context.Context, keyID string, minute time.Time, counters types.UsageCounters) error {
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"key_id": keyID, "minute": minute},
+		bson.M{"$inc": bson.M{
+			"requests":   counters.Requests,
+			"wallets":    counters.Wallets,
+			"rpc_calls":  counters.RPCCalls,
+			"cache_hits": counters.CacheHits,
+		}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	return err
+}
+
+func (s *MongoUsageStore) History(ctx context.Context, keyID string, from, to time.Time, limit int) ([]types.UsageRecord, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "minute", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := s.collection.Find(ctx, bson.M{
+		"key_id": keyID,
+		"minute": bson.M{"$gte": from, "$lt": to},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []types.UsageRecord{}
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+type MongoJobStore struct {
+	jobs   *mongo.Collection
+	chunks *mongo.Collection
+}
+
+func NewMongoJobStore(jobs, chunks *mongo.Collection) *MongoJobStore {
+	return &MongoJobStore{jobs: jobs, chunks: chunks}
+}
+
+// Create inserts the chunks first so a job is never claimed before its
+// addresses are stored.
+func (s *MongoJobStore) Create(ctx context.Context, job *types.BalanceJob, chunks []types.JobChunk) error {
+	if _, err := s.chunks.InsertMany(ctx, chunks); err != nil {
+		return err
+	}
+	_, err := s.jobs.InsertOne(ctx, job)
+	return err
+}
+
+func (s *MongoJobStore) Get(ctx context.Context, id bson.ObjectID) (*types.BalanceJob, error) {
+	var job types.BalanceJob
+	err := s.jobs.FindOne(ctx, bson.M{"_id": id}).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *MongoJobStore) Claim(ctx context.Context, owner string, now, until time.Time) (*types.BalanceJob, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"status": types.JobQueued},
+		bson.M{"status": types.JobRunning, "leased_until": bson.M{"$lt": now}},
+	}}
+	update := bson.M{
+		"$set": bson.M{"status": types.JobRunning, "lease_owner": owner, "leased_until": until},
+		"$min": bson.M{"started_at": now},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job types.BalanceJob
+	err := s.jobs.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *MongoJobStore) updateLeased(ctx context.Context, id bson.ObjectID, owner string, update bson.M) error {
+	result, err := s.jobs.UpdateOne(ctx, bson.M{"_id": id, "status": types.JobRunning, "lease_owner": owner}, update)
+	if err == nil && result.MatchedCount == 0 {
+		return ErrLeaseLost
+	}
+	return err
+}
+
+func (s *MongoJobStore) Extend(ctx context.Context, id bson.ObjectID, owner string, until time.Time) error {
+	return s.updateLeased(ctx, id, owner, bson.M{"$set": bson.M{"leased_until": until}})
+}
+
+func (s *MongoJobStore) PendingChunks(ctx context.Context, id bson.ObjectID) ([]types.JobChunk, error) {
+	cursor, err := s.chunks.Find(ctx,
+		bson.M{"job_id": id, "done": false},
+		options.Find().SetSort(bson.D{{Key: "index", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := []types.JobChunk{}
+	if err := cursor.All(ctx, &chunks); err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+// CompleteChunk counts the results on the job before storing them, so that
+// an instance that lost its lease fails before touching the chunk. If the
+// instance dies in between, or the chunk was already done, Finish corrects
+// the counts.
+func (s *MongoJobStore) CompleteChunk(ctx context.Context, id bson.ObjectID, owner string, index int, results []types.WalletBalance) error {
+	err := s.updateLeased(ctx, id, owner, bson.M{"$inc": bson.M{
+		"processed": len(results),
+		"failed":    countFailed(results),
+	}})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.chunks.UpdateOne(ctx,
+		bson.M{"job_id": id, "index": index, "done": false},
+		bson.M{"$set": bson.M{"results": results, "done": true}},
+	)
+	return err
+}
+
+func (s *MongoJobStore) Finish(ctx context.Context, id bson.ObjectID, owner string, at time.Time) error {
+	processed, failed := 0, 0
+	err := s.Results(ctx, id, func(results []types.WalletBalance) error {
+		processed += len(results)
+		failed += countFailed(results)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.updateLeased(ctx, id, owner, bson.M{
+		"$set": bson.M{
+			"status":       types.JobCompleted,
+			"completed_at": at,
+			"processed":    processed,
+			"failed":       failed,
+		},
+		"$unset": bson.M{"lease_owner": "", "leased_until": ""},
+	})
+}
+
+func (s *MongoJobStore) Results(ctx context.Context, id bson.ObjectID, visit func([]types.WalletBalance) error) error {
+	cursor, err := s.chunks.Find(ctx,
+		bson.M{"job_id": id},
+		options.Find().
+			SetSort(bson.D{{Key: "index", Value: 1}}).
+			SetProjection(bson.M{"results": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var chunk types.JobChunk
+		if err := cursor.Decode(&chunk); err != nil {
+			return err
+		}
+		if err := visit(chunk.Results); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
@@ -0,0 +1,274 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"nova/api/types"
+)
+
+const (
+	RevocationChannel = "api_key_revocations"
+
+	keyCachePattern = "api_key:*"
+	usagePendingKey = "usage:pending"
+	usageBucketTTL  = 2 * time.Hour
+)
+
+type RedisKeyCache struct {
+	client *redis.Client
+}
+
+func NewRedisKeyCache(client *redis.Client) *RedisKeyCache {
+	return &RedisKeyCache{client: client}
+}
+
+func (c *RedisKeyCache) Get(ctx context.Context, cacheKey string) (string, error) {
+	value, err := c.client.Get(ctx, cacheKey).Result()
+	if err == redis.Nil {
+		return "", ErrCacheMiss
+	}
+	return value, err
+}
+
+func (c *RedisKeyCache) Set(ctx context.Context, cacheKey, value string, ttl time.Duration) error {
+	return c.client.Set(ctx, cacheKey, value, ttl).Err()
+}
+
+func (c *RedisKeyCache) Delete(ctx context.Context, cacheKeys ...string) error {
+	if len(cacheKeys) == 0 {
+		return nil
+	}
+	return c.client.Del(ctx, cacheKeys...).Err()
+}
+
+func (c *RedisKeyCache) Purge(ctx context.Context) ([]string, error) {
+	return deleteMatching(ctx, c.client, keyCachePattern)
+}
+
+func (c *RedisKeyCache) ClaimNonce(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, nonce, 1, ttl).Result()
+}
+
+func (c *RedisKeyCache) Publish(ctx context.Context, cacheKey string) error {
+	return c.client.Publish(ctx, RevocationChannel, cacheKey).Err()
+}
+
+func (c *RedisKeyCache) Subscribe(ctx context.Context, subscribed func(), revoked func(cacheKey string)) error {
+	pubsub := c.client.Subscribe(ctx, RevocationChannel)
+	defer pubsub.Close()
+
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			if msg.Kind == "subscribe" {
+				subscribed()
+			}
+		case *redis.Message:
+			revoked(msg.Payload)
+		}
+	}
+}
+
+type RedisBalanceCache struct {
+	client *redis.Client
+}
+
+func NewRedisBalanceCache(client *redis.Client) *RedisBalanceCache {
+	return &RedisBalanceCache{client: client}
+}
+
+func (c *RedisBalanceCache) Get(ctx context.Context, address string) (float64, error) {
+	balance, err := c.client.Get(ctx, "balance:"+address).Float64()
+	if err == redis.Nil {
+		return 0, ErrCacheMiss
+	}
+	return balance, err
+}
+
+func (c *RedisBalanceCache) Set(ctx context.Context, address string, balance float64, ttl time.Duration) error {
+	return c.client.Set(ctx, "balance:"+address, balance, ttl).Err()
+}
+
+func (c *RedisBalanceCache) Purge(ctx context.Context) (int, error) {
+	deleted, err := deleteMatching(ctx, c.client, "balance:*")
+	return len(deleted), err
+}
+
+type RedisUsageCache struct {
+	client *redis.Client
+}
+
+func NewRedisUsageCache(client *redis.Client) *RedisUsageCache {
+	return &RedisUsageCache{client: client}
+}
+
+func dailyQuotaKey(keyID string, now time.Time) string {
+	return "quota:" + keyID + ":d:" + now.UTC().Format("20060102")
+}
+
+func monthlyQuotaKey(keyID string, now time.Time) string {
+	return "quota:" + keyID + ":m:" + now.UTC().Format("200601")
+}
+
+func usageBucketKey(bucket UsageBucket) string {
+	return "usage:" + bucket.KeyID + ":" + strconv.FormatInt(bucket.Minute.Unix(), 10)
+}
+
+func (c *RedisUsageCache) IncrementQuota(ctx context.Context, keyID string, now time.Time) (int64, int64, error) {
+	dailyKey := dailyQuotaKey(keyID, now)
+	monthlyKey := monthlyQuotaKey(keyID, now)
+
+	pipe := c.client.TxPipeline()
+	daily := pipe.Incr(ctx, dailyKey)
+	pipe.Expire(ctx, dailyKey, 48*time.Hour)
+	monthly := pipe.Incr(ctx, monthlyKey)
+	pipe.Expire(ctx, monthlyKey, 32*24*time.Hour)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	return daily.Val(), monthly.Val(), nil
+}
+
+func (c *RedisUsageCache) DecrementQuota(ctx context.Context, keyID string, now time.Time) error {
+	pipe := c.client.TxPipeline()
+	pipe.Decr(ctx, dailyQuotaKey(keyID, now))
+	pipe.Decr(ctx, monthlyQuotaKey(keyID, now))
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *RedisUsageCache) QuotaUsed(ctx context.Context, keyID string, now time.Time) (int64, int64, error) {
+	pipe := c.client.Pipeline()
+	daily := pipe.Get(ctx, dailyQuotaKey(keyID, now))
+	monthly := pipe.Get(ctx, monthlyQuotaKey(keyID, now))
+
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return 0, 0, err
+	}
+
+	dailyUsed, _ := daily.Int64()
+	monthlyUsed, _ := monthly.Int64()
+	return dailyUsed, monthlyUsed, nil
+}
+
+func (c *RedisUsageCache) Record(ctx context.Context, keyID string, minute time.Time, counters types.UsageCounters) error {
+	bucket := usageBucketKey(UsageBucket{KeyID: keyID, Minute: minute})
+
+	pipe := c.client.TxPipeline()
+	pipe.HIncrBy(ctx, bucket, "requests", counters.Requests)
+	pipe.HIncrBy(ctx, bucket, "wallets", counters.Wallets)
+	pipe.HIncrBy(ctx, bucket, "rpc_calls", counters.RPCCalls)
+	pipe.HIncrBy(ctx, bucket, "cache_hits", counters.CacheHits)
+	pipe.Expire(ctx, bucket, usageBucketTTL)
+	pipe.SAdd(ctx, usagePendingKey, bucket)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *RedisUsageCache) Pending(ctx context.Context) ([]UsageBucket, error) {
+	members, err := c.client.SMembers(ctx, usagePendingKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]UsageBucket, 0, len(members))
+	for _, member := range members {
+		bucket, ok := parseUsageBucket(member)
+		if !ok {
+			c.client.SRem(ctx, usagePendingKey, member)
+			continue
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, nil
+}
+
+func (c *RedisUsageCache) Take(ctx context.Context, bucket UsageBucket) (types.UsageCounters, error) {
+	key := usageBucketKey(bucket)
+
+	pipe := c.client.TxPipeline()
+	fields := pipe.HGetAll(ctx, key)
+	pipe.Del(ctx, key)
+	pipe.SRem(ctx, usagePendingKey, key)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return types.UsageCounters{}, err
+	}
+
+	return countersFromHash(fields.Val()), nil
+}
+
+func (c *RedisUsageCache) Peek(ctx context.Context, bucket UsageBucket) (types.UsageCounters, bool, error) {
+	fields, err := c.client.HGetAll(ctx, usageBucketKey(bucket)).Result()
+	if err != nil || len(fields) == 0 {
+		return types.UsageCounters{}, false, err
+	}
+
+	return countersFromHash(fields), true, nil
+}
+
+func parseUsageBucket(key string) (UsageBucket, bool) {
+	rest, ok := strings.CutPrefix(key, "usage:")
+	if !ok {
+		return UsageBucket{}, false
+	}
+
+	index := strings.LastIndex(rest, ":")
+	if index <= 0 {
+		return UsageBucket{}, false
+	}
+
+	unix, err := strconv.ParseInt(rest[index+1:], 10, 64)
+	if err != nil {
+		return UsageBucket{}, false
+	}
+
+	return UsageBucket{KeyID: rest[:index], Minute: time.Unix(unix, 0).UTC()}, true
+}
+
+func countersFromHash(fields map[string]string) types.UsageCounters {
+	value := func(name string) int64 {
+		n, _ := strconv.ParseInt(fields[name], 10, 64)
+		return n
+	}
+
+	return types.UsageCounters{
+		Requests:  value("requests"),
+		Wallets:   value("wallets"),
+		RPCCalls:  value("rpc_calls"),
+		CacheHits: value("cache_hits"),
+	}
+}
+
+func deleteMatching(ctx context.Context, client *redis.Client, pattern string) ([]string, error) {
+	var deleted []string
+	iter := client.Scan(ctx, 0, pattern, 500).Iterator()
+
+	for iter.Next(ctx) {
+		key := iter.Val()
+		if err := client.Del(ctx, key).Err(); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, key)
+	}
+
+	return deleted, iter.Err()
+}
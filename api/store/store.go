@@ -0,0 +1,180 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"nova/api/types"
+)
+
+const (
+	StorageMongo  = "mongo"
+	StorageMemory = "memory"
+)
+
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrCacheMiss = errors.New("cache miss")
+	ErrLeaseLost = errors.New("job lease lost")
+)
+
+// KeyStore is the durable home of API keys.
+type KeyStore interface {
+	Insert(ctx context.Context, key *types.APIKey) error
+	Get(ctx context.Context, id bson.ObjectID) (*types.APIKey, error)
+	List(ctx context.Context, offset, limit int) ([]types.APIKey, int64, error)
+
+	FindActiveByKeyID(ctx context.Context, keyID string) (*types.APIKey, error)
+	FindActiveLegacy(ctx context.Context, hash string) (*types.APIKey, error)
+	FindActivePlaintext(ctx context.Context, rawKey string) (*types.APIKey, error)
+
+	Revoke(ctx context.Context, id bson.ObjectID, at time.Time) error
+	Rotate(ctx context.Context, id bson.ObjectID, rotation KeyRotation) error
+
+	ListPlaintext(ctx context.Context) ([]types.APIKey, error)
+	// MigratePlaintext hashes the key stored as rawKey under keyID. It
+	// returns ErrNotFound when the key is no longer stored in plaintext,
+	// for example because a concurrent call migrated it first.
+	MigratePlaintext(ctx context.Context, id bson.ObjectID, rawKey, keyID, hash string) error
+
+	// ChangedSince returns keys revoked or rotated at or after since.
+	ChangedSince(ctx context.Context, since time.Time) ([]types.APIKey, error)
+}
+
+// KeyRotation replaces a key's credentials. The previous cache key is
+// retired at RotatedAt, and retired cache keys older than
+// DropRetiredBefore, which can no longer be cached, are dropped.
+type KeyRotation struct {
+	KeyID             string
+	KeyHash           string
+	SigningSecret     string
+	RotatedAt         time.Time
+	RetiredCacheKey   string
+	DropRetiredBefore time.Time
+}
+
+// KeyCache holds resolved key statuses shared by every instance, claimed
+// request nonces, and the revocation broadcast.
+type KeyCache interface {
+	Get(ctx context.Context, cacheKey string) (string, error)
+	Set(ctx context.Context, cacheKey, value string, ttl time.Duration) error
+	Delete(ctx context.Context, cacheKeys ...string) error
+
+	// Purge deletes every cached key status and returns the deleted keys.
+	Purge(ctx context.Context) ([]string, error)
+
+	// ClaimNonce reports false when nonce was already claimed within ttl.
+	ClaimNonce(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+
+	Publish(ctx context.Context, cacheKey string) error
+
+	// Subscribe blocks delivering revoked cache keys until ctx is done or
+	// the subscription fails. subscribed is called every time the
+	// subscription is (re)established.
+	Subscribe(ctx context.Context, subscribed func(), revoked func(cacheKey string)) error
+}
+
+type BalanceCache interface {
+	Get(ctx context.Context, address string) (float64, error)
+	Set(ctx context.Context, address string, balance float64, ttl time.Duration) error
+	Purge(ctx context.Context) (int, error)
+}
+
+// UsageCache holds quota counters and per-minute usage buckets that have
+// not been flushed to the UsageStore yet.
+type UsageCache interface {
+	IncrementQuota(ctx context.Context, keyID string, now time.Time) (daily, monthly int64, err error)
+	DecrementQuota(ctx context.Context, keyID string, now time.Time) error
+	QuotaUsed(ctx context.Context, keyID string, now time.Time) (daily, monthly int64, err error)
+
+	Record(ctx context.Context, keyID string, minute time.Time, counters types.UsageCounters) error
+	Pending(ctx context.Context) ([]UsageBucket, error)
+
+	// Take removes a bucket and returns its counters.
+	Take(ctx context.Context, bucket UsageBucket) (types.UsageCounters, error)
+	Peek(ctx context.Context, bucket UsageBucket) (types.UsageCounters, bool, error)
+}
+
+type UsageBucket struct {
+	KeyID  string
+	Minute time.Time
+}
+
+// UsageStore keeps per-minute usage history.
+type UsageStore interface {
+	Add(ctx context.Context, keyID string, minute time.Time, counters types.UsageCounters) error
+	History(ctx context.Context, keyID string, from, to time.Time, limit int) ([]types.UsageRecord, error)
+}
+
+// JobStore persists balance jobs and their chunks. Updates made on behalf
+// of an instance only apply while it still holds the job's lease, and
+// fail with ErrLeaseLost otherwise.
+type JobStore interface {
+	Create(ctx context.Context, job *types.BalanceJob, chunks []types.JobChunk) error
+	Get(ctx context.Context, id bson.ObjectID) (*types.BalanceJob, error)
+
+	// Claim leases the oldest queued job, or a running job whose lease has
+	// expired, to owner. It returns ErrNotFound when no job is waiting.
+	Claim(ctx context.Context, owner string, now, until time.Time) (*types.BalanceJob, error)
+	Extend(ctx context.Context, id bson.ObjectID, owner string, until time.Time) error
+
+	PendingChunks(ctx context.Context, id bson.ObjectID) ([]types.JobChunk, error)
+	// CompleteChunk stores the results of a chunk once; completing it again
+	// has no effect.
+	CompleteChunk(ctx context.Context, id bson.ObjectID, owner string, index int, results []types.WalletBalance) error
+	// Finish marks the job completed and recounts its progress from the
+	// chunks.
+	Finish(ctx context.Context, id bson.ObjectID, owner string, at time.Time) error
+
+	// Results visits the results of every chunk in order.
+	Results(ctx context.Context, id bson.ObjectID, visit func([]types.WalletBalance) error) error
+}
+
+type Stores struct {
+	Keys       KeyStore
+	KeyCache   KeyCache
+	Balances   BalanceCache
+	Usage      UsageStore
+	UsageCache UsageCache
+	Jobs       JobStore
+}
+
+// New returns MongoDB and Redis backed stores.
+func New(cfg *types.Config, db *types.Database) *Stores {
+	database := db.MongoDB.Database(cfg.MongoDatabase)
+
+	return &Stores{
+		Keys:       NewMongoKeyStore(database.Collection("api_keys")),
+		KeyCache:   NewRedisKeyCache(db.Redis),
+		Balances:   NewRedisBalanceCache(db.Redis),
+		Usage:      NewMongoUsageStore(database.Collection("usage")),
+		UsageCache: NewRedisUsageCache(db.Redis),
+		Jobs:       NewMongoJobStore(database.Collection("balance_jobs"), database.Collection("balance_job_chunks")),
+	}
+}
+
+// NewMemory returns stores that live in process memory. Instances sharing
+// the same Stores behave like replicas sharing MongoDB and Redis.
+func NewMemory() *Stores {
+	return &Stores{
+		Keys:       NewMemoryKeyStore(),
+		KeyCache:   NewMemoryKeyCache(),
+		Balances:   NewMemoryBalanceCache(),
+		Usage:      NewMemoryUsageStore(),
+		UsageCache: NewMemoryUsageCache(),
+		Jobs:       NewMemoryJobStore(),
+	}
+}
+
+func countFailed(results []types.WalletBalance) int {
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
@@ -3,10 +3,42 @@ package types
 import "time"
 
 type Config struct {
-	Port         string
-	MongoURI     string
-	RedisURI     string
-	SolaanRPCURL string
-	CacheTTL     time.Duration
-	RateLimit    int
+	Port           string
+	GRPCPort       string
+	MongoURI       string
+	MongoDatabase  string
+	AutoMigrate    bool
+	Storage        string
+	RedisURI       string
+	SolaanRPCURL   string
+	SolanaWSURL    string
+	RPCConcurrency int
+	RequestTimeout time.Duration
+	Network        string
+	CacheTTL       time.Duration
+	RateLimit      int
+	AdminAPIKey    string
+	DocsUI         bool
+
+	// ProxyHeader, such as X-Forwarded-For, holds the client IP on requests
+	// from TrustedProxies, each an address or a CIDR range.
+	ProxyHeader    string
+	TrustedProxies []string
+
+	SigningEncryptionKey string
+	SignatureMaxSkew     time.Duration
+
+	JWKSFile     string
+	JWKSURL      string
+	JWTIssuer    string
+	JWTAudience  string
+	JWTTierClaim string
+
+	RateLimitTiers map[string]RateLimitTier
+}
+
+type RateLimitTier struct {
+	RequestsPerMinute int
+	Burst             int
+	MaxWallets        int
 }
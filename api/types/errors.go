@@ -0,0 +1,72 @@
+package types
+
+// Error codes shared by every API version, with the HTTP status each is
+// sent with. Per-wallet errors only use invalid_address, upstream_error and
+// upstream_timeout, and come back with a 200.
+const (
+	// 400: the body, a query parameter or a header could not be used.
+	CodeInvalidRequest = "invalid_request"
+	// 400: the address is not a base58 Solana public key.
+	CodeInvalidAddress = "invalid_address"
+	// 400: more wallets than the tier allows. Details: max, tier.
+	CodeTooManyWallets = "too_many_wallets"
+	// 400: a GraphQL query costs more than the tier's burst. Details: cost,
+	// max.
+	CodeQueryTooComplex = "query_too_complex"
+	// 200: an automatic persisted query hash that the server has not seen.
+	// The client should resend it with the query.
+	CodePersistedQueryNotFound = "persisted_query_not_found"
+	// 401: no credentials, or they are invalid or revoked.
+	CodeUnauthorized = "unauthorized"
+	// 401: the API key is past its expiry.
+	CodeKeyExpired = "key_expired"
+	// 403: the key is not allowed here. Details: scope or network, if any.
+	CodeForbidden = "forbidden"
+	// 404: the route, job or key does not exist.
+	CodeNotFound = "not_found"
+	// 409: the resource is not in a state that allows the request.
+	CodeConflict = "conflict"
+	// 429: too many requests per minute. Details: limit, tier.
+	CodeRateLimited = "rate_limited"
+	// 429: the daily or monthly quota is used up. Details: period, limit.
+	CodeQuotaExceeded = "quota_exceeded"
+	// 502: the Solana RPC returned an error.
+	CodeUpstreamError = "upstream_error"
+	// 504: the Solana RPC did not answer before the request deadline.
+	CodeUpstreamTimeout = "upstream_timeout"
+	// 503: the feature is not configured on this server.
+	CodeUnavailable = "unavailable"
+	// 500: anything else. The message carries no internal details.
+	CodeInternal = "internal_error"
+)
+
+// APIError is the error body of /api/v1 routes.
+type APIError struct {
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+}
+
+// WalletError is a per-wallet error in /api/v1 responses.
+type WalletError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// BalanceResult is the /api/v1 form of a WalletBalance.
+type BalanceResult struct {
+	Address string       `json:"address"`
+	Balance float64      `json:"balance"`
+	Error   *WalletError `json:"error,omitempty"`
+}
+
+type BalanceResultsResponse struct {
+	Success bool            `json:"success"`
+	Data    []BalanceResult `json:"data"`
+}
+
+type BalanceResultStreamItem struct {
+	Index int `json:"index"`
+	BalanceResult
+}
@@ -0,0 +1,54 @@
+package types
+
+// GraphQLRequest is the body of POST /graphql. GET requests carry the same
+// fields as query parameters, with variables and extensions JSON encoded.
+type GraphQLRequest struct {
+	Query         string                 `json:"query,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	Extensions    *GraphQLExtensions     `json:"extensions,omitempty"`
+}
+
+type GraphQLExtensions struct {
+	PersistedQuery *PersistedQuery `json:"persistedQuery,omitempty"`
+}
+
+// PersistedQuery follows the automatic persisted queries protocol: the
+// client sends only the hash, and the query with it when the server does
+// not know the hash yet.
+type PersistedQuery struct {
+	Version    int    `json:"version"`
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+type GraphQLResponse struct {
+	Data       map[string]interface{} `json:"data,omitempty"`
+	Errors     []GraphQLError         `json:"errors,omitempty"`
+	Extensions *GraphQLCost           `json:"extensions,omitempty"`
+}
+
+// GraphQLError is an error in a GraphQL response. Requests rejected before
+// the query ran, by authentication or rate limits for example, get a body
+// of just errors with the HTTP status of the error code.
+type GraphQLError struct {
+	Message    string                  `json:"message"`
+	Locations  []GraphQLLocation       `json:"locations,omitempty"`
+	Path       []interface{}           `json:"path,omitempty"`
+	Extensions *GraphQLErrorExtensions `json:"extensions,omitempty"`
+}
+
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type GraphQLErrorExtensions struct {
+	Code      string                 `json:"code"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+}
+
+// GraphQLCost reports what a query cost, in rate-limit credits.
+type GraphQLCost struct {
+	Cost int `json:"cost"`
+}
@@ -0,0 +1,47 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+)
+
+// BalanceJob tracks a balance snapshot too large for a single request. The
+// addresses and results live in JobChunks.
+type BalanceJob struct {
+	ID          bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	KeyID       string        `bson:"key_id" json:"-"`
+	Status      string        `bson:"status" json:"status"`
+	Total       int           `bson:"total" json:"total"`
+	Processed   int           `bson:"processed" json:"processed"`
+	Failed      int           `bson:"failed" json:"failed"`
+	Chunks      int           `bson:"chunks" json:"-"`
+	CreatedAt   time.Time     `bson:"created_at" json:"created_at"`
+	StartedAt   *time.Time    `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	CompletedAt *time.Time    `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+
+	// The instance processing the job holds a lease on it. A job whose
+	// lease ran out is picked up again by another instance.
+	LeaseOwner  string     `bson:"lease_owner,omitempty" json:"-"`
+	LeasedUntil *time.Time `bson:"leased_until,omitempty" json:"-"`
+}
+
+type JobChunk struct {
+	JobID     bson.ObjectID   `bson:"job_id"`
+	Index     int             `bson:"index"`
+	Addresses []string        `bson:"addresses"`
+	Results   []WalletBalance `bson:"results,omitempty"`
+	Done      bool            `bson:"done"`
+	CreatedAt time.Time       `bson:"created_at"`
+}
+
+type JobResponse struct {
+	Success bool        `json:"success"`
+	Data    *BalanceJob `json:"data"`
+}
@@ -2,15 +2,127 @@ package types
 
 import (
 	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	TierFree       = "free"
+	TierPro        = "pro"
+	TierEnterprise = "enterprise"
 )
 
 type APIKey struct {
-	Key       string    `bson:"key"`
-	Active    bool      `bson:"active"`
-	CreatedAt time.Time `bson:"created_at"`
+	ID        bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	KeyID     string        `bson:"key_id,omitempty" json:"key_id"`
+	KeyHash   string        `bson:"key_hash,omitempty" json:"-"`
+	Key       string        `bson:"key,omitempty" json:"-"`
+	Legacy    bool          `bson:"legacy,omitempty" json:"legacy,omitempty"`
+	Name      string        `bson:"name,omitempty" json:"name,omitempty"`
+	Owner     string        `bson:"owner,omitempty" json:"owner,omitempty"`
+	Tier      string        `bson:"tier,omitempty" json:"tier,omitempty"`
+	Active    bool          `bson:"active" json:"active"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+	ExpiresAt *time.Time    `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	RevokedAt *time.Time    `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	RotatedAt *time.Time    `bson:"rotated_at,omitempty" json:"rotated_at,omitempty"`
+
+	RetiredCacheKeys []RetiredCacheKey `bson:"retired_cache_keys,omitempty" json:"-"`
+
+	Scopes         []string `bson:"scopes,omitempty" json:"scopes,omitempty"`
+	Networks       []string `bson:"networks,omitempty" json:"networks,omitempty"`
+	AllowedIPs     []string `bson:"allowed_ips,omitempty" json:"allowed_ips,omitempty"`
+	AllowedOrigins []string `bson:"allowed_origins,omitempty" json:"allowed_origins,omitempty"`
+
+	DailyQuota   int64 `bson:"daily_quota,omitempty" json:"daily_quota,omitempty"`
+	MonthlyQuota int64 `bson:"monthly_quota,omitempty" json:"monthly_quota,omitempty"`
+
+	SigningSecret    string `bson:"signing_secret,omitempty" json:"-"`
+	RequireSignature bool   `bson:"require_signature,omitempty" json:"require_signature,omitempty"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name           string     `json:"name"`
+	Owner          string     `json:"owner"`
+	Tier           string     `json:"tier"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	Scopes         []string   `json:"scopes"`
+	Networks       []string   `json:"networks"`
+	AllowedIPs     []string   `json:"allowed_ips"`
+	AllowedOrigins []string   `json:"allowed_origins"`
+	DailyQuota     int64      `json:"daily_quota"`
+	MonthlyQuota   int64      `json:"monthly_quota"`
+
+	RequireSignature bool `json:"require_signature"`
+}
+
+type APIKeyResponse struct {
+	Success       bool    `json:"success"`
+	Data          *APIKey `json:"data"`
+	Key           string  `json:"key,omitempty"`
+	SigningSecret string  `json:"signing_secret,omitempty"`
+}
+
+type APIKeyListResponse struct {
+	Success bool     `json:"success"`
+	Data    []APIKey `json:"data"`
+	Page    int      `json:"page"`
+	Limit   int      `json:"limit"`
+	Total   int64    `json:"total"`
+}
+
+type MigrationResponse struct {
+	Success  bool `json:"success"`
+	Migrated int  `json:"migrated"`
 }
 
 type CacheEntry struct {
 	Balance   float64
 	Timestamp time.Time
 }
+
+// RetiredCacheKey is the cache key under which a key was cached before a
+// rotation replaced its key ID.
+type RetiredCacheKey struct {
+	CacheKey  string    `bson:"cache_key"`
+	RetiredAt time.Time `bson:"retired_at"`
+}
+
+func (k *APIKey) Principal() *Principal {
+	scopes := k.Scopes
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+
+	tier := k.Tier
+	if tier == "" {
+		tier = TierFree
+	}
+
+	return &Principal{
+		ID:             k.ID.Hex(),
+		KeyID:          k.KeyID,
+		KeyHash:        k.KeyHash,
+		Legacy:         k.Legacy,
+		Owner:          k.Owner,
+		Tier:           tier,
+		Scopes:         scopes,
+		Networks:       k.Networks,
+		AllowedIPs:     k.AllowedIPs,
+		AllowedOrigins: k.AllowedOrigins,
+		ExpiresAt:      k.ExpiresAt,
+		DailyQuota:     k.DailyQuota,
+		MonthlyQuota:   k.MonthlyQuota,
+
+		SigningSecret:    k.SigningSecret,
+		RequireSignature: k.RequireSignature,
+	}
+}
+
+func IsValidTier(tier string) bool {
+	switch tier {
+	case TierFree, TierPro, TierEnterprise:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package types
+
+import (
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	ScopeBalanceRead = "balance:read"
+	ScopeTokensRead  = "tokens:read"
+	ScopeTxSend      = "tx:send"
+	ScopeAdmin       = "admin"
+)
+
+const (
+	NetworkMainnet = "mainnet-beta"
+	NetworkDevnet  = "devnet"
+	NetworkTestnet = "testnet"
+)
+
+var DefaultScopes = []string{ScopeBalanceRead}
+
+type Principal struct {
+	ID             string     `json:"id,omitempty"`
+	KeyID          string     `json:"key_id"`
+	KeyHash        string     `json:"key_hash,omitempty"`
+	Legacy         bool       `json:"legacy,omitempty"`
+	Owner          string     `json:"owner,omitempty"`
+	Tier           string     `json:"tier"`
+	Scopes         []string   `json:"scopes"`
+	Networks       []string   `json:"networks,omitempty"`
+	AllowedIPs     []string   `json:"allowed_ips,omitempty"`
+	AllowedOrigins []string   `json:"allowed_origins,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	DailyQuota     int64      `json:"daily_quota,omitempty"`
+	MonthlyQuota   int64      `json:"monthly_quota,omitempty"`
+
+	SigningSecret    string `json:"signing_secret,omitempty"`
+	RequireSignature bool   `json:"require_signature,omitempty"`
+}
+
+func IsValidScope(scope string) bool {
+	switch scope {
+	case ScopeBalanceRead, ScopeTokensRead, ScopeTxSend, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+func IsValidNetwork(network string) bool {
+	switch network {
+	case NetworkMainnet, NetworkDevnet, NetworkTestnet:
+		return true
+	}
+	return false
+}
+
+func IsValidIPRule(rule string) bool {
+	if _, _, err := net.ParseCIDR(rule); err == nil {
+		return true
+	}
+	return net.ParseIP(rule) != nil
+}
+
+// UsageKey identifies the principal's quotas, usage history and jobs. It is
+// the key document's ID, which survives rotation, or KeyID for principals
+// without one, such as bearer tokens.
+func (p *Principal) UsageKey() string {
+	if p.ID != "" {
+		return p.ID
+	}
+	return p.KeyID
+}
+
+func (p *Principal) Expired(now time.Time) bool {
+	return p.ExpiresAt != nil && !now.Before(*p.ExpiresAt)
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+func (p *Principal) AllowsNetwork(network string) bool {
+	return len(p.Networks) == 0 || slices.Contains(p.Networks, network)
+}
+
+func (p *Principal) AllowsIP(ip string) bool {
+	return len(p.AllowedIPs) == 0 || MatchesIPRule(p.AllowedIPs, ip)
+}
+
+// MatchesIPRule reports whether ip is one of rules, each an address or a
+// CIDR range.
+func MatchesIPRule(rules []string, ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	for _, rule := range rules {
+		if _, network, err := net.ParseCIDR(rule); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+			continue
+		}
+
+		if allowed := net.ParseIP(rule); allowed != nil && allowed.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsOrigin matches a browser Origin (or the origin of a Referer) against
+// the allowlist. Entries may use a leading wildcard label, such as
+// https://*.example.com.
+func (p *Principal) AllowsOrigin(origin string) bool {
+	if len(p.AllowedOrigins) == 0 {
+		return true
+	}
+
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return false
+	}
+
+	for _, rule := range p.AllowedOrigins {
+		allowed, err := url.Parse(rule)
+		if err != nil || allowed.Scheme != parsed.Scheme {
+			continue
+		}
+
+		if suffix, ok := strings.CutPrefix(allowed.Host, "*."); ok {
+			if strings.HasSuffix(parsed.Host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+
+		if allowed.Host == parsed.Host {
+			return true
+		}
+	}
+
+	return false
+}
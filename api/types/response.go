@@ -9,4 +9,28 @@ type BalanceResponse struct {
 type ErrorResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
+	Code    string `json:"code,omitempty"`
+}
+
+// BalanceStreamItem is one record of a streamed balance response. Records
+// arrive in the order lookups finish; Index is the wallet's position in the
+// request.
+type BalanceStreamItem struct {
+	Index int `json:"index"`
+	WalletBalance
+}
+
+// BalanceStreamEnd is the last record of a streamed balance response.
+type BalanceStreamEnd struct {
+	Summary BalanceStreamSummary `json:"summary"`
+}
+
+type BalanceStreamSummary struct {
+	Wallets   int   `json:"wallets"`
+	Succeeded int   `json:"succeeded"`
+	Errors    int   `json:"errors"`
+	Timeouts  int   `json:"timeouts"`
+	CacheHits int   `json:"cache_hits"`
+	RPCCalls  int   `json:"rpc_calls"`
+	ElapsedMS int64 `json:"elapsed_ms"`
 }
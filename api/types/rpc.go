@@ -0,0 +1,45 @@
+package types
+
+import "encoding/json"
+
+// JSON-RPC 2.0 error codes. Errors from the Solana node keep the node's own
+// code.
+const (
+	RPCParseError     = -32700
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	// RPCServerError is the code of errors raised by the gateway rather
+	// than the node, such as authentication and rate limits. Their data is
+	// an RPCErrorData.
+	RPCServerError = -32000
+)
+
+// RPCRequest is one call to POST /rpc. The body may also be an array of
+// calls, answered with an array in the same order.
+type RPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type RPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+type RPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// RPCErrorData carries the API error code of an RPCServerError.
+type RPCErrorData struct {
+	Code      string                 `json:"code"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+}
@@ -0,0 +1,41 @@
+package types
+
+// Message types of /ws. Clients send subscribe and unsubscribe; the server
+// answers with the rest.
+const (
+	WSSubscribe    = "subscribe"
+	WSUnsubscribe  = "unsubscribe"
+	WSSubscribed   = "subscribed"
+	WSUnsubscribed = "unsubscribed"
+	WSBalance      = "balance"
+	WSError        = "error"
+)
+
+// WSRequest is a message from a /ws client.
+type WSRequest struct {
+	Type      string   `json:"type"`
+	Addresses []string `json:"addresses"`
+}
+
+// WSAck confirms a subscribe or unsubscribe with the addresses it applied
+// to.
+type WSAck struct {
+	Type      string   `json:"type"`
+	Addresses []string `json:"addresses"`
+}
+
+// BalanceUpdate is the finalized balance of a subscribed address. One is
+// sent when the address is subscribed and one each time it changes.
+type BalanceUpdate struct {
+	Type     string  `json:"type"`
+	Address  string  `json:"address"`
+	Balance  float64 `json:"balance"`
+	Lamports uint64  `json:"lamports"`
+	Slot     uint64  `json:"slot"`
+}
+
+// WSErrorMessage is an error on /ws, with the fields of an APIError.
+type WSErrorMessage struct {
+	Type string `json:"type"`
+	APIError
+}
@@ -0,0 +1,44 @@
+package types
+
+import "time"
+
+type UsageCounters struct {
+	Requests  int64 `bson:"requests" json:"requests"`
+	Wallets   int64 `bson:"wallets" json:"wallets"`
+	RPCCalls  int64 `bson:"rpc_calls" json:"rpc_calls"`
+	CacheHits int64 `bson:"cache_hits" json:"cache_hits"`
+}
+
+type UsageRecord struct {
+	KeyID         string    `bson:"key_id" json:"-"`
+	Minute        time.Time `bson:"minute" json:"minute"`
+	UsageCounters `bson:",inline"`
+}
+
+type QuotaUsage struct {
+	Used     int64     `json:"used"`
+	Limit    int64     `json:"limit,omitempty"`
+	ResetsAt time.Time `json:"resets_at"`
+}
+
+type UsageReport struct {
+	KeyID   string        `json:"key_id"`
+	From    time.Time     `json:"from"`
+	To      time.Time     `json:"to"`
+	Daily   QuotaUsage    `json:"daily"`
+	Monthly QuotaUsage    `json:"monthly"`
+	Totals  UsageCounters `json:"totals"`
+	History []UsageRecord `json:"history"`
+}
+
+type UsageResponse struct {
+	Success bool         `json:"success"`
+	Data    *UsageReport `json:"data"`
+}
+
+func (c *UsageCounters) Add(other UsageCounters) {
+	c.Requests += other.Requests
+	c.Wallets += other.Wallets
+	c.RPCCalls += other.RPCCalls
+	c.CacheHits += other.CacheHits
+}
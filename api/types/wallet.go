@@ -1,11 +1,94 @@
 package types
 
+import "time"
+
 type BalanceRequest struct {
 	Wallets []string `json:"wallets" validate:"required"`
 }
 
+const (
+	SourceCache = "cache"
+	SourceRPC   = "rpc"
+)
+
 type WalletBalance struct {
 	Address string  `json:"address"`
 	Balance float64 `json:"balance"`
 	Error   string  `json:"error,omitempty"`
+	// ErrorCode classifies Error with one of the Code constants.
+	ErrorCode string `json:"-"`
+	// Source is empty for a repeated address answered by its first entry.
+	Source string `json:"-"`
+}
+
+// AccountBalance is a single account read at a given commitment, with the
+// slot the RPC node answered at.
+type AccountBalance struct {
+	Address    string  `json:"address"`
+	Balance    float64 `json:"balance"`
+	Lamports   uint64  `json:"lamports"`
+	Slot       uint64  `json:"slot"`
+	Commitment string  `json:"commitment"`
+
+	FetchedAt time.Time `json:"-"`
+	Source    string    `json:"-"`
+}
+
+type AccountBalanceResponse struct {
+	Success bool            `json:"success"`
+	Data    *AccountBalance `json:"data"`
+}
+
+// TokenBalance is one SPL token account. Amount is in base units and kept
+// as a string, as it may not fit a float64 exactly.
+type TokenBalance struct {
+	Mint     string  `json:"mint"`
+	Account  string  `json:"account"`
+	Amount   string  `json:"amount"`
+	Decimals uint8   `json:"decimals"`
+	UIAmount float64 `json:"ui_amount"`
+}
+
+type OwnerTokenBalances struct {
+	Owner     string         `json:"owner"`
+	Tokens    []TokenBalance `json:"tokens"`
+	Error     string         `json:"error,omitempty"`
+	ErrorCode string         `json:"-"`
+	RPCCalls  int            `json:"-"`
+}
+
+// AccountInfo is the state of one account. Owner is empty and Exists false
+// for an address that holds nothing.
+type AccountInfo struct {
+	Address    string
+	Lamports   uint64
+	Balance    float64
+	Owner      string
+	Executable bool
+	Exists     bool
+	Error      string
+	ErrorCode  string
+}
+
+type Signature struct {
+	Signature string
+	Slot      uint64
+	BlockTime *time.Time
+	Failed    bool
+	Memo      string
+}
+
+// StakeAccount is a stake account, with the delegation fields empty until
+// it is delegated.
+type StakeAccount struct {
+	Address           string
+	Lamports          uint64
+	Balance           float64
+	State             string
+	Staker            string
+	Withdrawer        string
+	Voter             string
+	DelegatedStake    uint64
+	ActivationEpoch   *uint64
+	DeactivationEpoch *uint64
 }
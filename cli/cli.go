@@ -0,0 +1,251 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"nova/api/config"
+	"nova/api/database"
+	"nova/api/store"
+	"nova/api/types"
+)
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(env *env, args []string) error
+}
+
+var commands = []command{
+	{"serve", "serve", "Run the API server (default when no command is given)", serve},
+	{"keys create", "keys create [flags]", "Create an API key", keysCreate},
+	{"keys list", "keys list [--page N] [--limit N]", "List API keys", keysList},
+	{"keys show", "keys show <id>", "Show an API key", keysShow},
+	{"keys revoke", "keys revoke <id>", "Revoke an API key", keysRevoke},
+	{"keys rotate", "keys rotate <id>", "Issue a new secret for an API key", keysRotate},
+	{"usage report", "usage report <id> [--from RFC3339] [--to RFC3339]", "Show usage and quotas for an API key", usageReport},
+	{"cache purge", "cache purge [--balances] [--keys]", "Purge cached balances and API key statuses", cachePurge},
+	{"db migrate", "db migrate", "Apply schema migrations and hash plaintext API keys", dbMigrate},
+	{"db status", "db status", "List applied and pending schema migrations", dbStatus},
+	{"db ensure-indexes", "db ensure-indexes", "Create missing MongoDB indexes", dbEnsureIndexes},
+	{"config validate", "config validate", "Load and validate the configuration", configValidate},
+}
+
+// errUsage is returned after usage has already been printed.
+var errUsage = errors.New("usage")
+
+// Run executes the command named by args and returns the process exit code.
+// Every command accepts --json to print machine readable output.
+func Run(args []string) int {
+	env := &env{stdout: os.Stdout, stderr: os.Stderr}
+	defer env.close()
+
+	if len(args) > 0 && (args[0] == "--json" || args[0] == "-json") {
+		env.json = true
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+
+	cmd, rest, ok := findCommand(args)
+	if !ok {
+		printUsage(env.stderr)
+		if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+			return 0
+		}
+		return 2
+	}
+
+	env.usage = cmd.usage
+	if err := cmd.run(env, rest); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+
+		env.fail(err)
+		return 1
+	}
+
+	return 0
+}
+
+func findCommand(args []string) (command, []string, bool) {
+	if len(args) >= 2 {
+		name := args[0] + " " + args[1]
+		for _, cmd := range commands {
+			if cmd.name == name {
+				return cmd, args[2:], true
+			}
+		}
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd, args[1:], true
+		}
+	}
+
+	return command{}, nil, false
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: nova <command> [flags] [--json]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.usage, cmd.summary)
+	}
+	tw.Flush()
+}
+
+type env struct {
+	stdout io.Writer
+	stderr io.Writer
+	json   bool
+	usage  string
+
+	cfg *types.Config
+	db  *types.Database
+}
+
+// flags returns a flag set for the named command with the shared --json flag
+// already registered.
+func (e *env) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.BoolVar(&e.json, "json", e.json, "print JSON output")
+	return fs
+}
+
+// parse parses flags and returns positional arguments. Unlike fs.Parse,
+// flags may follow positional arguments.
+func (e *env) parse(fs *flag.FlagSet, args []string, positional int) ([]string, error) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+
+	if len(rest) != positional {
+		fmt.Fprintf(e.stderr, "Usage: nova %s\n", e.usage)
+		return nil, errUsage
+	}
+
+	return rest, nil
+}
+
+// config loads configuration the same way the server does: .env first, then
+// the environment.
+func (e *env) config() (*types.Config, error) {
+	if e.cfg != nil {
+		return e.cfg, nil
+	}
+
+	if err := config.LoadEnvFile(); err != nil {
+		return nil, fmt.Errorf("failed to load .env file: %w", err)
+	}
+
+	cfg, err := config.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(cfg); err != nil {
+		return nil, err
+	}
+
+	e.cfg = cfg
+	return cfg, nil
+}
+
+func (e *env) database() (*types.Config, *types.Database, error) {
+	cfg, err := e.config()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.Storage == store.StorageMemory {
+		return nil, nil, errors.New("in-memory storage cannot be managed from the CLI, set STORAGE=mongo")
+	}
+
+	if e.db == nil {
+		db, err := database.Connect(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		e.db = db
+	}
+
+	return cfg, e.db, nil
+}
+
+func (e *env) stores() (*types.Config, *store.Stores, error) {
+	cfg, db, err := e.database()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, store.New(cfg, db), nil
+}
+
+func (e *env) close() {
+	if e.db != nil {
+		database.Close(e.db)
+	}
+}
+
+// print writes value as JSON when --json is set and calls text otherwise.
+func (e *env) print(value interface{}, text func(w io.Writer)) error {
+	if e.json {
+		encoder := json.NewEncoder(e.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+
+	tw := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+	text(tw)
+	return tw.Flush()
+}
+
+func (e *env) fail(err error) {
+	if e.json {
+		e.print(types.ErrorResponse{Success: false, Message: err.Error()}, nil)
+		return
+	}
+
+	fmt.Fprintln(e.stderr, "Error:", err)
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,375 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"nova/api"
+	"nova/api/config"
+	"nova/api/database"
+	"nova/api/services"
+	"nova/api/store"
+	"nova/api/types"
+)
+
+const commandTimeout = 30 * time.Second
+
+func commandContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), commandTimeout)
+}
+
+func serve(env *env, args []string) error {
+	fs := env.flags("serve")
+	if _, err := env.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	api.Main()
+	return nil
+}
+
+func usageReport(env *env, args []string) error {
+	fs := env.flags("usage report")
+	now := time.Now().UTC()
+	from := fs.String("from", "", "start of the report window (RFC 3339, default 24h ago)")
+	to := fs.String("to", "", "end of the report window (RFC 3339, default now)")
+
+	positional, err := env.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	fromTime, err := parseTimeFlag("from", *from, now.Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+
+	toTime, err := parseTimeFlag("to", *to, now.Add(time.Minute))
+	if err != nil {
+		return err
+	}
+
+	if !fromTime.Before(toTime) {
+		return fmt.Errorf("from must be before to")
+	}
+
+	cfg, stores, err := env.stores()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	keyService, err := services.NewKeyService(cfg, stores)
+	if err != nil {
+		return err
+	}
+
+	keyDoc, err := keyService.Get(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+
+	report, err := services.NewUsageService(stores).Report(ctx, keyDoc.Principal(), fromTime, toTime, now)
+	if err != nil {
+		return err
+	}
+
+	return env.print(types.UsageResponse{Success: true, Data: report}, func(w io.Writer) {
+		fmt.Fprintf(w, "Key ID:\t%s\n", report.KeyID)
+		fmt.Fprintf(w, "Window:\t%s to %s\n", report.From.Format(time.RFC3339), report.To.Format(time.RFC3339))
+		fmt.Fprintf(w, "Daily:\t%s\n", formatQuota(report.Daily))
+		fmt.Fprintf(w, "Monthly:\t%s\n", formatQuota(report.Monthly))
+		fmt.Fprintf(w, "Requests:\t%d\n", report.Totals.Requests)
+		fmt.Fprintf(w, "Wallets:\t%d\n", report.Totals.Wallets)
+		fmt.Fprintf(w, "RPC calls:\t%d\n", report.Totals.RPCCalls)
+		fmt.Fprintf(w, "Cache hits:\t%d\n", report.Totals.CacheHits)
+	})
+}
+
+func parseTimeFlag(name, value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s timestamp (expected RFC 3339)", name)
+	}
+	return parsed, nil
+}
+
+func formatQuota(quota types.QuotaUsage) string {
+	if quota.Limit == 0 {
+		return fmt.Sprintf("%d (unlimited)", quota.Used)
+	}
+	return fmt.Sprintf("%d of %d, resets %s", quota.Used, quota.Limit, quota.ResetsAt.Format(time.RFC3339))
+}
+
+type cachePurgeResponse struct {
+	Success  bool `json:"success"`
+	Balances int  `json:"balances"`
+	Keys     int  `json:"keys"`
+}
+
+func cachePurge(env *env, args []string) error {
+	fs := env.flags("cache purge")
+	balances := fs.Bool("balances", false, "purge cached wallet balances")
+	keys := fs.Bool("keys", false, "purge cached API key statuses")
+
+	if _, err := env.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	if !*balances && !*keys {
+		*balances, *keys = true, true
+	}
+
+	cfg, stores, err := env.stores()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	response := cachePurgeResponse{Success: true}
+
+	if *balances {
+		response.Balances, err = services.NewSolanaService(cfg.SolaanRPCURL, stores.Balances).PurgeBalanceCache(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to purge balances: %w", err)
+		}
+	}
+
+	if *keys {
+		keyService, err := services.NewKeyService(cfg, stores)
+		if err != nil {
+			return err
+		}
+
+		response.Keys, err = keyService.PurgeKeyCache(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to purge API key statuses: %w", err)
+		}
+	}
+
+	return env.print(response, func(w io.Writer) {
+		fmt.Fprintf(w, "Purged %d cached balances and %d cached API key statuses\n", response.Balances, response.Keys)
+	})
+}
+
+type migrateResponse struct {
+	Success  bool                       `json:"success"`
+	Applied  []database.MigrationRecord `json:"applied"`
+	Migrated int                        `json:"migrated"`
+}
+
+// dbMigrate applies pending schema migrations and then hashes any API keys
+// still stored in plaintext.
+func dbMigrate(env *env, args []string) error {
+	fs := env.flags("db migrate")
+	if _, err := env.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	cfg, db, err := env.database()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	keyService, err := services.NewKeyService(cfg, store.New(cfg, db))
+	if err != nil {
+		return err
+	}
+
+	applied, err := database.Migrate(ctx, cfg, db)
+	if err != nil {
+		return err
+	}
+
+	migrated, err := keyService.MigratePlaintextKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	response := migrateResponse{Success: true, Applied: applied, Migrated: migrated}
+	if response.Applied == nil {
+		response.Applied = []database.MigrationRecord{}
+	}
+
+	return env.print(response, func(w io.Writer) {
+		if len(applied) == 0 {
+			fmt.Fprintln(w, "Schema is up to date")
+		}
+		for _, record := range applied {
+			fmt.Fprintf(w, "Applied migration %d\t%s\n", record.Version, record.Name)
+		}
+		fmt.Fprintf(w, "Migrated %d plaintext API keys\n", migrated)
+	})
+}
+
+type migrationStatusResponse struct {
+	Success    bool                       `json:"success"`
+	Migrations []database.MigrationRecord `json:"migrations"`
+}
+
+func dbStatus(env *env, args []string) error {
+	fs := env.flags("db status")
+	if _, err := env.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	cfg, db, err := env.database()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	status, err := database.MigrationStatus(ctx, cfg, db)
+	if err != nil {
+		return err
+	}
+
+	return env.print(migrationStatusResponse{Success: true, Migrations: status}, func(w io.Writer) {
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, record := range status {
+			applied := "pending"
+			if record.AppliedAt != nil {
+				applied = record.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", record.Version, record.Name, applied)
+		}
+	})
+}
+
+type indexesResponse struct {
+	Success bool                   `json:"success"`
+	Indexes []database.IndexResult `json:"indexes"`
+}
+
+func dbEnsureIndexes(env *env, args []string) error {
+	fs := env.flags("db ensure-indexes")
+	if _, err := env.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	cfg, db, err := env.database()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	indexes, err := database.EnsureIndexes(ctx, cfg, db)
+	if err != nil {
+		return err
+	}
+
+	return env.print(indexesResponse{Success: true, Indexes: indexes}, func(w io.Writer) {
+		fmt.Fprintln(w, "COLLECTION\tINDEX")
+		for _, index := range indexes {
+			fmt.Fprintf(w, "%s\t%s\n", index.Collection, index.Name)
+		}
+	})
+}
+
+type configSummary struct {
+	Port           string `json:"port"`
+	MongoURI       string `json:"mongo_uri"`
+	MongoDatabase  string `json:"mongo_database"`
+	RedisURI       string `json:"redis_uri"`
+	SolanaRPCHost  string `json:"solana_rpc_host"`
+	Network        string `json:"network"`
+	AdminAPI       bool   `json:"admin_api"`
+	RequestSigning bool   `json:"request_signing"`
+	JWTAuth        bool   `json:"jwt_auth"`
+}
+
+type configResponse struct {
+	Success bool           `json:"success"`
+	Config  *configSummary `json:"config"`
+}
+
+// configValidate never prints secrets: URIs are stripped of credentials and
+// keys are reported only as enabled or not.
+func configValidate(env *env, args []string) error {
+	fs := env.flags("config validate")
+	if _, err := env.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	if err := config.LoadEnvFile(); err != nil {
+		return fmt.Errorf("failed to load .env file: %w", err)
+	}
+
+	cfg, err := config.Parse()
+	if err != nil {
+		return err
+	}
+
+	if err := config.Validate(cfg); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	summary := &configSummary{
+		Port:           cfg.Port,
+		MongoURI:       redactURI(cfg.MongoURI),
+		MongoDatabase:  cfg.MongoDatabase,
+		RedisURI:       redactURI(cfg.RedisURI),
+		SolanaRPCHost:  rpcHost(cfg.SolaanRPCURL),
+		Network:        cfg.Network,
+		AdminAPI:       cfg.AdminAPIKey != "",
+		RequestSigning: cfg.SigningEncryptionKey != "",
+		JWTAuth:        cfg.JWKSFile != "" || cfg.JWKSURL != "",
+	}
+
+	return env.print(configResponse{Success: true, Config: summary}, func(w io.Writer) {
+		fmt.Fprintf(w, "Port:\t%s\n", summary.Port)
+		fmt.Fprintf(w, "MongoDB:\t%s (database %s)\n", summary.MongoURI, summary.MongoDatabase)
+		fmt.Fprintf(w, "Redis:\t%s\n", summary.RedisURI)
+		fmt.Fprintf(w, "Solana RPC:\t%s (%s)\n", summary.SolanaRPCHost, summary.Network)
+		fmt.Fprintf(w, "Admin API:\t%s\n", enabled(summary.AdminAPI))
+		fmt.Fprintf(w, "Request signing:\t%s\n", enabled(summary.RequestSigning))
+		fmt.Fprintf(w, "JWT auth:\t%s\n", enabled(summary.JWTAuth))
+		fmt.Fprintln(w, "\nConfiguration is valid")
+	})
+}
+
+func redactURI(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" {
+		return value
+	}
+
+	if parsed.User != nil {
+		parsed.User = url.User("redacted")
+	}
+	return parsed.String()
+}
+
+// rpcHost drops the path and query, which usually carry the provider API key.
+func rpcHost(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return "invalid URL"
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+func enabled(value bool) string {
+	if value {
+		return "enabled"
+	}
+	return "disabled"
+}
@@ -0,0 +1,270 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"nova/api/services"
+	"nova/api/types"
+)
+
+func keysCreate(env *env, args []string) error {
+	fs := env.flags("keys create")
+	name := fs.String("name", "", "name of the API key")
+	owner := fs.String("owner", "", "owner of the API key")
+	tier := fs.String("tier", types.TierFree, "rate limit tier (free, pro, enterprise)")
+	expires := fs.String("expires", "", "expiry as a duration (720h) or RFC 3339 timestamp")
+	scopes := fs.String("scopes", "", "comma separated scopes")
+	networks := fs.String("networks", "", "comma separated Solana networks")
+	allowedIPs := fs.String("allowed-ips", "", "comma separated IPs or CIDR ranges")
+	allowedOrigins := fs.String("allowed-origins", "", "comma separated browser origins")
+	dailyQuota := fs.Int64("daily-quota", 0, "requests allowed per UTC day (0 for unlimited)")
+	monthlyQuota := fs.Int64("monthly-quota", 0, "requests allowed per UTC month (0 for unlimited)")
+	requireSignature := fs.Bool("require-signature", false, "only accept HMAC signed requests")
+
+	if _, err := env.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	req := types.CreateAPIKeyRequest{
+		Name:             *name,
+		Owner:            *owner,
+		Tier:             *tier,
+		Scopes:           splitList(*scopes),
+		Networks:         splitList(*networks),
+		AllowedIPs:       splitList(*allowedIPs),
+		AllowedOrigins:   splitList(*allowedOrigins),
+		DailyQuota:       *dailyQuota,
+		MonthlyQuota:     *monthlyQuota,
+		RequireSignature: *requireSignature,
+	}
+
+	if *expires != "" {
+		expiresAt, err := parseExpiry(*expires, time.Now())
+		if err != nil {
+			return err
+		}
+		req.ExpiresAt = &expiresAt
+	}
+
+	keyService, err := env.keyService()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	keyDoc, rawKey, err := keyService.Create(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return env.printKey(types.APIKeyResponse{
+		Success:       true,
+		Data:          keyDoc,
+		Key:           rawKey,
+		SigningSecret: keyService.RevealSigningSecret(keyDoc),
+	})
+}
+
+func keysList(env *env, args []string) error {
+	fs := env.flags("keys list")
+	page := fs.Int("page", 1, "page number")
+	limit := fs.Int("limit", 50, "keys per page (max 100)")
+
+	if _, err := env.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	if *page < 1 || *limit < 1 || *limit > 100 {
+		return fmt.Errorf("page must be at least 1 and limit between 1 and 100")
+	}
+
+	keyService, err := env.keyService()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	keys, total, err := keyService.List(ctx, *page, *limit)
+	if err != nil {
+		return err
+	}
+
+	response := types.APIKeyListResponse{
+		Success: true,
+		Data:    keys,
+		Page:    *page,
+		Limit:   *limit,
+		Total:   total,
+	}
+
+	return env.print(response, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tKEY ID\tNAME\tOWNER\tTIER\tSTATUS\tCREATED")
+		for i := range keys {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				keys[i].ID.Hex(),
+				orDash(keys[i].KeyID),
+				orDash(keys[i].Name),
+				orDash(keys[i].Owner),
+				orDash(keys[i].Tier),
+				keyStatus(&keys[i]),
+				keys[i].CreatedAt.Format(time.RFC3339),
+			)
+		}
+		fmt.Fprintf(w, "\nPage %d, %d of %d keys\n", *page, len(keys), total)
+	})
+}
+
+func keysShow(env *env, args []string) error {
+	return keyAction(env, "keys show", args, func(keyService *services.KeyService, id string) (*types.APIKey, string, error) {
+		ctx, cancel := commandContext()
+		defer cancel()
+
+		keyDoc, err := keyService.Get(ctx, id)
+		return keyDoc, "", err
+	})
+}
+
+func keysRevoke(env *env, args []string) error {
+	return keyAction(env, "keys revoke", args, func(keyService *services.KeyService, id string) (*types.APIKey, string, error) {
+		ctx, cancel := commandContext()
+		defer cancel()
+
+		keyDoc, err := keyService.Revoke(ctx, id)
+		return keyDoc, "", err
+	})
+}
+
+func keysRotate(env *env, args []string) error {
+	return keyAction(env, "keys rotate", args, func(keyService *services.KeyService, id string) (*types.APIKey, string, error) {
+		ctx, cancel := commandContext()
+		defer cancel()
+
+		return keyService.Rotate(ctx, id)
+	})
+}
+
+func keyAction(env *env, name string, args []string, action func(*services.KeyService, string) (*types.APIKey, string, error)) error {
+	fs := env.flags(name)
+
+	positional, err := env.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	keyService, err := env.keyService()
+	if err != nil {
+		return err
+	}
+
+	keyDoc, rawKey, err := action(keyService, positional[0])
+	if err != nil {
+		return err
+	}
+
+	response := types.APIKeyResponse{
+		Success: true,
+		Data:    keyDoc,
+		Key:     rawKey,
+	}
+	if rawKey != "" {
+		response.SigningSecret = keyService.RevealSigningSecret(keyDoc)
+	}
+
+	return env.printKey(response)
+}
+
+func (e *env) keyService() (*services.KeyService, error) {
+	cfg, stores, err := e.stores()
+	if err != nil {
+		return nil, err
+	}
+
+	return services.NewKeyService(cfg, stores)
+}
+
+func (e *env) printKey(response types.APIKeyResponse) error {
+	keyDoc := response.Data
+
+	return e.print(response, func(w io.Writer) {
+		fmt.Fprintf(w, "ID:\t%s\n", keyDoc.ID.Hex())
+		fmt.Fprintf(w, "Key ID:\t%s\n", orDash(keyDoc.KeyID))
+		fmt.Fprintf(w, "Name:\t%s\n", orDash(keyDoc.Name))
+		fmt.Fprintf(w, "Owner:\t%s\n", orDash(keyDoc.Owner))
+		fmt.Fprintf(w, "Tier:\t%s\n", orDash(keyDoc.Tier))
+		fmt.Fprintf(w, "Status:\t%s\n", keyStatus(keyDoc))
+		fmt.Fprintf(w, "Created:\t%s\n", keyDoc.CreatedAt.Format(time.RFC3339))
+
+		if keyDoc.ExpiresAt != nil {
+			fmt.Fprintf(w, "Expires:\t%s\n", keyDoc.ExpiresAt.Format(time.RFC3339))
+		}
+		if len(keyDoc.Scopes) > 0 {
+			fmt.Fprintf(w, "Scopes:\t%s\n", strings.Join(keyDoc.Scopes, ", "))
+		}
+		if len(keyDoc.Networks) > 0 {
+			fmt.Fprintf(w, "Networks:\t%s\n", strings.Join(keyDoc.Networks, ", "))
+		}
+		if len(keyDoc.AllowedIPs) > 0 {
+			fmt.Fprintf(w, "Allowed IPs:\t%s\n", strings.Join(keyDoc.AllowedIPs, ", "))
+		}
+		if len(keyDoc.AllowedOrigins) > 0 {
+			fmt.Fprintf(w, "Allowed origins:\t%s\n", strings.Join(keyDoc.AllowedOrigins, ", "))
+		}
+		if keyDoc.DailyQuota > 0 {
+			fmt.Fprintf(w, "Daily quota:\t%d\n", keyDoc.DailyQuota)
+		}
+		if keyDoc.MonthlyQuota > 0 {
+			fmt.Fprintf(w, "Monthly quota:\t%d\n", keyDoc.MonthlyQuota)
+		}
+		if keyDoc.RequireSignature {
+			fmt.Fprintf(w, "Signature:\trequired\n")
+		}
+
+		if response.Key != "" {
+			fmt.Fprintf(w, "\nAPI key:\t%s\n", response.Key)
+		}
+		if response.SigningSecret != "" {
+			fmt.Fprintf(w, "Signing secret:\t%s\n", response.SigningSecret)
+		}
+		if response.Key != "" {
+			fmt.Fprintln(w, "\nStore the key now, it cannot be shown again.")
+		}
+	})
+}
+
+func parseExpiry(value string, now time.Time) (time.Time, error) {
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(duration), nil
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expiry %q (expected a duration or RFC 3339 timestamp)", value)
+	}
+	return expiresAt, nil
+}
+
+func keyStatus(keyDoc *types.APIKey) string {
+	switch {
+	case !keyDoc.Active:
+		return "revoked"
+	case keyDoc.ExpiresAt != nil && !keyDoc.ExpiresAt.After(time.Now()):
+		return "expired"
+	case keyDoc.Legacy:
+		return "active (legacy)"
+	}
+	return "active"
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
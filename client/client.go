@@ -0,0 +1,358 @@
+// Package client is a Go client for the Nova balance API.
+//
+//	c := client.New("https://nova.example.com", client.WithAPIKey(key))
+//	balances, err := c.GetBalances(ctx, wallets)
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"nova/api/signing"
+	"nova/api/types"
+)
+
+// DefaultChunkSize is the most wallets every tier accepts in one call.
+// GetBalances splits larger lists into several calls.
+const DefaultChunkSize = 100
+
+var ErrInvalidChunkSize = errors.New("nova: chunk size must be at least 1")
+
+type (
+	BalanceRequest  = types.BalanceRequest
+	BalanceResponse = types.BalanceResponse
+	WalletBalance   = types.WalletBalance
+	UsageReport     = types.UsageReport
+	ErrorResponse   = types.ErrorResponse
+)
+
+// APIError is returned for every non-2xx response.
+type APIError struct {
+	StatusCode int
+	Message    string
+	Code       string
+
+	// RetryAfter is how long the server asked the client to wait, zero when
+	// the response did not say.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("nova: %d %s (%s)", e.StatusCode, e.Message, e.Code)
+	}
+	return fmt.Sprintf("nova: %d %s", e.StatusCode, e.Message)
+}
+
+func (e *APIError) retryable() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests:
+		// Quotas reset daily or monthly, retrying cannot help.
+		return e.Code != "quota_exceeded"
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+type Option func(*Client)
+
+func WithAPIKey(apiKey string) Option {
+	return func(c *Client) { c.apiKey = apiKey }
+}
+
+// WithSigning signs every request with the key's HMAC signing secret.
+// keyID is the public id of the key, the part after the nova_live_ prefix.
+func WithSigning(keyID, secret string) Option {
+	return func(c *Client) {
+		c.keyID = keyID
+		c.signingSecret = secret
+	}
+}
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.http = httpClient }
+}
+
+// WithChunkSize sends up to size wallets per call, for keys whose tier
+// accepts more than DefaultChunkSize. GetBalances fails with
+// ErrInvalidChunkSize when size is below 1.
+func WithChunkSize(size int) Option {
+	return func(c *Client) { c.chunkSize = size }
+}
+
+// WithRetries sets how many times a rate limited or unavailable request is
+// retried. Zero disables retries.
+func WithRetries(retries int) Option {
+	return func(c *Client) { c.retries = retries }
+}
+
+// WithBackoff sets the first wait between retries when the server gives no
+// Retry-After, and the longest wait the client accepts either way.
+func WithBackoff(base, max time.Duration) Option {
+	return func(c *Client) {
+		c.backoff = base
+		c.maxWait = max
+	}
+}
+
+type Client struct {
+	baseURL       string
+	http          *http.Client
+	apiKey        string
+	keyID         string
+	signingSecret string
+	chunkSize     int
+	retries       int
+	backoff       time.Duration
+	maxWait       time.Duration
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		http:      &http.Client{Timeout: 30 * time.Second},
+		chunkSize: DefaultChunkSize,
+		retries:   3,
+		backoff:   500 * time.Millisecond,
+		maxWait:   30 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// GetBalances returns the balance of every wallet in the order given.
+// Wallets that could not be looked up have Error set rather than failing
+// the whole call.
+func (c *Client) GetBalances(ctx context.Context, wallets []string) ([]WalletBalance, error) {
+	if c.chunkSize < 1 {
+		return nil, ErrInvalidChunkSize
+	}
+
+	results := make([]WalletBalance, 0, len(wallets))
+
+	var throttle time.Duration
+	for start := 0; start < len(wallets); start += c.chunkSize {
+		end := min(start+c.chunkSize, len(wallets))
+
+		// The last chunk used up the rate limit, so the next one would be
+		// rejected.
+		if err := sleep(ctx, min(throttle, c.maxWait)); err != nil {
+			return nil, err
+		}
+
+		body, err := json.Marshal(BalanceRequest{Wallets: wallets[start:end]})
+		if err != nil {
+			return nil, err
+		}
+
+		var response BalanceResponse
+		throttle, err = c.do(ctx, http.MethodPost, "/api/get-balance", body, &response)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, response.Data...)
+	}
+
+	return results, nil
+}
+
+// GetBalance returns the balance of one wallet in SOL.
+func (c *Client) GetBalance(ctx context.Context, wallet string) (float64, error) {
+	results, err := c.GetBalances(ctx, []string{wallet})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(results) != 1 {
+		return 0, fmt.Errorf("nova: expected 1 balance, got %d", len(results))
+	}
+	if results[0].Error != "" {
+		return 0, errors.New(results[0].Error)
+	}
+	return results[0].Balance, nil
+}
+
+// Usage returns the usage of the authenticated key between from and to.
+func (c *Client) Usage(ctx context.Context, from, to time.Time) (*UsageReport, error) {
+	query := url.Values{}
+	query.Set("from", from.UTC().Format(time.RFC3339))
+	query.Set("to", to.UTC().Format(time.RFC3339))
+
+	var response types.UsageResponse
+	if _, err := c.do(ctx, http.MethodGet, "/api/usage?"+query.Encode(), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+// do sends the request, retrying it when the server asks to. It returns
+// how long to wait before the next request when the response reports no
+// rate limit left.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, out interface{}) (time.Duration, error) {
+	wait := c.backoff
+
+	for attempt := 0; ; attempt++ {
+		header, err := c.send(ctx, method, path, body, out)
+		if err == nil {
+			return throttle(header), nil
+		}
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !apiErr.retryable() || attempt >= c.retries {
+			return 0, err
+		}
+
+		delay := wait
+		if apiErr.RetryAfter > 0 {
+			delay = apiErr.RetryAfter
+		}
+		if delay > c.maxWait {
+			return 0, err
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return 0, err
+		}
+
+		wait *= 2
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, body []byte, out interface{}) (http.Header, error) {
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if err := c.authenticate(req, method, path, body); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    http.StatusText(resp.StatusCode),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+
+		var errorResponse ErrorResponse
+		if json.Unmarshal(respBody, &errorResponse) == nil && errorResponse.Message != "" {
+			apiErr.Message = errorResponse.Message
+			apiErr.Code = errorResponse.Code
+		}
+		return nil, apiErr
+	}
+
+	return resp.Header, json.Unmarshal(respBody, out)
+}
+
+// authenticate signs the request when a signing secret is set, since keys
+// that require signatures reject a bare X-API-Key. Every attempt gets a
+// fresh timestamp and nonce.
+func (c *Client) authenticate(req *http.Request, method, path string, body []byte) error {
+	if c.signingSecret == "" {
+		if c.apiKey != "" {
+			req.Header.Set("X-API-Key", c.apiKey)
+		}
+		return nil
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonceHex := hex.EncodeToString(nonce)
+
+	req.Header.Set(signing.HeaderKeyID, c.keyID)
+	req.Header.Set(signing.HeaderTimestamp, timestamp)
+	req.Header.Set(signing.HeaderNonce, nonceHex)
+	req.Header.Set(signing.HeaderSignature, signing.Sign(c.signingSecret, method, path, timestamp, nonceHex, body))
+	return nil
+}
+
+// throttle is the time for one request to free up when header reports no
+// rate limit left, and zero otherwise. Limits are per minute.
+func throttle(header http.Header) time.Duration {
+	if header.Get("X-RateLimit-Remaining") != "0" {
+		return 0
+	}
+
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil || limit <= 0 {
+		return 0
+	}
+	return time.Minute / time.Duration(limit)
+}
+
+// sleep waits for d unless ctx is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+
+	return 0
+}
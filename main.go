@@ -1,7 +1,11 @@
 package main
 
-import "nova/api"
+import (
+	"os"
+
+	"nova/cli"
+)
 
 func main() {
-	api.Main()
+	os.Exit(cli.Run(os.Args[1:]))
 }
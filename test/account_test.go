@@ -0,0 +1,103 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nova/api/types"
+	"nova/test/fakerpc"
+)
+
+func accountRequest(t *testing.T, ts *TestSuite, path, ifNoneMatch, clientIP string) (*http.Response, []byte) {
+	t.Helper()
+
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set("X-API-Key", ts.testAPIKey)
+	req.Header.Set("X-Forwarded-For", clientIP)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+
+	resp, err := ts.app.Test(req, 30000)
+	require.NoError(t, err)
+
+	body, _ := io.ReadAll(resp.Body)
+	return resp, body
+}
+
+func TestAccount_BalanceWithCaching(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	path := fmt.Sprintf("/api/v1/accounts/%s/balance", testWallets[0])
+
+	resp, body := accountRequest(t, ts, path, "", "172.25.0.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var response types.AccountBalanceResponse
+	require.NoError(t, json.Unmarshal(body, &response))
+	assert.Equal(t, testWallets[0], response.Data.Address)
+	assert.Equal(t, 1.5, response.Data.Balance)
+	assert.Equal(t, uint64(1_500_000_000), response.Data.Lamports)
+	assert.Equal(t, "finalized", response.Data.Commitment)
+	assert.NotZero(t, response.Data.Slot)
+
+	etag := resp.Header.Get("ETag")
+	assert.Equal(t, fmt.Sprintf(`"%x-%x"`, response.Data.Slot, response.Data.Lamports), etag)
+
+	cacheControl := resp.Header.Get("Cache-Control")
+	require.True(t, strings.HasPrefix(cacheControl, "public, max-age="), cacheControl)
+	maxAge, err := strconv.Atoi(strings.TrimPrefix(cacheControl, "public, max-age="))
+	require.NoError(t, err)
+	assert.True(t, maxAge > 0 && maxAge <= 10, "max-age %d should not outlive the cache TTL", maxAge)
+	t.Log("✓ Balance is returned with an ETag and Cache-Control")
+
+	resp, body = accountRequest(t, ts, path, etag, "172.25.0.2")
+	assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+	assert.Empty(t, body)
+	assert.Equal(t, etag, resp.Header.Get("ETag"))
+
+	resp, _ = accountRequest(t, ts, path, `"stale", W/`+etag, "172.25.0.3")
+	assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+
+	resp, _ = accountRequest(t, ts, path, `"stale"`, "172.25.0.4")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, ts.rpc.Calls("getBalance"), "Revalidation should be served from cache")
+	t.Log("✓ If-None-Match returns 304 Not Modified")
+
+	balanceRequest(t, ts, testWallets[:1], "172.25.0.5")
+	assert.Equal(t, 1, ts.rpc.Calls("getBalance"), "POST lookups share the finalized balance")
+
+	resp, body = accountRequest(t, ts, path+"?commitment=confirmed", "", "172.25.0.6")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	require.NoError(t, json.Unmarshal(body, &response))
+	assert.Equal(t, "confirmed", response.Data.Commitment)
+	assert.Equal(t, 2, ts.rpc.Calls("getBalance"))
+	t.Log("✓ Each commitment is cached separately")
+}
+
+func TestAccount_Errors(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	resp, _ := accountRequest(t, ts, "/api/v1/accounts/not-a-wallet/balance", "", "172.25.1.1")
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = accountRequest(t, ts, fmt.Sprintf("/api/v1/accounts/%s/balance?commitment=recent", testWallets[0]), "", "172.25.1.2")
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	ts.rpc.FailAccount(testWallets[1], &fakerpc.Error{Code: -32005, Message: "Node is behind"})
+	resp, _ = accountRequest(t, ts, fmt.Sprintf("/api/v1/accounts/%s/balance", testWallets[1]), "", "172.25.1.3")
+	assert.Equal(t, fiber.StatusBadGateway, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("ETag"), "Errors must not be cached")
+	t.Log("✓ Bad input is rejected and RPC failures are not cacheable")
+}
@@ -0,0 +1,212 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"nova/api/services"
+	"nova/api/store"
+	"nova/api/types"
+)
+
+func adminRequest(t *testing.T, ts *TestSuite, method, path string, body interface{}) (*http.Response, []byte) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		reqBody, _ := json.Marshal(body)
+		reader = bytes.NewReader(reqBody)
+	}
+
+	req, _ := http.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Admin-Key", testAdminKey)
+
+	resp, err := ts.app.Test(req, 30000)
+	require.NoError(t, err)
+
+	respBody, _ := io.ReadAll(resp.Body)
+	return resp, respBody
+}
+
+func authStatus(t *testing.T, ts *TestSuite, apiKey, clientIP string) int {
+	t.Helper()
+
+	req, _ := http.NewRequest("POST", "/api/get-balance", bytes.NewReader([]byte(`{"wallets":[]}`)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", apiKey)
+	req.Header.Set("X-Forwarded-For", clientIP)
+
+	resp, err := ts.app.Test(req, 30000)
+	require.NoError(t, err)
+	return resp.StatusCode
+}
+
+func TestAdmin_RequiresAdminKey(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	req, _ := http.NewRequest("GET", "/admin/keys", nil)
+	resp, err := ts.app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+	req, _ = http.NewRequest("GET", "/admin/keys", nil)
+	req.Header.Set("X-Admin-Key", ts.testAPIKey)
+	resp, err = ts.app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+	t.Log("✓ Admin endpoints reject missing and non-admin credentials")
+}
+
+func TestAdmin_KeyLifecycle(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	expiresAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	resp, body := adminRequest(t, ts, "POST", "/admin/keys", types.CreateAPIKeyRequest{
+		Name:      "lifecycle",
+		Owner:     "test-suite",
+		Tier:      types.TierPro,
+		ExpiresAt: &expiresAt,
+	})
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode, string(body))
+
+	var created types.APIKeyResponse
+	require.NoError(t, json.Unmarshal(body, &created))
+	require.NotEmpty(t, created.Key)
+
+	keyID := created.Data.ID.Hex()
+
+	assert.Equal(t, "lifecycle", created.Data.Name)
+	assert.Equal(t, types.TierPro, created.Data.Tier)
+	assert.True(t, created.Data.Active)
+	assert.Equal(t, fiber.StatusBadRequest, authStatus(t, ts, created.Key, "172.16.0.1"))
+	t.Logf("✓ Created key %s", keyID)
+
+	resp, body = adminRequest(t, ts, "GET", "/admin/keys?page=1&limit=100", nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var listed types.APIKeyListResponse
+	require.NoError(t, json.Unmarshal(body, &listed))
+	assert.GreaterOrEqual(t, listed.Total, int64(1))
+	assert.NotContains(t, string(body), created.Key, "List must not expose key material")
+
+	resp, body = adminRequest(t, ts, "GET", "/admin/keys/"+keyID, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.NotContains(t, string(body), created.Key, "Get must not expose key material")
+
+	resp, body = adminRequest(t, ts, "POST", "/admin/keys/"+keyID+"/rotate", nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode, string(body))
+
+	var rotated types.APIKeyResponse
+	require.NoError(t, json.Unmarshal(body, &rotated))
+	require.NotEqual(t, created.Key, rotated.Key)
+
+	assert.Equal(t, fiber.StatusUnauthorized, authStatus(t, ts, created.Key, "172.16.0.2"))
+	assert.Equal(t, fiber.StatusBadRequest, authStatus(t, ts, rotated.Key, "172.16.0.3"))
+	t.Log("✓ Rotation invalidates the previous key")
+
+	resp, _ = adminRequest(t, ts, "DELETE", "/admin/keys/"+keyID, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	assert.Equal(t, fiber.StatusUnauthorized, authStatus(t, ts, rotated.Key, "172.16.0.4"))
+	t.Log("✓ Revocation takes effect immediately")
+
+	resp, _ = adminRequest(t, ts, "GET", "/admin/keys/"+bson.NewObjectID().Hex(), nil)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	resp, _ = adminRequest(t, ts, "POST", "/admin/keys", types.CreateAPIKeyRequest{Tier: "platinum"})
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAdmin_KeysHashedAtRest(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stored, err := ts.stores.Keys.Get(ctx, ts.testKeyID)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(ts.testAPIKey, "nova_live_"))
+	assert.NotContains(t, fmt.Sprintf("%+v", *stored), ts.testAPIKey, "Raw key must not be stored")
+	assert.Equal(t, services.HashAPIKey(ts.testAPIKey), stored.KeyHash)
+
+	keyID, ok := services.ParseAPIKey(ts.testAPIKey)
+	require.True(t, ok)
+	assert.Equal(t, keyID, stored.KeyID)
+
+	forged := services.APIKeyPrefix + keyID + "_" + strings.Repeat("0", 64)
+	assert.Equal(t, fiber.StatusUnauthorized, authStatus(t, ts, forged, "172.16.1.1"))
+	assert.Equal(t, fiber.StatusBadRequest, authStatus(t, ts, ts.testAPIKey, "172.16.1.2"))
+	t.Log("✓ Keys are stored hashed and verified against the public id")
+
+	legacyKey := fmt.Sprintf("legacy-test-key-%d", time.Now().UnixNano())
+	legacyID := bson.NewObjectID()
+	require.NoError(t, ts.stores.Keys.Insert(ctx, &types.APIKey{
+		ID:        legacyID,
+		Key:       legacyKey,
+		Active:    true,
+		CreatedAt: time.Now(),
+	}))
+
+	resp, body := adminRequest(t, ts, "POST", "/admin/keys/migrate", nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode, string(body))
+
+	migrated, err := ts.stores.Keys.Get(ctx, legacyID)
+	require.NoError(t, err)
+	assert.Empty(t, migrated.Key)
+	assert.True(t, migrated.Legacy)
+	assert.Equal(t, services.HashAPIKey(legacyKey), migrated.KeyHash)
+
+	assert.Equal(t, fiber.StatusBadRequest, authStatus(t, ts, legacyKey, "172.16.1.3"))
+	t.Log("✓ Legacy plaintext keys keep working after migration")
+
+	err = ts.stores.Keys.MigratePlaintext(ctx, legacyID, legacyKey, "other-id", services.HashAPIKey(legacyKey))
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	racedKey := fmt.Sprintf("legacy-race-key-%d", time.Now().UnixNano())
+	racedID := bson.NewObjectID()
+	require.NoError(t, ts.stores.Keys.Insert(ctx, &types.APIKey{
+		ID:        racedID,
+		Key:       racedKey,
+		Active:    true,
+		CreatedAt: time.Now(),
+	}))
+
+	principals := make([]*types.Principal, 8)
+	var wg sync.WaitGroup
+	for i := range principals {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			principals[i], _ = ts.server.Keys().Authenticate(ctx, racedKey)
+		}()
+	}
+	wg.Wait()
+
+	raced, err := ts.stores.Keys.Get(ctx, racedID)
+	require.NoError(t, err)
+	for _, principal := range principals {
+		require.NotNil(t, principal)
+		assert.Equal(t, raced.KeyID, principal.KeyID)
+	}
+	t.Log("✓ Concurrent first uses of a plaintext key agree on its stored key id")
+}
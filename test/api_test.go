@@ -6,115 +6,93 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
-	"os"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/joho/godotenv"
-	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
-	"go.mongodb.org/mongo-driver/v2/mongo/options"
 
+	"nova/api"
 	"nova/api/config"
-	"nova/api/middleware"
-	"nova/api/routes"
-	"nova/api/services"
+	"nova/api/store"
 	"nova/api/types"
+	"nova/test/fakerpc"
 )
 
 func setupTest(t *testing.T) *TestSuite {
 	t.Helper()
 
-	middleware.ClearRateLimiters()
+	return newTestSuite(t, types.CreateAPIKeyRequest{Name: "api-test"})
+}
 
-	if err := godotenv.Load("../.env"); err != nil {
-		t.Logf("Warning: Could not load .env file: %v", err)
-	}
+// newTestSuite serves the API from in-memory stores and a fake Solana RPC
+// seeded with testBalances, so the suite needs no network or databases.
+func newTestSuite(tb testing.TB, keyRequest types.CreateAPIKeyRequest, opts ...api.Option) *TestSuite {
+	tb.Helper()
 
-	if os.Getenv("HELIUS_API_KEY") == "" {
-		t.Skip("HELIUS_API_KEY not set - skipping tests")
+	rpc := fakerpc.New()
+	for i, wallet := range testWallets {
+		rpc.SetBalance(wallet, testBalances[i])
 	}
 
-	cfg := config.Load()
+	cfg := testConfig(rpc.URL())
+	cfg.SolanaWSURL = rpc.WSURL()
+	stores := store.NewMemory()
+
+	server, err := api.NewServer(append([]api.Option{
+		api.WithConfig(cfg),
+		api.WithStores(stores),
+		api.WithLogger(log.New(io.Discard, "", 0)),
+	}, opts...)...)
+	require.NoError(tb, err)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	mongoClient, err := mongo.Connect(options.Client().ApplyURI(cfg.MongoURI))
-	require.NoError(t, err, "MongoDB connection should succeed")
-
-	err = mongoClient.Ping(ctx, nil)
-	require.NoError(t, err, "MongoDB ping should succeed")
-
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: cfg.RedisURI,
-	})
-
-	err = redisClient.Ping(ctx).Err()
-	require.NoError(t, err, "Redis ping should succeed")
-
-	solanaService := services.NewSolanaService(cfg.SolaanRPCURL, redisClient)
-
-	testAPIKey := "test-api-key-123"
-	collection := mongoClient.Database("nova").Collection("api_keys")
-
-	collection.DeleteOne(ctx, bson.M{"key": testAPIKey})
-
-	apiKey := types.APIKey{
-		Key:       testAPIKey,
-		Active:    true,
-		CreatedAt: time.Now(),
-	}
-
-	_, err = collection.InsertOne(ctx, apiKey)
-	require.NoError(t, err, "API key insertion should succeed")
-
-	db := &types.Database{
-		MongoDB: mongoClient,
-		Redis:   redisClient,
-	}
-
-	app := fiber.New(fiber.Config{
-		DisableStartupMessage: true,
-	})
-
-	app.Use(middleware.RateLimitMiddleware())
-	app.Use(middleware.AuthMiddleware(db))
-
-	routes.InitSolanaService(solanaService)
-	routes.InitRoutes(app, db)
+	keyDoc, testAPIKey, err := server.Keys().Create(ctx, keyRequest)
+	require.NoError(tb, err, "API key creation should succeed")
 
 	return &TestSuite{
-		app:           app,
-		mongoClient:   mongoClient,
-		redisClient:   redisClient,
-		solanaService: solanaService,
+		app:           server.App(),
+		server:        server,
+		stores:        stores,
+		rpc:           rpc,
+		solanaService: server.Solana(),
 		testAPIKey:    testAPIKey,
+		testKeyID:     keyDoc.ID,
 		cfg:           cfg,
 	}
 }
 
-func (ts *TestSuite) cleanup(t *testing.T) {
-	t.Helper()
-
-	if ts.mongoClient != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		collection := ts.mongoClient.Database("nova").Collection("api_keys")
-		collection.DeleteOne(ctx, bson.M{"key": ts.testAPIKey})
-		ts.mongoClient.Disconnect(ctx)
+func testConfig(rpcURL string) *types.Config {
+	return &types.Config{
+		MongoDatabase:        "nova",
+		Storage:              store.StorageMemory,
+		SolaanRPCURL:         rpcURL,
+		RPCConcurrency:       16,
+		RequestTimeout:       30 * time.Second,
+		Network:              types.NetworkMainnet,
+		CacheTTL:             10 * time.Second,
+		RateLimit:            10,
+		AdminAPIKey:          testAdminKey,
+		SigningEncryptionKey: testSigningEncryptionKey,
+		SignatureMaxSkew:     5 * time.Minute,
+		JWTTierClaim:         "nova_tier",
+		RateLimitTiers:       config.DefaultRateLimitTiers(),
+		ProxyHeader:          fiber.HeaderXForwardedFor,
+		// app.Test requests come from 0.0.0.0 and listeners from loopback.
+		TrustedProxies: []string{"0.0.0.0", "127.0.0.1"},
 	}
+}
 
-	if ts.redisClient != nil {
-		ts.redisClient.Close()
-	}
+func (ts *TestSuite) cleanup(t *testing.T) {
+	t.Helper()
+	ts.rpc.Close()
 }
 
 func TestAPI_SingleWallet(t *testing.T) {
@@ -147,7 +125,7 @@ func TestAPI_SingleWallet(t *testing.T) {
 	assert.True(t, response.Success)
 	assert.Len(t, response.Data, 1)
 	assert.Equal(t, testWallets[0], response.Data[0].Address)
-	assert.GreaterOrEqual(t, response.Data[0].Balance, 0.0)
+	assert.Equal(t, 1.5, response.Data[0].Balance)
 
 	t.Logf("✓ Single wallet test passed - Balance: %f SOL (took %v)", response.Data[0].Balance, duration)
 }
@@ -182,19 +160,72 @@ func TestAPI_MultipleWallets(t *testing.T) {
 	assert.True(t, response.Success)
 	assert.Len(t, response.Data, len(testWallets))
 
+	expected := []float64{1.5, 0.042, 0}
 	for i, result := range response.Data {
 		assert.Equal(t, testWallets[i], result.Address)
-		if result.Error != "" {
-			t.Logf("Wallet %s had error: %s", result.Address, result.Error)
-		} else {
-			assert.GreaterOrEqual(t, result.Balance, 0.0)
-			t.Logf("Wallet %s: %f SOL", result.Address, result.Balance)
-		}
+		assert.Empty(t, result.Error)
+		assert.Equal(t, expected[i], result.Balance)
+		t.Logf("Wallet %s: %f SOL", result.Address, result.Balance)
 	}
 
 	t.Logf("✓ Multiple wallets test passed (took %v)", duration)
 }
 
+func TestAPI_WalletLimitsAndDedupe(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	balances := func(apiKey string, wallets []string, clientIP string) (int, types.BalanceResponse) {
+		reqBody, _ := json.Marshal(types.BalanceRequest{Wallets: wallets})
+		req, _ := http.NewRequest("POST", "/api/get-balance", bytes.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", apiKey)
+		req.Header.Set("X-Forwarded-For", clientIP)
+
+		resp, err := ts.app.Test(req, 30000)
+		require.NoError(t, err)
+
+		var response types.BalanceResponse
+		body, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(body, &response)
+		return resp.StatusCode, response
+	}
+
+	many := make([]string, 101)
+	for i := range many {
+		many[i] = testWallets[i%len(testWallets)]
+	}
+
+	status, _ := balances(ts.testAPIKey, many, "192.168.2.1")
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	t.Log("✓ Free tier is limited to 100 wallets")
+
+	_, proKey, err := ts.server.Keys().Create(context.Background(), types.CreateAPIKeyRequest{Name: "pro", Tier: types.TierPro})
+	require.NoError(t, err)
+
+	wallets := make([]string, 0, 1000)
+	for i := 0; i < 997; i++ {
+		wallets = append(wallets, testWallets[i%2])
+	}
+	wallets = append(wallets, " "+testWallets[0]+" ", testWallets[2], testWallets[1])
+
+	status, response := balances(proKey, wallets, "192.168.2.2")
+	require.Equal(t, fiber.StatusOK, status)
+	require.Len(t, response.Data, len(wallets))
+
+	expected := map[string]float64{testWallets[0]: 1.5, testWallets[1]: 0.042, testWallets[2]: 0}
+	for i, result := range response.Data {
+		assert.Equal(t, strings.TrimSpace(wallets[i]), result.Address)
+		assert.Equal(t, expected[result.Address], result.Balance)
+	}
+	assert.Equal(t, 3, ts.rpc.Calls("getBalance"), "Each distinct address should be fetched once")
+	t.Log("✓ 1000 wallets are deduped to 3 lookups and returned in order")
+
+	status, _ = balances(proKey, append(wallets, testWallets[0]), "192.168.2.3")
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	t.Log("✓ Pro tier is limited to 1000 wallets")
+}
+
 func TestAPI_SameWalletMultipleRequests(t *testing.T) {
 	ts := setupTest(t)
 	defer ts.cleanup(t)
@@ -371,38 +402,45 @@ func TestAPI_RateLimiting(t *testing.T) {
 	}
 	reqBody, _ := json.Marshal(request)
 
-	t.Log("Testing IP rate limiting (10 requests per minute limit)")
+	proKey := createRestrictedKey(t, ts, types.CreateAPIKeyRequest{Name: "pro", Tier: types.TierPro})
 
 	clientIP := "192.168.1.100"
-	successCount := 0
-	rateLimitedCount := 0
-
-	for i := 0; i < 12; i++ {
+	call := func(apiKey string) *http.Response {
 		req, _ := http.NewRequest("POST", "/api/get-balance", bytes.NewReader(reqBody))
 		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("X-API-Key", ts.testAPIKey)
+		req.Header.Set("X-API-Key", apiKey)
 		req.Header.Set("X-Forwarded-For", clientIP)
 
 		resp, err := ts.app.Test(req, 30000)
 		require.NoError(t, err)
+		return resp
+	}
 
-		if resp.StatusCode == fiber.StatusOK {
-			successCount++
-			t.Logf("Request %d: Success", i+1)
-		} else if resp.StatusCode == fiber.StatusTooManyRequests {
+	for i := 0; i < 30; i++ {
+		resp := call(proKey)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode, "request %d", i+1)
+		assert.Equal(t, "1200", resp.Header.Get("X-RateLimit-Limit"))
+	}
+	t.Log("✓ A pro key is limited by its tier, not per IP")
+
+	rateLimitedCount := 0
+	for i := 0; i < 12; i++ {
+		resp := call("invalid-key")
+		if resp.StatusCode == fiber.StatusTooManyRequests {
 			rateLimitedCount++
-			t.Logf("Request %d: Rate limited", i+1)
+			assert.Equal(t, "10", resp.Header.Get("X-RateLimit-Limit"))
+			assert.NotEmpty(t, resp.Header.Get(fiber.HeaderRetryAfter))
 		} else {
-			t.Logf("Request %d: Unexpected status %d", i+1, resp.StatusCode)
+			assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
 		}
 	}
+	assert.GreaterOrEqual(t, rateLimitedCount, 1, "Should rate limit failed requests")
+	t.Log("✓ Failed authentication is limited to 10 requests per minute per IP")
 
-	t.Logf("Rate limiting results: %d successful, %d rate limited", successCount, rateLimitedCount)
-
-	assert.GreaterOrEqual(t, successCount, 1, "Should allow at least 1 request")
-	assert.GreaterOrEqual(t, rateLimitedCount, 1, "Should rate limit some requests")
-
-	t.Log("✓ Rate limiting test passed")
+	resp := call(proKey)
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "10", resp.Header.Get("X-RateLimit-Limit"))
+	t.Log("✓ A limited IP is rejected before its key is looked up")
 }
 
 func TestAPI_Caching(t *testing.T) {
@@ -416,6 +454,7 @@ func TestAPI_Caching(t *testing.T) {
 	reqBody, _ := json.Marshal(request)
 
 	t.Logf("Testing caching behavior for wallet: %s", wallet)
+	ts.rpc.SetLatency(50 * time.Millisecond)
 
 	req1, _ := http.NewRequest("POST", "/api/get-balance", bytes.NewReader(reqBody))
 	req1.Header.Set("Content-Type", "application/json")
@@ -457,6 +496,7 @@ func TestAPI_Caching(t *testing.T) {
 
 	assert.Equal(t, balance1, balance2, "Both requests should return same balance")
 	assert.True(t, duration2 < duration1, "Second request should be faster due to caching")
+	assert.Equal(t, 1, ts.rpc.Calls("getBalance"), "Second request should not reach the RPC")
 
 	speedup := float64(duration1) / float64(duration2)
 	t.Logf("Cache miss: %v, Cache hit: %v, Speedup: %.2fx", duration1, duration2, speedup)
@@ -493,26 +533,21 @@ func TestAPI_AuthenticationAndRateLimiting(t *testing.T) {
 
 	clientIP := "192.168.1.200"
 
-	successCount := 0
 	rateLimitedCount := 0
 	for i := 0; i < 15; i++ {
 		req, _ := http.NewRequest("POST", "/api/get-balance", bytes.NewReader(reqBody))
 		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("X-API-Key", ts.testAPIKey)
+		req.Header.Set("X-API-Key", "invalid-key")
 		req.Header.Set("X-Forwarded-For", clientIP)
 
 		resp, _ := ts.app.Test(req, 30000)
-		if resp.StatusCode == fiber.StatusOK {
-			successCount++
-		} else if resp.StatusCode == fiber.StatusTooManyRequests {
+		if resp.StatusCode == fiber.StatusTooManyRequests {
 			rateLimitedCount++
 		}
 	}
 
-	assert.GreaterOrEqual(t, successCount, 1, "Should allow at least 1 request")
-	assert.GreaterOrEqual(t, rateLimitedCount, 1, "Should rate limit some requests")
-
-	t.Log("✓ Valid API key with rate limiting: Working correctly")
+	assert.GreaterOrEqual(t, rateLimitedCount, 1, "Should rate limit repeated invalid keys")
+	t.Log("✓ Invalid API keys with rate limiting: Working correctly")
 
 	req, _ = http.NewRequest("POST", "/api/get-balance", bytes.NewReader(reqBody))
 	req.Header.Set("Content-Type", "application/json")
@@ -521,13 +556,7 @@ func TestAPI_AuthenticationAndRateLimiting(t *testing.T) {
 
 	resp, err = ts.app.Test(req, 30000)
 	require.NoError(t, err)
-
-	if resp.StatusCode == fiber.StatusOK {
-		t.Log("✓ Valid API key from different IP: Working correctly")
-	} else if resp.StatusCode == fiber.StatusTooManyRequests {
-		t.Log("✓ Valid API key from different IP: Rate limited (acceptable)")
-	} else {
-		t.Logf("Unexpected status code: %d", resp.StatusCode)
-	}
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	t.Log("✓ Valid API key from a different IP: Working correctly")
 	t.Log("✓ Authentication and rate limiting test passed")
 }
@@ -0,0 +1,175 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"nova/api"
+	"nova/api/services"
+	"nova/api/types"
+)
+
+func createRestrictedKey(t *testing.T, ts *TestSuite, request types.CreateAPIKeyRequest) string {
+	t.Helper()
+
+	resp, body := adminRequest(t, ts, "POST", "/admin/keys", request)
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode, string(body))
+
+	var created types.APIKeyResponse
+	require.NoError(t, json.Unmarshal(body, &created))
+
+	return created.Key
+}
+
+func TestAuth_ScopesAndRestrictions(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	tokensOnly := createRestrictedKey(t, ts, types.CreateAPIKeyRequest{
+		Name:   "tokens-only",
+		Scopes: []string{types.ScopeTokensRead},
+	})
+	assert.Equal(t, fiber.StatusForbidden, authStatus(t, ts, tokensOnly, "172.17.0.1"))
+	t.Log("✓ Missing balance:read scope is rejected")
+
+	devnetOnly := createRestrictedKey(t, ts, types.CreateAPIKeyRequest{
+		Name:     "devnet-only",
+		Networks: []string{types.NetworkDevnet},
+	})
+	assert.Equal(t, fiber.StatusForbidden, authStatus(t, ts, devnetOnly, "172.17.0.2"))
+	t.Log("✓ Network restriction is enforced")
+
+	ipRestricted := createRestrictedKey(t, ts, types.CreateAPIKeyRequest{
+		Name:       "ip-restricted",
+		AllowedIPs: []string{"10.20.0.0/16", "172.17.0.9"},
+	})
+	assert.Equal(t, fiber.StatusBadRequest, authStatus(t, ts, ipRestricted, "10.20.3.4"))
+	assert.Equal(t, fiber.StatusBadRequest, authStatus(t, ts, ipRestricted, "172.17.0.9"))
+	assert.Equal(t, fiber.StatusForbidden, authStatus(t, ts, ipRestricted, "172.17.0.10"))
+	t.Log("✓ IP and CIDR allowlist is enforced")
+
+	browserKey := createRestrictedKey(t, ts, types.CreateAPIKeyRequest{
+		Name:           "browser",
+		AllowedOrigins: []string{"https://app.example.com", "https://*.example.org"},
+	})
+
+	originStatus := func(origin, referer, ip string) int {
+		req, _ := http.NewRequest("POST", "/api/get-balance", bytes.NewReader([]byte(`{"wallets":[]}`)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", browserKey)
+		req.Header.Set("X-Forwarded-For", ip)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if referer != "" {
+			req.Header.Set("Referer", referer)
+		}
+
+		resp, err := ts.app.Test(req, 30000)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, fiber.StatusBadRequest, originStatus("https://app.example.com", "", "172.17.1.1"))
+	assert.Equal(t, fiber.StatusBadRequest, originStatus("", "https://wallet.example.org/page", "172.17.1.2"))
+	assert.Equal(t, fiber.StatusForbidden, originStatus("https://evil.example.net", "", "172.17.1.3"))
+	assert.Equal(t, fiber.StatusForbidden, originStatus("", "", "172.17.1.4"))
+	t.Log("✓ Origin allowlist is enforced")
+
+	resp, _ := adminRequest(t, ts, "POST", "/admin/keys", types.CreateAPIKeyRequest{AllowedIPs: []string{"not-an-ip"}})
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAuth_UntrustedProxyHeader(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	ipRestricted := createRestrictedKey(t, ts, types.CreateAPIKeyRequest{
+		Name:       "ip-restricted",
+		AllowedIPs: []string{"10.20.0.0/16"},
+	})
+	assert.Equal(t, fiber.StatusBadRequest, authStatus(t, ts, ipRestricted, "10.20.3.4"))
+
+	cfg := *ts.cfg
+	cfg.TrustedProxies = []string{"10.0.0.1"}
+	server, err := api.NewServer(
+		api.WithConfig(&cfg),
+		api.WithStores(ts.stores),
+		api.WithLogger(log.New(io.Discard, "", 0)),
+	)
+	require.NoError(t, err)
+
+	untrusted := *ts
+	untrusted.app = server.App()
+	assert.Equal(t, fiber.StatusForbidden, authStatus(t, &untrusted, ipRestricted, "10.20.3.4"))
+	t.Log("✓ The proxy header is ignored unless the request comes from a trusted proxy")
+}
+
+func TestAuth_ExpiredKey(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	keyID, rawKey, err := services.GenerateAPIKey()
+	require.NoError(t, err)
+
+	expired := time.Now().Add(-time.Minute)
+	keyDoc := types.APIKey{
+		ID:        bson.NewObjectID(),
+		KeyID:     keyID,
+		KeyHash:   services.HashAPIKey(rawKey),
+		Active:    true,
+		CreatedAt: time.Now().Add(-time.Hour),
+		ExpiresAt: &expired,
+	}
+
+	require.NoError(t, ts.stores.Keys.Insert(ctx, &keyDoc))
+
+	assert.Equal(t, fiber.StatusUnauthorized, authStatus(t, ts, rawKey, "172.17.2.1"))
+	t.Log("✓ Expired key is rejected")
+}
+
+func TestAuth_RevocationPropagatesAcrossInstances(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	replicaA, err := services.NewKeyService(ts.cfg, ts.stores)
+	require.NoError(t, err)
+	replicaB, err := services.NewKeyService(ts.cfg, ts.stores)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go replicaB.ListenForRevocations(ctx)
+
+	keyDoc, rawKey, err := replicaA.Create(ctx, types.CreateAPIKeyRequest{Name: "replicated"})
+	require.NoError(t, err)
+
+	_, err = replicaB.Authenticate(ctx, rawKey)
+	require.NoError(t, err, "Replica B should cache the principal locally")
+
+	time.Sleep(200 * time.Millisecond)
+
+	_, err = replicaA.Revoke(ctx, keyDoc.ID.Hex())
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		_, err := replicaB.Authenticate(ctx, rawKey)
+		return err != nil
+	}, 2*time.Second, 20*time.Millisecond, "Revocation should reach replica B immediately")
+
+	t.Log("✓ Revocation evicted the cached principal on another instance")
+}
@@ -0,0 +1,203 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nova/api/services"
+	"nova/api/types"
+	"nova/client"
+)
+
+func serveHTTP(t *testing.T, handler http.Handler) string {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestClient_GetBalances(t *testing.T) {
+	ts := newTestSuite(t, types.CreateAPIKeyRequest{Name: "client", Tier: types.TierEnterprise})
+	defer ts.cleanup(t)
+
+	c := client.New(serveHTTP(t, ts.server.Handler()), client.WithAPIKey(ts.testAPIKey))
+	ctx := context.Background()
+
+	balance, err := c.GetBalance(ctx, testWallets[0])
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, balance)
+	t.Log("✓ Single balance lookup")
+
+	wallets := make([]string, 0, 250)
+	for i := 0; i < 247; i++ {
+		wallets = append(wallets, solana.NewWallet().PublicKey().String())
+	}
+	wallets = append(wallets, testWallets...)
+
+	balances, err := c.GetBalances(ctx, wallets)
+	require.NoError(t, err)
+	require.Len(t, balances, len(wallets))
+
+	for i, result := range balances {
+		assert.Equal(t, wallets[i], result.Address)
+	}
+	assert.Equal(t, 0.042, balances[248].Balance)
+	t.Log("✓ 250 wallets are split into chunks and returned in order")
+
+	usage, err := c.Usage(ctx, time.Now().Add(-time.Hour), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	keyID, _ := services.ParseAPIKey(ts.testAPIKey)
+	assert.Equal(t, keyID, usage.KeyID)
+}
+
+func TestClient_SignedRequests(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	keyDoc, rawKey, err := ts.server.Keys().Create(context.Background(), types.CreateAPIKeyRequest{
+		Name:             "client-signed",
+		RequireSignature: true,
+	})
+	require.NoError(t, err)
+
+	url := serveHTTP(t, ts.server.Handler())
+
+	_, err = client.New(url, client.WithAPIKey(rawKey)).GetBalance(context.Background(), testWallets[0])
+	var apiErr *client.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+
+	signed := client.New(url, client.WithSigning(keyDoc.KeyID, ts.server.Keys().RevealSigningSecret(keyDoc)))
+	for i := 0; i < 2; i++ {
+		balance, err := signed.GetBalance(context.Background(), testWallets[0])
+		require.NoError(t, err)
+		assert.Equal(t, 1.5, balance)
+	}
+	t.Log("✓ HMAC signed requests are accepted with a fresh nonce each time")
+}
+
+func TestClient_RetriesRateLimits(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	var attempts atomic.Int32
+	limited := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) <= 2 {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"success":false,"message":"Ratelimit exceeded"}`))
+			return
+		}
+		ts.server.Handler().ServeHTTP(w, r)
+	})
+
+	c := client.New(serveHTTP(t, limited), client.WithAPIKey(ts.testAPIKey), client.WithBackoff(10*time.Millisecond, time.Second))
+
+	balance, err := c.GetBalance(context.Background(), testWallets[0])
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, balance)
+	assert.Equal(t, int32(3), attempts.Load())
+	t.Log("✓ 429 responses are retried")
+
+	direct := client.New(serveHTTP(t, ts.server.Handler()), client.WithAPIKey(ts.testAPIKey), client.WithRetries(0))
+	for {
+		_, err = direct.GetBalance(context.Background(), testWallets[0])
+		if err != nil {
+			break
+		}
+	}
+
+	var apiErr *client.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+	assert.Greater(t, apiErr.RetryAfter, time.Duration(0), "The server should say when to retry")
+	t.Logf("✓ Server asked to retry after %v", apiErr.RetryAfter)
+
+	patient := client.New(serveHTTP(t, ts.server.Handler()), client.WithAPIKey(ts.testAPIKey), client.WithBackoff(10*time.Millisecond, 100*time.Millisecond))
+	start := time.Now()
+	_, err = patient.GetBalance(context.Background(), testWallets[0])
+	require.ErrorAs(t, err, &apiErr)
+	assert.Less(t, time.Since(start), time.Second, "Waits longer than the backoff cap are not attempted")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	slow := client.New(serveHTTP(t, ts.server.Handler()), client.WithAPIKey(ts.testAPIKey), client.WithBackoff(time.Second, time.Minute))
+	_, err = slow.GetBalance(ctx, testWallets[0])
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "Waiting honours the context")
+}
+
+func TestClient_QuotaIsNotRetried(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	_, rawKey, err := ts.server.Keys().Create(context.Background(), types.CreateAPIKeyRequest{
+		Name:       "client-quota",
+		DailyQuota: 1,
+	})
+	require.NoError(t, err)
+
+	c := client.New(serveHTTP(t, ts.server.Handler()), client.WithAPIKey(rawKey), client.WithBackoff(time.Second, time.Minute))
+
+	_, err = c.GetBalance(context.Background(), testWallets[0])
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = c.GetBalance(context.Background(), testWallets[0])
+
+	var apiErr *client.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "quota_exceeded", apiErr.Code)
+	assert.Less(t, time.Since(start), time.Second)
+	t.Log("✓ Exhausted quota fails fast")
+}
+
+func TestClient_ChunkPacing(t *testing.T) {
+	var times []time.Time
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		times = append(times, time.Now())
+
+		var request types.BalanceRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		response := types.BalanceResponse{Success: true}
+		for _, wallet := range request.Wallets {
+			response.Data = append(response.Data, types.WalletBalance{Address: wallet, Balance: 1})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-RateLimit-Limit", "600")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		json.NewEncoder(w).Encode(response)
+	})
+
+	url := serveHTTP(t, handler)
+	c := client.New(url, client.WithChunkSize(1))
+
+	balances, err := c.GetBalances(context.Background(), testWallets[:3])
+	require.NoError(t, err)
+	require.Len(t, balances, 3)
+	require.Len(t, times, 3)
+	for i := 1; i < len(times); i++ {
+		assert.GreaterOrEqual(t, times[i].Sub(times[i-1]), 100*time.Millisecond)
+	}
+	t.Log("✓ Chunks wait for the rate limit when none is left")
+
+	for _, size := range []int{0, -1} {
+		_, err := client.New(url, client.WithChunkSize(size)).GetBalances(context.Background(), testWallets)
+		assert.ErrorIs(t, err, client.ErrInvalidChunkSize)
+	}
+	t.Log("✓ Chunk sizes below 1 are rejected")
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nova/api/config"
+	"nova/api/types"
+)
+
+func TestConfig_ParseAndValidate(t *testing.T) {
+	t.Setenv("SOLANA_RPC_URL", "http://127.0.0.1:8899")
+	t.Setenv("MONGO_DATABASE", "nova_test")
+	t.Setenv("SIGNING_ENCRYPTION_KEY", testSigningEncryptionKey)
+	t.Setenv("PROXY_HEADER", "X-Forwarded-For")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 127.0.0.1,")
+
+	cfg, err := config.Parse()
+	require.NoError(t, err)
+
+	assert.Equal(t, "nova_test", cfg.MongoDatabase)
+	assert.Equal(t, "http://127.0.0.1:8899", cfg.SolaanRPCURL)
+	assert.Equal(t, "ws://127.0.0.1:8899", cfg.SolanaWSURL)
+	assert.Equal(t, "X-Forwarded-For", cfg.ProxyHeader)
+	assert.Equal(t, []string{"10.0.0.0/8", "127.0.0.1"}, cfg.TrustedProxies)
+	assert.NoError(t, config.Validate(cfg))
+	t.Log("✓ Explicit RPC URL does not require a Helius key")
+
+	cfg.Port = "http"
+	cfg.Network = "localnet"
+	cfg.MongoDatabase = "bad.name"
+	cfg.SigningEncryptionKey = "abc"
+	cfg.RequestTimeout = 0
+	cfg.TrustedProxies = append(cfg.TrustedProxies, "proxy.internal")
+	cfg.RateLimitTiers[types.TierFree] = types.RateLimitTier{}
+
+	err = config.Validate(cfg)
+	require.Error(t, err)
+
+	for _, expected := range []string{"PORT", "SOLANA_NETWORK", "MONGO_DATABASE", "SIGNING_ENCRYPTION_KEY", "REQUEST_TIMEOUT", "TRUSTED_PROXIES", "rate limit tier"} {
+		assert.Contains(t, err.Error(), expected)
+	}
+	t.Log("✓ Every invalid setting is reported at once")
+}
+
+func TestConfig_ProxyHeaderNeedsTrustedProxies(t *testing.T) {
+	cfg := testConfig("http://127.0.0.1:8899")
+	cfg.Port = "3000"
+	cfg.GRPCPort = "9090"
+	cfg.MongoURI = "mongodb://localhost:27017"
+	cfg.RedisURI = "localhost:6379"
+	cfg.SolanaWSURL = "ws://127.0.0.1:8899"
+	require.NoError(t, config.Validate(cfg))
+
+	cfg.TrustedProxies = nil
+	err := config.Validate(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "TRUSTED_PROXIES is required")
+
+	cfg.ProxyHeader = ""
+	cfg.TrustedProxies = []string{"127.0.0.1"}
+	err = config.Validate(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "PROXY_HEADER is required")
+	t.Log("✓ The proxy header and trusted proxies are set together")
+}
+
+func TestConfig_Port(t *testing.T) {
+	t.Setenv("SOLANA_RPC_URL", "http://127.0.0.1:8899")
+	t.Setenv("PORT", "")
+	t.Setenv("API_PORT", "")
+	os.Unsetenv("API_PORT")
+
+	cfg, err := config.Parse()
+	require.NoError(t, err)
+	assert.Equal(t, "8080", cfg.Port)
+	t.Log("✓ The port defaults to 8080")
+
+	t.Setenv("API_PORT", "8081")
+	cfg, err = config.Parse()
+	require.NoError(t, err)
+	assert.Equal(t, "8081", cfg.Port)
+	t.Log("✓ API_PORT is still read when PORT is unset")
+
+	t.Setenv("PORT", "9000")
+	cfg, err = config.Parse()
+	require.NoError(t, err)
+	assert.Equal(t, "9000", cfg.Port)
+	t.Log("✓ PORT takes precedence over API_PORT")
+}
+
+func TestConfig_RequiresRPCEndpoint(t *testing.T) {
+	t.Setenv("SOLANA_RPC_URL", "")
+	t.Setenv("HELIUS_API_KEY", "")
+
+	_, err := config.Parse()
+	assert.Error(t, err)
+	t.Log("✓ Missing RPC endpoint is rejected")
+}
@@ -0,0 +1,145 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nova/api/types"
+	"nova/test/fakerpc"
+)
+
+func v1Request(t *testing.T, ts *TestSuite, method, path, apiKey string, body []byte, clientIP string) (*http.Response, []byte) {
+	t.Helper()
+
+	req, _ := http.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	req.Header.Set("X-Forwarded-For", clientIP)
+
+	resp, err := ts.app.Test(req, 30000)
+	require.NoError(t, err)
+
+	data, _ := io.ReadAll(resp.Body)
+	return resp, data
+}
+
+func v1Error(t *testing.T, resp *http.Response, data []byte, status int, code string) types.APIError {
+	t.Helper()
+
+	require.Equal(t, status, resp.StatusCode, string(data))
+
+	var apiErr types.APIError
+	require.NoError(t, json.Unmarshal(data, &apiErr))
+	assert.Equal(t, code, apiErr.Code)
+	assert.NotEmpty(t, apiErr.Message)
+	assert.NotEmpty(t, apiErr.RequestID)
+	assert.Equal(t, resp.Header.Get(fiber.HeaderXRequestID), apiErr.RequestID)
+	assert.NotContains(t, string(data), `"success"`)
+	return apiErr
+}
+
+func TestErrors_V1Shape(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	resp, data := v1Request(t, ts, "POST", "/api/v1/balances", "", nil, "172.26.0.1")
+	v1Error(t, resp, data, fiber.StatusUnauthorized, types.CodeUnauthorized)
+
+	resp, data = v1Request(t, ts, "POST", "/api/v1/balances", ts.testAPIKey, []byte("{"), "172.26.0.2")
+	v1Error(t, resp, data, fiber.StatusBadRequest, types.CodeInvalidRequest)
+	t.Log("✓ Errors carry a code and the request ID")
+
+	wallets := make([]string, 101)
+	for i := range wallets {
+		wallets[i] = testWallets[0]
+	}
+	reqBody, _ := json.Marshal(types.BalanceRequest{Wallets: wallets})
+	resp, data = v1Request(t, ts, "POST", "/api/v1/balances", ts.testAPIKey, reqBody, "172.26.0.3")
+	apiErr := v1Error(t, resp, data, fiber.StatusBadRequest, types.CodeTooManyWallets)
+	assert.Equal(t, float64(100), apiErr.Details["max"])
+	assert.Equal(t, types.TierFree, apiErr.Details["tier"])
+	t.Log("✓ Details explain the error")
+
+	resp, data = v1Request(t, ts, "GET", "/api/v1/accounts/not-a-wallet/balance", ts.testAPIKey, nil, "172.26.0.4")
+	v1Error(t, resp, data, fiber.StatusBadRequest, types.CodeInvalidAddress)
+
+	resp, data = v1Request(t, ts, "GET", "/api/v1/nothing-here", ts.testAPIKey, nil, "172.26.0.5")
+	v1Error(t, resp, data, fiber.StatusNotFound, types.CodeNotFound)
+	t.Log("✓ Unknown routes are structured errors too")
+}
+
+func TestErrors_V1WalletErrors(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	ts.rpc.FailAccount(testWallets[1], &fakerpc.Error{Code: -32005, Message: "Node is behind"})
+
+	reqBody, _ := json.Marshal(types.BalanceRequest{Wallets: []string{testWallets[0], testWallets[1], "not-a-wallet"}})
+	resp, data := v1Request(t, ts, "POST", "/api/v1/balances", ts.testAPIKey, reqBody, "172.26.1.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode, string(data))
+	assert.NotContains(t, string(data), "Node is behind", "Upstream errors must not leak")
+
+	var response types.BalanceResultsResponse
+	require.NoError(t, json.Unmarshal(data, &response))
+	require.Len(t, response.Data, 3)
+
+	assert.Nil(t, response.Data[0].Error)
+	assert.Equal(t, 1.5, response.Data[0].Balance)
+	require.NotNil(t, response.Data[1].Error)
+	assert.Equal(t, types.CodeUpstreamError, response.Data[1].Error.Code)
+	assert.NotEmpty(t, response.Data[1].Error.Message)
+	require.NotNil(t, response.Data[2].Error)
+	assert.Equal(t, types.CodeInvalidAddress, response.Data[2].Error.Code)
+	t.Log("✓ Per-wallet errors are coded and sanitized")
+}
+
+func TestErrors_LegacyShape(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	reqBody, _ := json.Marshal(types.BalanceRequest{})
+	resp, data := v1Request(t, ts, "POST", "/api/get-balance", ts.testAPIKey, reqBody, "172.26.2.1")
+	require.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	var errorResponse types.ErrorResponse
+	require.NoError(t, json.Unmarshal(data, &errorResponse))
+	assert.False(t, errorResponse.Success)
+	assert.Equal(t, "No wallets provided", errorResponse.Message)
+	assert.Equal(t, types.CodeInvalidRequest, errorResponse.Code)
+	assert.NotContains(t, string(data), "request_id")
+	t.Log("✓ Legacy routes keep their body, with a code added")
+}
+
+func TestErrors_LimitCodes(t *testing.T) {
+	ts := newTestSuite(t, types.CreateAPIKeyRequest{Name: "errors-quota", DailyQuota: 1})
+	defer ts.cleanup(t)
+
+	reqBody, _ := json.Marshal(types.BalanceRequest{Wallets: testWallets[:1]})
+	resp, data := v1Request(t, ts, "POST", "/api/v1/balances", ts.testAPIKey, reqBody, "172.26.3.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode, string(data))
+
+	resp, data = v1Request(t, ts, "POST", "/api/v1/balances", ts.testAPIKey, reqBody, "172.26.3.1")
+	apiErr := v1Error(t, resp, data, fiber.StatusTooManyRequests, types.CodeQuotaExceeded)
+	assert.Equal(t, "daily", apiErr.Details["period"])
+	assert.Equal(t, float64(1), apiErr.Details["limit"])
+	t.Log("✓ quota_exceeded names the period and limit")
+
+	var last *http.Response
+	for i := 0; i < 12; i++ {
+		last, data = v1Request(t, ts, "GET", "/api/v1/usage", "invalid-key", nil, "172.26.3.2")
+	}
+	apiErr = v1Error(t, last, data, fiber.StatusTooManyRequests, types.CodeRateLimited)
+	assert.True(t, strings.HasPrefix(apiErr.Message, "Ratelimit exceeded"))
+	assert.Equal(t, float64(10), apiErr.Details["limit"])
+	t.Log("✓ rate_limited names the limit")
+}
@@ -0,0 +1,592 @@
+// Package fakerpc is an in-process Solana JSON-RPC and pubsub server for
+// tests. Accounts, latency and failures are scripted by the test instead of
+// coming from a live cluster.
+package fakerpc
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fasthttp/websocket"
+)
+
+const (
+	CodeInvalidParams  = -32602
+	CodeMethodNotFound = -32601
+	CodeInternalError  = -32603
+)
+
+type Server struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	accounts  map[string]uint64
+	tokens    map[string][]TokenAccount
+	history   map[string][]Signature
+	stakes    []StakeAccount
+	failing   map[string]*Error
+	latency   time.Duration
+	delays    map[string]time.Duration
+	failNext  int
+	limitNext int
+	calls     map[string]int
+	slot      uint64
+
+	upgrader       websocket.Upgrader
+	conns          map[*pubsubConn]struct{}
+	subscriptionID uint64
+}
+
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// TokenAccount is an SPL token account held by an owner. Program defaults
+// to the Token program.
+type TokenAccount struct {
+	Address  string
+	Mint     string
+	Amount   uint64
+	Decimals uint8
+	Program  string
+}
+
+const (
+	TokenProgram     = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+	Token2022Program = "TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb"
+	StakeProgram     = "Stake11111111111111111111111111111111111111"
+	SystemProgram    = "11111111111111111111111111111111"
+)
+
+// Signature is a transaction in an address's history.
+type Signature struct {
+	Signature string
+	Slot      uint64
+	BlockTime int64
+	Failed    bool
+	Memo      string
+}
+
+// StakeAccount is a stake account. It is delegated when Voter is set.
+type StakeAccount struct {
+	Address         string
+	Staker          string
+	Withdrawer      string
+	Lamports        uint64
+	Voter           string
+	Stake           uint64
+	ActivationEpoch uint64
+}
+
+type request struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+type contextResult struct {
+	Context struct {
+		Slot uint64 `json:"slot"`
+	} `json:"context"`
+	Value interface{} `json:"value"`
+}
+
+// New starts a server. Accounts that were never set report a zero balance,
+// like an unfunded address on a real cluster.
+func New() *Server {
+	s := &Server{
+		accounts: make(map[string]uint64),
+		tokens:   make(map[string][]TokenAccount),
+		history:  make(map[string][]Signature),
+		failing:  make(map[string]*Error),
+		delays:   make(map[string]time.Duration),
+		calls:    make(map[string]int),
+		slot:     1,
+		conns:    make(map[*pubsubConn]struct{}),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+func (s *Server) Close() {
+	s.DropConnections()
+	s.server.Close()
+}
+
+// SetBalance also notifies the account's pubsub subscribers.
+func (s *Server) SetBalance(address string, lamports uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accounts[address] = lamports
+	s.notifyLocked(address)
+}
+
+// SetTokenAccount adds a token account to owner.
+func (s *Server) SetTokenAccount(owner string, account TokenAccount) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if account.Program == "" {
+		account.Program = TokenProgram
+	}
+	s.tokens[owner] = append(s.tokens[owner], account)
+}
+
+// AddSignature appends a transaction to address's history, which is
+// returned newest first, so add the oldest first.
+func (s *Server) AddSignature(address string, signature Signature) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history[address] = append(s.history[address], signature)
+}
+
+func (s *Server) SetStakeAccount(account StakeAccount) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stakes = append(s.stakes, account)
+}
+
+// SetLatency delays every response by d.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// DelayAccount delays responses for address by d on top of the latency.
+func (s *Server) DelayAccount(address string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delays[address] = d
+}
+
+// FailAccount makes every call for address return a JSON-RPC error until
+// cleared with a nil error.
+func (s *Server) FailAccount(address string, err *Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err == nil {
+		delete(s.failing, address)
+		return
+	}
+	s.failing[address] = err
+}
+
+// FailNext makes the next n calls return an internal error.
+func (s *Server) FailNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failNext = n
+}
+
+// RateLimitNext answers the next n HTTP requests with 429 Too Many Requests.
+func (s *Server) RateLimitNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limitNext = n
+}
+
+// Calls returns how many times method was called, including failed calls.
+func (s *Server) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		s.servePubSub(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	latency := s.latency
+	limited := s.limitNext > 0
+	if limited {
+		s.limitNext--
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if limited {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+		return
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if len(raw) > 0 && raw[0] == '[' {
+		var batch []request
+		if err := json.Unmarshal(raw, &batch); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		responses := make([]response, len(batch))
+		for i := range batch {
+			responses[i] = s.call(batch[i])
+		}
+		json.NewEncoder(w).Encode(responses)
+		return
+	}
+
+	var req request
+	if err := json.Unmarshal(raw, &req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if delay := s.delayFor(req); delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	json.NewEncoder(w).Encode(s.call(req))
+}
+
+func (s *Server) delayFor(req request) time.Duration {
+	var address string
+	if len(req.Params) == 0 || json.Unmarshal(req.Params[0], &address) != nil {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.delays[address]
+}
+
+func (s *Server) call(req request) response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls[req.Method]++
+	resp := response{JSONRPC: "2.0", ID: req.ID}
+
+	if s.failNext > 0 {
+		s.failNext--
+		resp.Error = &Error{Code: CodeInternalError, Message: "Internal error"}
+		return resp
+	}
+
+	switch req.Method {
+	case "getBalance":
+		var address string
+		if len(req.Params) == 0 || json.Unmarshal(req.Params[0], &address) != nil {
+			resp.Error = &Error{Code: CodeInvalidParams, Message: "Invalid params"}
+			return resp
+		}
+
+		if err, ok := s.failing[address]; ok {
+			resp.Error = err
+			return resp
+		}
+
+		resp.Result = s.withContext(s.accounts[address])
+	case "getTokenAccountsByOwner":
+		var owner string
+		var filter struct {
+			Mint      string `json:"mint"`
+			ProgramID string `json:"programId"`
+		}
+		if len(req.Params) < 2 || json.Unmarshal(req.Params[0], &owner) != nil || json.Unmarshal(req.Params[1], &filter) != nil {
+			resp.Error = &Error{Code: CodeInvalidParams, Message: "Invalid params"}
+			return resp
+		}
+
+		if err, ok := s.failing[owner]; ok {
+			resp.Error = err
+			return resp
+		}
+
+		accounts := []interface{}{}
+		for _, account := range s.tokens[owner] {
+			if (filter.Mint != "" && account.Mint != filter.Mint) || (filter.ProgramID != "" && account.Program != filter.ProgramID) {
+				continue
+			}
+			accounts = append(accounts, tokenAccountJSON(owner, account))
+		}
+		resp.Result = s.withContext(accounts)
+	case "getMultipleAccounts":
+		var addresses []string
+		if len(req.Params) == 0 || json.Unmarshal(req.Params[0], &addresses) != nil {
+			resp.Error = &Error{Code: CodeInvalidParams, Message: "Invalid params"}
+			return resp
+		}
+
+		accounts := make([]interface{}, len(addresses))
+		for i, address := range addresses {
+			if err, ok := s.failing[address]; ok {
+				resp.Error = err
+				return resp
+			}
+			accounts[i] = s.accountJSON(address)
+		}
+		resp.Result = s.withContext(accounts)
+	case "getAccountInfo":
+		var address string
+		if len(req.Params) == 0 || json.Unmarshal(req.Params[0], &address) != nil {
+			resp.Error = &Error{Code: CodeInvalidParams, Message: "Invalid params"}
+			return resp
+		}
+
+		if err, ok := s.failing[address]; ok {
+			resp.Error = err
+			return resp
+		}
+		resp.Result = s.withContext(s.accountJSON(address))
+	case "getSignaturesForAddress":
+		var address string
+		var opts struct {
+			Limit  int    `json:"limit"`
+			Before string `json:"before"`
+		}
+		if len(req.Params) == 0 || json.Unmarshal(req.Params[0], &address) != nil {
+			resp.Error = &Error{Code: CodeInvalidParams, Message: "Invalid params"}
+			return resp
+		}
+		if len(req.Params) > 1 {
+			json.Unmarshal(req.Params[1], &opts)
+		}
+
+		if err, ok := s.failing[address]; ok {
+			resp.Error = err
+			return resp
+		}
+
+		history := s.history[address]
+		signatures := []interface{}{}
+		before := opts.Before == ""
+		for i := len(history) - 1; i >= 0; i-- {
+			if !before {
+				before = history[i].Signature == opts.Before
+				continue
+			}
+			if opts.Limit > 0 && len(signatures) == opts.Limit {
+				break
+			}
+			signatures = append(signatures, signatureJSON(history[i]))
+		}
+		resp.Result = signatures
+	case "getProgramAccounts":
+		var program string
+		var opts struct {
+			Filters []programFilter `json:"filters"`
+		}
+		if len(req.Params) < 2 || json.Unmarshal(req.Params[0], &program) != nil || json.Unmarshal(req.Params[1], &opts) != nil {
+			resp.Error = &Error{Code: CodeInvalidParams, Message: "Invalid params"}
+			return resp
+		}
+
+		accounts := []interface{}{}
+		if program != StakeProgram {
+			resp.Result = accounts
+			return resp
+		}
+
+		for _, stake := range s.stakes {
+			if err, ok := s.failing[stake.Withdrawer]; ok {
+				resp.Error = err
+				return resp
+			}
+			if matchesStakeFilters(stake, opts.Filters) {
+				accounts = append(accounts, stakeAccountJSON(stake))
+			}
+		}
+		resp.Result = accounts
+	case "getSlot":
+		resp.Result = s.slot
+	case "getHealth":
+		resp.Result = "ok"
+	default:
+		resp.Error = &Error{Code: CodeMethodNotFound, Message: "Method not found"}
+	}
+
+	return resp
+}
+
+func (s *Server) withContext(value interface{}) contextResult {
+	s.slot++
+
+	result := contextResult{Value: value}
+	result.Context.Slot = s.slot
+	return result
+}
+
+// accountJSON is the state of a system account. Unlike getBalance, accounts
+// that were never set do not exist. s.mu must be held.
+func (s *Server) accountJSON(address string) interface{} {
+	lamports, ok := s.accounts[address]
+	if !ok {
+		return nil
+	}
+	return map[string]interface{}{
+		"lamports":   lamports,
+		"owner":      SystemProgram,
+		"data":       []string{"", "base64"},
+		"executable": false,
+		"rentEpoch":  0,
+		"space":      0,
+	}
+}
+
+func tokenAccountJSON(owner string, account TokenAccount) map[string]interface{} {
+	return map[string]interface{}{
+		"pubkey": account.Address,
+		"account": map[string]interface{}{
+			"lamports":   2039280,
+			"owner":      account.Program,
+			"executable": false,
+			"rentEpoch":  0,
+			"space":      165,
+			"data": map[string]interface{}{
+				"program": "spl-token",
+				"space":   165,
+				"parsed": map[string]interface{}{
+					"type": "account",
+					"info": map[string]interface{}{
+						"mint":  account.Mint,
+						"owner": owner,
+						"state": "initialized",
+						"tokenAmount": map[string]interface{}{
+							"amount":         strconv.FormatUint(account.Amount, 10),
+							"decimals":       account.Decimals,
+							"uiAmountString": uiAmountString(account.Amount, account.Decimals),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func uiAmountString(amount uint64, decimals uint8) string {
+	value, _ := new(big.Float).Quo(
+		new(big.Float).SetUint64(amount),
+		new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)),
+	).Float64()
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func signatureJSON(signature Signature) map[string]interface{} {
+	result := map[string]interface{}{
+		"signature":          signature.Signature,
+		"slot":               signature.Slot,
+		"err":                nil,
+		"memo":               nil,
+		"blockTime":          signature.BlockTime,
+		"confirmationStatus": "finalized",
+	}
+	if signature.Failed {
+		result["err"] = map[string]interface{}{"InstructionError": []interface{}{0, "Custom"}}
+	}
+	if signature.Memo != "" {
+		result["memo"] = signature.Memo
+	}
+	return result
+}
+
+type programFilter struct {
+	Memcmp *struct {
+		Offset int    `json:"offset"`
+		Bytes  string `json:"bytes"`
+	} `json:"memcmp"`
+}
+
+// matchesStakeFilters supports memcmp filters on the staker (offset 12) and
+// withdrawer (offset 44) of a stake account.
+func matchesStakeFilters(stake StakeAccount, filters []programFilter) bool {
+	for _, filter := range filters {
+		if filter.Memcmp == nil {
+			continue
+		}
+		switch filter.Memcmp.Offset {
+		case 12:
+			if stake.Staker != filter.Memcmp.Bytes {
+				return false
+			}
+		case 44:
+			if stake.Withdrawer != filter.Memcmp.Bytes {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func stakeAccountJSON(stake StakeAccount) map[string]interface{} {
+	info := map[string]interface{}{
+		"meta": map[string]interface{}{
+			"authorized": map[string]interface{}{
+				"staker":     stake.Staker,
+				"withdrawer": stake.Withdrawer,
+			},
+			"rentExemptReserve": "2282880",
+		},
+	}
+	kind := "initialized"
+	if stake.Voter != "" {
+		kind = "delegated"
+		info["stake"] = map[string]interface{}{
+			"delegation": map[string]interface{}{
+				"voter":             stake.Voter,
+				"stake":             strconv.FormatUint(stake.Stake, 10),
+				"activationEpoch":   strconv.FormatUint(stake.ActivationEpoch, 10),
+				"deactivationEpoch": "18446744073709551615",
+			},
+			"creditsObserved": 0,
+		}
+	}
+
+	return map[string]interface{}{
+		"pubkey": stake.Address,
+		"account": map[string]interface{}{
+			"lamports":   stake.Lamports,
+			"owner":      StakeProgram,
+			"executable": false,
+			"rentEpoch":  0,
+			"space":      200,
+			"data": map[string]interface{}{
+				"program": "stake",
+				"space":   200,
+				"parsed":  map[string]interface{}{"type": kind, "info": info},
+			},
+		},
+	}
+}
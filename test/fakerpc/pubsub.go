@@ -0,0 +1,158 @@
+package fakerpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/fasthttp/websocket"
+)
+
+// pubsubConn is one websocket client. Its subscriptions are guarded by the
+// server's mu.
+type pubsubConn struct {
+	ws            *websocket.Conn
+	writeMu       sync.Mutex
+	subscriptions map[uint64]string
+}
+
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  struct {
+		Result       contextResult `json:"result"`
+		Subscription uint64        `json:"subscription"`
+	} `json:"params"`
+}
+
+// WSURL is the pubsub endpoint, served on the same address as JSON-RPC.
+// It answers accountSubscribe and accountUnsubscribe, and pushes an
+// accountNotification whenever SetBalance changes a subscribed account.
+func (s *Server) WSURL() string {
+	return "ws" + strings.TrimPrefix(s.server.URL, "http")
+}
+
+// Subscriptions returns how many accountSubscribe subscriptions to address
+// are open across all connections.
+func (s *Server) Subscriptions(address string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for conn := range s.conns {
+		for _, subscribed := range conn.subscriptions {
+			if subscribed == address {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// Connections returns how many pubsub connections are open.
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// DropConnections closes every pubsub connection without a close message,
+// like a node restart.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn := range s.conns {
+		conn.ws.NetConn().Close()
+		delete(s.conns, conn)
+	}
+}
+
+func (s *Server) servePubSub(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	conn := &pubsubConn{ws: ws, subscriptions: make(map[uint64]string)}
+
+	s.mu.Lock()
+	s.conns[conn] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		ws.Close()
+	}()
+
+	for {
+		var req request
+		if err := ws.ReadJSON(&req); err != nil {
+			return
+		}
+		s.pubsubCall(conn, req)
+	}
+}
+
+// pubsubCall answers req while holding s.mu, so that no notification for a
+// new subscription can overtake its reply.
+func (s *Server) pubsubCall(conn *pubsubConn, req request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls[req.Method]++
+	resp := response{JSONRPC: "2.0", ID: req.ID}
+
+	switch req.Method {
+	case "accountSubscribe":
+		var address string
+		if len(req.Params) == 0 || json.Unmarshal(req.Params[0], &address) != nil {
+			resp.Error = &Error{Code: CodeInvalidParams, Message: "Invalid params"}
+			break
+		}
+
+		s.subscriptionID++
+		conn.subscriptions[s.subscriptionID] = address
+		resp.Result = s.subscriptionID
+	case "accountUnsubscribe":
+		var id uint64
+		if len(req.Params) == 0 || json.Unmarshal(req.Params[0], &id) != nil {
+			resp.Error = &Error{Code: CodeInvalidParams, Message: "Invalid params"}
+			break
+		}
+
+		_, ok := conn.subscriptions[id]
+		delete(conn.subscriptions, id)
+		resp.Result = ok
+	default:
+		resp.Error = &Error{Code: CodeMethodNotFound, Message: "Method not found"}
+	}
+
+	conn.write(resp)
+}
+
+// notifyLocked pushes the state of address to its subscribers. s.mu must
+// be held.
+func (s *Server) notifyLocked(address string) {
+	var message notification
+	message.JSONRPC = "2.0"
+	message.Method = "accountNotification"
+	message.Params.Result = s.withContext(s.accountJSON(address))
+
+	for conn := range s.conns {
+		for id, subscribed := range conn.subscriptions {
+			if subscribed == address {
+				message.Params.Subscription = id
+				conn.write(message)
+			}
+		}
+	}
+}
+
+func (c *pubsubConn) write(message interface{}) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.ws.WriteJSON(message)
+}
@@ -0,0 +1,342 @@
+package test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nova/api"
+	"nova/api/types"
+	"nova/test/fakerpc"
+)
+
+type graphQLResult struct {
+	Data       json.RawMessage      `json:"data"`
+	Errors     []types.GraphQLError `json:"errors"`
+	Extensions types.GraphQLCost    `json:"extensions"`
+}
+
+func graphQLRequest(t *testing.T, ts *TestSuite, apiKey string, request types.GraphQLRequest, clientIP string) (*http.Response, graphQLResult) {
+	t.Helper()
+
+	body, _ := json.Marshal(request)
+	req, _ := http.NewRequest("POST", "/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return sendGraphQL(t, ts, req, apiKey, clientIP)
+}
+
+func sendGraphQL(t *testing.T, ts *TestSuite, req *http.Request, apiKey, clientIP string) (*http.Response, graphQLResult) {
+	t.Helper()
+
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	req.Header.Set("X-Forwarded-For", clientIP)
+
+	resp, err := ts.app.Test(req, 30000)
+	require.NoError(t, err)
+
+	data, _ := io.ReadAll(resp.Body)
+	var result graphQLResult
+	require.NoError(t, json.Unmarshal(data, &result), string(data))
+	return resp, result
+}
+
+func testSignature(t *testing.T) string {
+	t.Helper()
+
+	signature, err := solana.NewWallet().PrivateKey.Sign([]byte("nova"))
+	require.NoError(t, err)
+	return signature.String()
+}
+
+// graphQLCode returns the code of the only error of result.
+func graphQLCode(t *testing.T, result graphQLResult) string {
+	t.Helper()
+
+	require.Len(t, result.Errors, 1)
+	require.NotNil(t, result.Errors[0].Extensions)
+	return result.Errors[0].Extensions.Code
+}
+
+func TestGraphQL_Account(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	apiKey := createRestrictedKey(t, ts, types.CreateAPIKeyRequest{
+		Name:   "dashboard",
+		Scopes: []string{types.ScopeBalanceRead, types.ScopeTokensRead},
+	})
+
+	wallet := testWallets[0]
+	usdc := "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+	ts.rpc.SetTokenAccount(wallet, fakerpc.TokenAccount{
+		Address: solana.NewWallet().PublicKey().String(), Mint: usdc, Amount: 12_500_000, Decimals: 6,
+	})
+	first, second := testSignature(t), testSignature(t)
+	ts.rpc.AddSignature(wallet, fakerpc.Signature{Signature: first, Slot: 100, BlockTime: 1_700_000_000})
+	ts.rpc.AddSignature(wallet, fakerpc.Signature{Signature: second, Slot: 200, Failed: true, Memo: "hello"})
+
+	voter := solana.NewWallet().PublicKey().String()
+	stake := solana.NewWallet().PublicKey().String()
+	ts.rpc.SetStakeAccount(fakerpc.StakeAccount{
+		Address: stake, Staker: wallet, Withdrawer: wallet,
+		Lamports: 2_000_000_000, Voter: voter, Stake: 1_997_717_120, ActivationEpoch: 500,
+	})
+
+	resp, result := graphQLRequest(t, ts, apiKey, types.GraphQLRequest{
+		Query: `query Dashboard($address: String!) {
+			account(address: $address) {
+				address lamports balance exists owner
+				tokenAccounts { mint amount uiAmount decimals }
+				transactions(limit: 1) { signature slot success memo }
+				stakeAccounts { address state voter delegatedStake activationEpoch deactivationEpoch }
+			}
+		}`,
+		Variables: map[string]interface{}{"address": wallet},
+	}, "172.29.0.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	require.Empty(t, result.Errors)
+
+	var data struct {
+		Account struct {
+			Address       string
+			Lamports      string
+			Balance       float64
+			Exists        bool
+			Owner         string
+			TokenAccounts []struct {
+				Mint     string
+				Amount   string
+				UIAmount float64 `json:"uiAmount"`
+				Decimals int
+			}
+			Transactions []struct {
+				Signature string
+				Slot      string
+				Success   bool
+				Memo      *string
+			}
+			StakeAccounts []struct {
+				Address           string
+				State             string
+				Voter             string
+				DelegatedStake    string
+				ActivationEpoch   string
+				DeactivationEpoch *string
+			}
+		}
+	}
+	require.NoError(t, json.Unmarshal(result.Data, &data))
+
+	account := data.Account
+	assert.Equal(t, wallet, account.Address)
+	assert.Equal(t, "1500000000", account.Lamports)
+	assert.Equal(t, 1.5, account.Balance)
+	assert.True(t, account.Exists)
+	assert.Equal(t, fakerpc.SystemProgram, account.Owner)
+
+	require.Len(t, account.TokenAccounts, 1)
+	assert.Equal(t, usdc, account.TokenAccounts[0].Mint)
+	assert.Equal(t, "12500000", account.TokenAccounts[0].Amount)
+	assert.Equal(t, 12.5, account.TokenAccounts[0].UIAmount)
+	assert.Equal(t, 6, account.TokenAccounts[0].Decimals)
+
+	require.Len(t, account.Transactions, 1)
+	assert.Equal(t, second, account.Transactions[0].Signature)
+	assert.Equal(t, "200", account.Transactions[0].Slot)
+	assert.False(t, account.Transactions[0].Success)
+	require.NotNil(t, account.Transactions[0].Memo)
+	assert.Equal(t, "hello", *account.Transactions[0].Memo)
+
+	require.Len(t, account.StakeAccounts, 1)
+	assert.Equal(t, stake, account.StakeAccounts[0].Address)
+	assert.Equal(t, "delegated", account.StakeAccounts[0].State)
+	assert.Equal(t, voter, account.StakeAccounts[0].Voter)
+	assert.Equal(t, "1997717120", account.StakeAccounts[0].DelegatedStake)
+	assert.Equal(t, "500", account.StakeAccounts[0].ActivationEpoch)
+	assert.Nil(t, account.StakeAccounts[0].DeactivationEpoch)
+	t.Log("✓ Balance, tokens, transactions and stakes come back in one request")
+
+	assert.Equal(t, 8, result.Extensions.Cost)
+	t.Log("✓ The response reports what the query cost")
+}
+
+func TestGraphQL_Batching(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	missing := solana.NewWallet().PublicKey().String()
+	resp, result := graphQLRequest(t, ts, ts.testAPIKey, types.GraphQLRequest{
+		Query: `query Wallets($addresses: [String!]!, $missing: String!) {
+			accounts(addresses: $addresses) { address balance }
+			missing: account(address: $missing) { exists owner }
+			again: account(address: "` + testWallets[0] + `") { lamports }
+		}`,
+		Variables: map[string]interface{}{"addresses": testWallets, "missing": missing},
+	}, "172.29.1.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	require.Empty(t, result.Errors)
+
+	var data struct {
+		Accounts []struct {
+			Address string
+			Balance float64
+		}
+		Missing struct {
+			Exists bool
+			Owner  *string
+		}
+		Again struct {
+			Lamports string
+		}
+	}
+	require.NoError(t, json.Unmarshal(result.Data, &data))
+
+	require.Len(t, data.Accounts, 3)
+	for i, account := range data.Accounts {
+		assert.Equal(t, testWallets[i], account.Address)
+		assert.Equal(t, float64(testBalances[i])/1e9, account.Balance)
+	}
+	assert.False(t, data.Missing.Exists)
+	assert.Nil(t, data.Missing.Owner)
+	assert.Equal(t, "1500000000", data.Again.Lamports)
+
+	assert.Equal(t, 1, ts.rpc.Calls("getMultipleAccounts"))
+	assert.Zero(t, ts.rpc.Calls("getBalance"))
+	t.Log("✓ Accounts read anywhere in a query cost one getMultipleAccounts call")
+
+	resp, result = graphQLRequest(t, ts, ts.testAPIKey, types.GraphQLRequest{
+		Query: `{ account(address: "not-a-wallet") { address balance } }`,
+	}, "172.29.1.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, types.CodeInvalidAddress, graphQLCode(t, result))
+	assert.Equal(t, []interface{}{"account", "balance"}, result.Errors[0].Path)
+	assert.JSONEq(t, `{"account": {"address": "not-a-wallet", "balance": null}}`, string(result.Data))
+	t.Log("✓ Failed lookups null their field with a coded error")
+}
+
+func TestGraphQL_Cost(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	ts := newTestSuite(t, types.CreateAPIKeyRequest{Name: "graphql-cost"}, api.WithClock(clock.Now))
+	defer ts.cleanup(t)
+
+	wallets := make([]string, 7)
+	for i := range wallets {
+		wallets[i] = solana.NewWallet().PublicKey().String()
+	}
+	stakes := types.GraphQLRequest{
+		Query:     `query Stakes($addresses: [String!]!) { accounts(addresses: $addresses) { balance stakeAccounts { address } } }`,
+		Variables: map[string]interface{}{"addresses": wallets},
+	}
+
+	resp, result := graphQLRequest(t, ts, ts.testAPIKey, stakes, "172.29.2.1")
+	require.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, types.CodeQueryTooComplex, graphQLCode(t, result))
+	assert.Equal(t, float64(23), result.Errors[0].Extensions.Details["cost"])
+	assert.Equal(t, float64(20), result.Errors[0].Extensions.Details["max"])
+	assert.Zero(t, ts.rpc.Calls("getProgramAccounts"))
+	t.Log("✓ Queries costing more than the tier's burst are rejected before they run")
+
+	stakes.Variables["addresses"] = wallets[:5]
+	resp, result = graphQLRequest(t, ts, ts.testAPIKey, stakes, "172.29.2.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	require.Empty(t, result.Errors)
+	assert.Equal(t, 17, result.Extensions.Cost)
+	assert.Equal(t, "2", resp.Header.Get("X-RateLimit-Remaining"))
+
+	resp, result = graphQLRequest(t, ts, ts.testAPIKey, stakes, "172.29.2.1")
+	require.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, types.CodeRateLimited, graphQLCode(t, result))
+	assert.Equal(t, float64(17), result.Errors[0].Extensions.Details["cost"])
+	assert.NotEmpty(t, resp.Header.Get(fiber.HeaderRetryAfter))
+	t.Log("✓ Queries spend their cost from the per-minute limit")
+
+	clock.Advance(time.Minute)
+	resp, result = graphQLRequest(t, ts, ts.testAPIKey, types.GraphQLRequest{
+		Query: `{ account(address: "` + testWallets[0] + `") { a: stakeAccounts { address } b: stakeAccounts { address } } }`,
+	}, "172.29.2.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, 4, result.Extensions.Cost)
+	assert.Equal(t, types.CodeQueryTooComplex, graphQLCode(t, result))
+	assert.Equal(t, 6, ts.rpc.Calls("getProgramAccounts"))
+	t.Log("✓ Aliases cannot do more than the query paid for")
+}
+
+func TestGraphQL_PersistedQueries(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	query := `{ account(address: "` + testWallets[1] + `") { lamports } }`
+	sum := sha256.Sum256([]byte(query))
+	hash := hex.EncodeToString(sum[:])
+	extensions := &types.GraphQLExtensions{PersistedQuery: &types.PersistedQuery{Version: 1, SHA256Hash: hash}}
+
+	resp, result := graphQLRequest(t, ts, ts.testAPIKey, types.GraphQLRequest{Extensions: extensions}, "172.29.3.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, types.CodePersistedQueryNotFound, graphQLCode(t, result))
+	assert.Equal(t, "PersistedQueryNotFound", result.Errors[0].Message)
+	t.Log("✓ Unknown hashes ask for the query")
+
+	resp, result = graphQLRequest(t, ts, ts.testAPIKey, types.GraphQLRequest{
+		Query:      `{ account(address: "` + testWallets[0] + `") { lamports } }`,
+		Extensions: extensions,
+	}, "172.29.3.1")
+	require.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, types.CodeInvalidRequest, graphQLCode(t, result))
+	t.Log("✓ A query must match its hash")
+
+	resp, result = graphQLRequest(t, ts, ts.testAPIKey, types.GraphQLRequest{Query: query, Extensions: extensions}, "172.29.3.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	require.Empty(t, result.Errors)
+	assert.JSONEq(t, `{"account": {"lamports": "42000000"}}`, string(result.Data))
+
+	encoded, _ := json.Marshal(extensions)
+	req, _ := http.NewRequest("GET", "/graphql?extensions="+url.QueryEscape(string(encoded)), nil)
+	resp, result = sendGraphQL(t, ts, req, ts.testAPIKey, "172.29.3.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	require.Empty(t, result.Errors)
+	assert.JSONEq(t, `{"account": {"lamports": "42000000"}}`, string(result.Data))
+	t.Log("✓ Registered queries run by hash over GET")
+}
+
+func TestGraphQL_Errors(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	request := types.GraphQLRequest{Query: `{ account(address: "` + testWallets[0] + `") { balance } }`}
+
+	resp, result := graphQLRequest(t, ts, "", request, "172.29.4.1")
+	require.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, types.CodeUnauthorized, graphQLCode(t, result))
+	assert.Equal(t, resp.Header.Get(fiber.HeaderXRequestID), result.Errors[0].Extensions.RequestID)
+	assert.Nil(t, result.Data)
+	t.Log("✓ Rejected requests get GraphQL errors with the HTTP status of their code")
+
+	resp, result = graphQLRequest(t, ts, ts.testAPIKey, types.GraphQLRequest{Query: `{ account(address: 1) { balance }`}, "172.29.4.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	require.NotEmpty(t, result.Errors)
+	assert.NotEmpty(t, result.Errors[0].Locations)
+	assert.Zero(t, ts.rpc.Calls("getMultipleAccounts"))
+	t.Log("✓ Invalid queries are reported without reading anything")
+
+	resp, result = graphQLRequest(t, ts, ts.testAPIKey, types.GraphQLRequest{
+		Query: `{ account(address: "` + testWallets[0] + `") { balance tokenAccounts { mint } } }`,
+	}, "172.29.4.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, types.CodeForbidden, graphQLCode(t, result))
+	assert.Equal(t, []interface{}{"account", "tokenAccounts"}, result.Errors[0].Path)
+	assert.JSONEq(t, `{"account": {"balance": 1.5, "tokenAccounts": null}}`, string(result.Data))
+	t.Log("✓ Fields outside the key's scopes are null with a forbidden error")
+}
@@ -0,0 +1,283 @@
+package test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"nova/api/grpcapi/novapb"
+	"nova/api/types"
+	"nova/test/fakerpc"
+)
+
+// dialGRPC serves the suite's gRPC server over an in-memory listener.
+func dialGRPC(t *testing.T, ts *TestSuite) novapb.BalanceServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	go ts.server.GRPC().Serve(listener)
+	t.Cleanup(ts.server.GRPC().Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return novapb.NewBalanceServiceClient(conn)
+}
+
+func grpcContext(t *testing.T, apiKey, clientIP string) context.Context {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+
+	pairs := []string{"x-forwarded-for", clientIP}
+	if apiKey != "" {
+		pairs = append(pairs, "x-api-key", apiKey)
+	}
+	return metadata.AppendToOutgoingContext(ctx, pairs...)
+}
+
+// grpcError checks err's code and returns the API error code it carries.
+func grpcError(t *testing.T, err error, code codes.Code) *errdetails.ErrorInfo {
+	t.Helper()
+
+	st, ok := status.FromError(err)
+	require.True(t, ok, "expected a status error, got %v", err)
+	require.Equal(t, code, st.Code(), st.Message())
+
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	t.Fatalf("status %v has no ErrorInfo", st)
+	return nil
+}
+
+func TestGRPC_GetBalances(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+	client := dialGRPC(t, ts)
+
+	ts.rpc.FailAccount(testWallets[1], &fakerpc.Error{Code: -32005, Message: "Node is behind"})
+
+	resp, err := client.GetBalances(grpcContext(t, ts.testAPIKey, "172.28.0.1"), &novapb.GetBalancesRequest{
+		Wallets: []string{testWallets[0], testWallets[1], "not-a-wallet"},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Balances, 3)
+
+	assert.Equal(t, testWallets[0], resp.Balances[0].Address)
+	assert.Equal(t, 1.5, resp.Balances[0].Balance)
+	assert.Nil(t, resp.Balances[0].Error)
+	require.NotNil(t, resp.Balances[1].Error)
+	assert.Equal(t, types.CodeUpstreamError, resp.Balances[1].Error.Code)
+	assert.NotContains(t, resp.Balances[1].Error.Message, "Node is behind")
+	require.NotNil(t, resp.Balances[2].Error)
+	assert.Equal(t, types.CodeInvalidAddress, resp.Balances[2].Error.Code)
+	t.Log("✓ Balances are returned in order with coded wallet errors")
+
+	_, err = client.GetBalances(grpcContext(t, ts.testAPIKey, "172.28.0.1"), &novapb.GetBalancesRequest{})
+	info := grpcError(t, err, codes.InvalidArgument)
+	assert.Equal(t, types.CodeInvalidRequest, info.Reason)
+
+	wallets := make([]string, 101)
+	for i := range wallets {
+		wallets[i] = testWallets[0]
+	}
+	_, err = client.GetBalances(grpcContext(t, ts.testAPIKey, "172.28.0.1"), &novapb.GetBalancesRequest{Wallets: wallets})
+	info = grpcError(t, err, codes.InvalidArgument)
+	assert.Equal(t, types.CodeTooManyWallets, info.Reason)
+	assert.Equal(t, "100", info.Metadata["max"])
+	t.Log("✓ Requests are validated like the HTTP API")
+}
+
+func TestGRPC_Auth(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+	client := dialGRPC(t, ts)
+
+	request := &novapb.GetBalancesRequest{Wallets: testWallets[:1]}
+
+	_, err := client.GetBalances(grpcContext(t, "", "172.28.1.1"), request)
+	info := grpcError(t, err, codes.Unauthenticated)
+	assert.Equal(t, types.CodeUnauthorized, info.Reason)
+	assert.Equal(t, "nova", info.Domain)
+
+	_, err = client.GetBalances(grpcContext(t, "nova_not-a-key", "172.28.1.1"), request)
+	grpcError(t, err, codes.Unauthenticated)
+	t.Log("✓ Calls without a valid API key are rejected")
+
+	_, err = client.GetTokenBalances(grpcContext(t, ts.testAPIKey, "172.28.1.1"), &novapb.GetTokenBalancesRequest{Owners: testWallets[:1]})
+	info = grpcError(t, err, codes.PermissionDenied)
+	assert.Equal(t, types.CodeForbidden, info.Reason)
+	assert.Equal(t, types.ScopeTokensRead, info.Metadata["scope"])
+	t.Log("✓ Methods require their scope")
+}
+
+func TestGRPC_StreamBalances(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+	client := dialGRPC(t, ts)
+
+	stream, err := client.StreamBalances(grpcContext(t, ts.testAPIKey, "172.28.2.1"), &novapb.GetBalancesRequest{
+		Wallets: append([]string{"not-a-wallet"}, testWallets...),
+	})
+	require.NoError(t, err)
+
+	updates := make(map[int32]*novapb.Balance)
+	for {
+		update, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		updates[update.Index] = update.Balance
+	}
+
+	require.Len(t, updates, 4)
+	assert.Equal(t, types.CodeInvalidAddress, updates[0].Error.GetCode())
+	for i, wallet := range testWallets {
+		assert.Equal(t, wallet, updates[int32(i+1)].Address)
+		assert.Equal(t, float64(testBalances[i])/1e9, updates[int32(i+1)].Balance)
+	}
+	t.Log("✓ Every balance is streamed with its index")
+}
+
+func TestGRPC_TokenBalances(t *testing.T) {
+	ts := newTestSuite(t, types.CreateAPIKeyRequest{
+		Name:   "grpc-tokens",
+		Scopes: []string{types.ScopeBalanceRead, types.ScopeTokensRead},
+	})
+	defer ts.cleanup(t)
+	client := dialGRPC(t, ts)
+
+	usdc := solana.NewWallet().PublicKey().String()
+	bonk := solana.NewWallet().PublicKey().String()
+	usdcAccount := solana.NewWallet().PublicKey().String()
+	ts.rpc.SetTokenAccount(testWallets[0], fakerpc.TokenAccount{
+		Address: usdcAccount, Mint: usdc, Amount: 12_500_000, Decimals: 6,
+	})
+	ts.rpc.SetTokenAccount(testWallets[0], fakerpc.TokenAccount{
+		Address: solana.NewWallet().PublicKey().String(), Mint: bonk, Amount: 7, Decimals: 0,
+		Program: fakerpc.Token2022Program,
+	})
+	ts.rpc.FailAccount(testWallets[2], &fakerpc.Error{Code: -32005, Message: "Node is behind"})
+
+	resp, err := client.GetTokenBalances(grpcContext(t, ts.testAPIKey, "172.28.3.1"), &novapb.GetTokenBalancesRequest{
+		Owners: testWallets,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Owners, 3)
+
+	owner := resp.Owners[0]
+	assert.Equal(t, testWallets[0], owner.Owner)
+	assert.Nil(t, owner.Error)
+	require.Len(t, owner.Tokens, 2)
+	assert.Equal(t, usdc, owner.Tokens[0].Mint)
+	assert.Equal(t, usdcAccount, owner.Tokens[0].Account)
+	assert.Equal(t, "12500000", owner.Tokens[0].Amount)
+	assert.Equal(t, uint32(6), owner.Tokens[0].Decimals)
+	assert.Equal(t, 12.5, owner.Tokens[0].UiAmount)
+	assert.Equal(t, bonk, owner.Tokens[1].Mint)
+	assert.Equal(t, "7", owner.Tokens[1].Amount)
+	t.Log("✓ Token and Token-2022 accounts are listed")
+
+	assert.Empty(t, resp.Owners[1].Tokens)
+	assert.Nil(t, resp.Owners[1].Error)
+	require.NotNil(t, resp.Owners[2].Error)
+	assert.Equal(t, types.CodeUpstreamError, resp.Owners[2].Error.Code)
+	t.Log("✓ A failed owner does not fail the call")
+
+	resp, err = client.GetTokenBalances(grpcContext(t, ts.testAPIKey, "172.28.3.2"), &novapb.GetTokenBalancesRequest{
+		Owners: testWallets[:1],
+		Mint:   bonk,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Owners[0].Tokens, 1)
+	assert.Equal(t, bonk, resp.Owners[0].Tokens[0].Mint)
+
+	_, err = client.GetTokenBalances(grpcContext(t, ts.testAPIKey, "172.28.3.3"), &novapb.GetTokenBalancesRequest{
+		Owners: testWallets[:1],
+		Mint:   "not-a-mint",
+	})
+	info := grpcError(t, err, codes.InvalidArgument)
+	assert.Equal(t, types.CodeInvalidAddress, info.Reason)
+	t.Log("✓ Balances can be filtered by mint")
+}
+
+func TestGRPC_Limits(t *testing.T) {
+	ts := newTestSuite(t, types.CreateAPIKeyRequest{Name: "grpc-quota", DailyQuota: 2})
+	defer ts.cleanup(t)
+	client := dialGRPC(t, ts)
+
+	request := &novapb.GetBalancesRequest{Wallets: testWallets[:1]}
+
+	var header metadata.MD
+	_, err := client.GetBalances(grpcContext(t, ts.testAPIKey, "172.28.4.1"), request, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"120"}, header.Get("x-ratelimit-limit"))
+	assert.NotEmpty(t, header.Get("x-ratelimit-remaining"))
+	t.Log("✓ Calls report the tier's rate limit")
+
+	// The quota is shared with the HTTP API.
+	resp, data := v1Request(t, ts, "POST", "/api/v1/balances", ts.testAPIKey, []byte(`{"wallets":["`+testWallets[0]+`"]}`), "172.28.4.2")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode, string(data))
+
+	_, err = client.GetBalances(grpcContext(t, ts.testAPIKey, "172.28.4.1"), request)
+	info := grpcError(t, err, codes.ResourceExhausted)
+	assert.Equal(t, types.CodeQuotaExceeded, info.Reason)
+	assert.Equal(t, "daily", info.Metadata["period"])
+	t.Log("✓ Quotas apply across HTTP and gRPC")
+
+	var trailer metadata.MD
+	for i := 0; i < 12; i++ {
+		_, err = client.GetBalances(grpcContext(t, "", "172.28.4.3"), request, grpc.Trailer(&trailer))
+	}
+	info = grpcError(t, err, codes.ResourceExhausted)
+	assert.Equal(t, types.CodeRateLimited, info.Reason)
+	assert.NotEmpty(t, trailer.Get("retry-after"))
+	t.Log("✓ Calls that fail authentication are limited per IP")
+}
+
+func TestGRPC_Metrics(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+	client := dialGRPC(t, ts)
+
+	_, err := client.GetBalances(grpcContext(t, ts.testAPIKey, "172.28.5.1"), &novapb.GetBalancesRequest{Wallets: testWallets[:1]})
+	require.NoError(t, err)
+	_, err = client.GetBalances(grpcContext(t, "", "172.28.5.1"), &novapb.GetBalancesRequest{Wallets: testWallets[:1]})
+	require.Error(t, err)
+
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	resp, err := ts.app.Test(req, 30000)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+
+	assert.Contains(t, string(body), `nova_grpc_requests_total{method="/nova.v1.BalanceService/GetBalances",code="OK"} 1`)
+	assert.Contains(t, string(body), `nova_grpc_requests_total{method="/nova.v1.BalanceService/GetBalances",code="Unauthenticated"} 1`)
+	assert.Contains(t, string(body), "nova_grpc_request_duration_seconds_count 2")
+	t.Log("✓ gRPC calls are counted on /metrics")
+}
@@ -0,0 +1,258 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nova/api"
+	"nova/api/store"
+	"nova/api/types"
+)
+
+func jobRequest(t *testing.T, ts *TestSuite, method, path, contentType string, body io.Reader, clientIP string) (*http.Response, []byte) {
+	t.Helper()
+
+	req, _ := http.NewRequest(method, path, body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("X-API-Key", ts.testAPIKey)
+	req.Header.Set("X-Forwarded-For", clientIP)
+
+	resp, err := ts.app.Test(req, 30000)
+	require.NoError(t, err)
+
+	data, _ := io.ReadAll(resp.Body)
+	return resp, data
+}
+
+func createJob(t *testing.T, ts *TestSuite, contentType string, body io.Reader, clientIP string) *types.BalanceJob {
+	t.Helper()
+
+	resp, data := jobRequest(t, ts, "POST", "/api/jobs/balances", contentType, body, clientIP)
+	require.Equal(t, fiber.StatusAccepted, resp.StatusCode, string(data))
+
+	var response types.JobResponse
+	require.NoError(t, json.Unmarshal(data, &response))
+	assert.Equal(t, "/api/jobs/"+response.Data.ID.Hex(), resp.Header.Get("Location"))
+	return response.Data
+}
+
+func runJobs(t *testing.T, ts *TestSuite) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ts.server.Run(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func waitForJob(t *testing.T, ts *TestSuite, id string) *types.BalanceJob {
+	t.Helper()
+
+	var job *types.BalanceJob
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = ts.server.Jobs().Get(context.Background(), ts.testKeyID.Hex(), id)
+		return err == nil && job.Status == types.JobCompleted
+	}, 10*time.Second, 10*time.Millisecond)
+	return job
+}
+
+func TestJobs_BalanceSnapshot(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+	runJobs(t, ts)
+
+	wallets := make([]string, 0, 350)
+	for i := 0; i < 345; i++ {
+		wallets = append(wallets, solana.NewWallet().PublicKey().String())
+	}
+	wallets = append(wallets, testWallets...)
+	wallets = append(wallets, testWallets[0], "not-a-wallet")
+
+	reqBody, _ := json.Marshal(types.BalanceRequest{Wallets: wallets})
+	job := createJob(t, ts, "application/json", bytes.NewReader(reqBody), "172.23.0.1")
+	assert.Equal(t, len(wallets), job.Total)
+
+	job = waitForJob(t, ts, job.ID.Hex())
+	assert.Equal(t, len(wallets), job.Processed)
+	assert.Equal(t, 1, job.Failed)
+	assert.NotNil(t, job.CompletedAt)
+	t.Logf("✓ Job of %d wallets completed", job.Total)
+
+	path := "/api/jobs/" + job.ID.Hex() + "/results"
+
+	resp, data := jobRequest(t, ts, "GET", path, "", nil, "172.23.0.2")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var response types.BalanceResponse
+	require.NoError(t, json.Unmarshal(data, &response))
+	require.Len(t, response.Data, len(wallets))
+	for i, result := range response.Data {
+		assert.Equal(t, wallets[i], result.Address)
+	}
+	assert.Equal(t, 1.5, response.Data[345].Balance)
+	assert.Equal(t, 1.5, response.Data[348].Balance)
+	assert.NotEmpty(t, response.Data[349].Error)
+	assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+	t.Log("✓ JSON results are streamed in submission order")
+
+	resp, data = jobRequest(t, ts, "GET", path+"?format=ndjson", "", nil, "172.23.0.3")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+	lines := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var result types.WalletBalance
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &result))
+		assert.Equal(t, wallets[lines], result.Address)
+		lines++
+	}
+	assert.Equal(t, len(wallets), lines)
+	t.Log("✓ NDJSON results have one wallet per line")
+
+	resp, data = jobRequest(t, ts, "GET", path+"?format=csv", "", nil, "172.23.0.4")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), ".csv")
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, len(wallets)+1)
+	assert.Equal(t, []string{"address", "balance", "error"}, records[0])
+	assert.Equal(t, []string{testWallets[1], "0.042", ""}, records[347])
+	t.Log("✓ CSV results have a header row")
+
+	resp, _ = jobRequest(t, ts, "GET", path+"?format=xml", "", nil, "172.23.0.5")
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestJobs_CSVUpload(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	upload := "address,label\n" + strings.Join(testWallets, ",treasury\n") + ",treasury\n"
+
+	job := createJob(t, ts, "text/csv", strings.NewReader(upload), "172.23.1.1")
+	assert.Equal(t, len(testWallets), job.Total)
+	t.Log("✓ CSV body with a header row")
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, _ := writer.CreateFormFile("file", "wallets.csv")
+	part.Write([]byte(strings.Join(testWallets, "\n")))
+	writer.Close()
+
+	job = createJob(t, ts, writer.FormDataContentType(), &form, "172.23.1.2")
+	assert.Equal(t, len(testWallets), job.Total)
+	t.Log("✓ CSV file upload")
+
+	resp, _ := jobRequest(t, ts, "POST", "/api/jobs/balances", "text/csv", strings.NewReader("address\n"), "172.23.1.3")
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = jobRequest(t, ts, "GET", "/api/jobs/"+job.ID.Hex()+"/results", "", nil, "172.23.1.4")
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode, "Results are not available before the job completes")
+
+	other := newTestSuite(t, types.CreateAPIKeyRequest{Name: "other"})
+	defer other.cleanup(t)
+	resp, _ = jobRequest(t, other, "GET", "/api/jobs/"+job.ID.Hex(), "", nil, "172.23.1.5")
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func TestJobs_ResumeAfterRestart(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	ts := newTestSuite(t, types.CreateAPIKeyRequest{Name: "jobs-resume"}, api.WithClock(clock.Now))
+	defer ts.cleanup(t)
+
+	wallets := make([]string, 0, 250)
+	for i := 0; i < 250; i++ {
+		wallets = append(wallets, solana.NewWallet().PublicKey().String())
+	}
+	reqBody, _ := json.Marshal(types.BalanceRequest{Wallets: wallets})
+	job := createJob(t, ts, "application/json", bytes.NewReader(reqBody), "172.23.2.1")
+
+	// A pod claims the job, finishes the first chunk and dies.
+	ctx := context.Background()
+	crashed, err := ts.stores.Jobs.Claim(ctx, "crashed-pod", clock.Now(), clock.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, job.ID, crashed.ID)
+
+	first := make([]types.WalletBalance, 100)
+	for i := range first {
+		first[i] = types.WalletBalance{Address: wallets[i], Balance: 9}
+	}
+	require.NoError(t, ts.stores.Jobs.CompleteChunk(ctx, job.ID, "crashed-pod", 0, first))
+
+	runJobs(t, ts)
+	time.Sleep(1500 * time.Millisecond)
+
+	running, err := ts.server.Jobs().Get(ctx, ts.testKeyID.Hex(), job.ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, types.JobRunning, running.Status)
+	assert.Equal(t, 100, running.Processed)
+	t.Log("✓ A leased job is left alone")
+
+	clock.Advance(2 * time.Minute)
+	job = waitForJob(t, ts, job.ID.Hex())
+	assert.Equal(t, 250, job.Processed)
+	assert.Equal(t, 150, ts.rpc.Calls("getBalance"), "Only the unfinished chunks are looked up again")
+
+	resp, data := jobRequest(t, ts, "GET", "/api/jobs/"+job.ID.Hex()+"/results", "", nil, "172.23.2.2")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var response types.BalanceResponse
+	require.NoError(t, json.Unmarshal(data, &response))
+	require.Len(t, response.Data, 250)
+	assert.Equal(t, 9.0, response.Data[99].Balance)
+	assert.Equal(t, 0.0, response.Data[100].Balance)
+	t.Log("✓ An expired lease is taken over and the job resumes at the next chunk")
+
+	keyDoc, err := ts.stores.Keys.Get(ctx, ts.testKeyID)
+	require.NoError(t, err)
+	report, err := ts.server.Usage().Report(ctx, keyDoc.Principal(), clock.Now().Add(-time.Hour), clock.Now().Add(time.Hour), clock.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(150), report.Totals.RPCCalls)
+	t.Log("✓ Each chunk's lookups count towards the key's usage")
+}
+
+func TestJobs_StaleLeaseCannotCompleteChunks(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	reqBody, _ := json.Marshal(types.BalanceRequest{Wallets: testWallets})
+	job := createJob(t, ts, "application/json", bytes.NewReader(reqBody), "172.23.3.1")
+
+	ctx := context.Background()
+	now := time.Now()
+	_, err := ts.stores.Jobs.Claim(ctx, "stale-pod", now, now.Add(time.Minute))
+	require.NoError(t, err)
+	_, err = ts.stores.Jobs.Claim(ctx, "new-pod", now.Add(2*time.Minute), now.Add(3*time.Minute))
+	require.NoError(t, err)
+
+	stale := []types.WalletBalance{{Address: testWallets[0], Balance: 9}}
+	err = ts.stores.Jobs.CompleteChunk(ctx, job.ID, "stale-pod", 0, stale)
+	assert.ErrorIs(t, err, store.ErrLeaseLost)
+
+	pending, err := ts.stores.Jobs.PendingChunks(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+	t.Log("✓ An instance whose lease was taken over cannot complete chunks")
+}
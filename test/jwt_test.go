@@ -0,0 +1,274 @@
+package test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nova/api/config"
+	"nova/api/middleware"
+	"nova/api/services"
+	"nova/api/store"
+	"nova/api/types"
+)
+
+const (
+	testJWTIssuer   = "https://issuer.nova.test"
+	testJWTAudience = "nova-api"
+)
+
+type jwtFixture struct {
+	app        *fiber.App
+	signingKey *rsa.PrivateKey
+	kid        string
+}
+
+// setupJWTTest runs entirely offline: the JWKS is served from a local
+// httptest server and keys live in memory.
+func setupJWTTest(t *testing.T) *jwtFixture {
+	t.Helper()
+
+	signingKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	kid := "test-key-1"
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": kid,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(signingKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(signingKey.E)).Bytes()),
+		}},
+	}
+
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	t.Cleanup(jwksServer.Close)
+
+	cfg := &types.Config{
+		Network:        types.NetworkMainnet,
+		JWKSURL:        jwksServer.URL,
+		JWTIssuer:      testJWTIssuer,
+		JWTAudience:    testJWTAudience,
+		JWTTierClaim:   "nova_tier",
+		RateLimitTiers: config.DefaultRateLimitTiers(),
+	}
+
+	app := fiber.New(fiber.Config{
+		DisableStartupMessage: true,
+	})
+
+	keys, err := services.NewKeyService(cfg, store.NewMemory())
+	require.NoError(t, err)
+
+	api := app.Group("/api")
+	limiters := middleware.NewRateLimiters()
+	api.Use(middleware.AuthMiddleware(cfg, keys, services.NewJWTVerifier(cfg), time.Now))
+	api.Use(middleware.TierRateLimitMiddleware(limiters, cfg.RateLimitTiers, time.Now))
+	api.Get("/whoami", middleware.RequireScope(types.ScopeBalanceRead), func(c *fiber.Ctx) error {
+		return c.JSON(c.Locals("principal"))
+	})
+
+	return &jwtFixture{app: app, signingKey: signingKey, kid: kid}
+}
+
+func (f *jwtFixture) token(t *testing.T, claims jwt.MapClaims, key *rsa.PrivateKey) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = f.kid
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func (f *jwtFixture) claims(subject string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   subject,
+		"iss":   testJWTIssuer,
+		"aud":   testJWTAudience,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"scope": "balance:read tokens:read",
+	}
+}
+
+func (f *jwtFixture) call(t *testing.T, token string) (int, []byte) {
+	t.Helper()
+
+	req, _ := http.NewRequest("GET", "/api/whoami", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Forwarded-For", "10.50.0.1")
+
+	resp, err := f.app.Test(req, 10000)
+	require.NoError(t, err)
+
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, body
+}
+
+func TestJWT_ValidTokenMapsToPrincipal(t *testing.T) {
+	f := setupJWTTest(t)
+
+	claims := f.claims("svc-reconciler")
+	claims["nova_tier"] = types.TierPro
+
+	status, body := f.call(t, f.token(t, claims, f.signingKey))
+	require.Equal(t, fiber.StatusOK, status, string(body))
+
+	var principal types.Principal
+	require.NoError(t, json.Unmarshal(body, &principal))
+
+	assert.Equal(t, "jwt:svc-reconciler", principal.KeyID)
+	assert.Equal(t, types.TierPro, principal.Tier)
+	assert.ElementsMatch(t, []string{types.ScopeBalanceRead, types.ScopeTokensRead}, principal.Scopes)
+	t.Log("✓ JWT claims mapped to principal, scopes and tier")
+}
+
+func TestJWT_RejectedTokens(t *testing.T) {
+	f := setupJWTTest(t)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	expired := f.claims("svc-expired")
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	wrongIssuer := f.claims("svc-issuer")
+	wrongIssuer["iss"] = "https://evil.test"
+
+	wrongAudience := f.claims("svc-audience")
+	wrongAudience["aud"] = "someone-else"
+
+	noScope := f.claims("svc-scope")
+	noScope["scope"] = "tokens:read"
+
+	cases := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"expired", f.token(t, expired, f.signingKey), fiber.StatusUnauthorized},
+		{"wrong issuer", f.token(t, wrongIssuer, f.signingKey), fiber.StatusUnauthorized},
+		{"wrong audience", f.token(t, wrongAudience, f.signingKey), fiber.StatusUnauthorized},
+		{"unknown signer", f.token(t, f.claims("svc-forged"), otherKey), fiber.StatusUnauthorized},
+		{"garbage", "not-a-jwt", fiber.StatusUnauthorized},
+		{"missing scope", f.token(t, noScope, f.signingKey), fiber.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		status, body := f.call(t, tc.token)
+		assert.Equal(t, tc.status, status, "%s: %s", tc.name, body)
+	}
+
+	t.Log("✓ Invalid bearer tokens are rejected")
+}
+
+func TestJWT_TierRateLimitFromClaim(t *testing.T) {
+	f := setupJWTTest(t)
+
+	freeToken := f.token(t, f.claims("svc-free"), f.signingKey)
+
+	proClaims := f.claims("svc-pro")
+	proClaims["nova_tier"] = types.TierPro
+	proToken := f.token(t, proClaims, f.signingKey)
+
+	freeLimited := 0
+	proLimited := 0
+	for i := 0; i < 40; i++ {
+		if status, _ := f.call(t, freeToken); status == fiber.StatusTooManyRequests {
+			freeLimited++
+		}
+		if status, _ := f.call(t, proToken); status == fiber.StatusTooManyRequests {
+			proLimited++
+		}
+	}
+
+	assert.Greater(t, freeLimited, 0, "Free tier should be rate limited")
+	assert.Equal(t, 0, proLimited, "Pro tier should allow a larger burst")
+	t.Logf("✓ Tier rate limits applied from claim (free limited %d times)", freeLimited)
+}
+
+func TestJWT_JWKSRefresh(t *testing.T) {
+	signingKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kid": "unsupported", "kty": "EC", "crv": "secp256k1", "x": "AA", "y": "AA"},
+			{
+				"kid": "test-key-1",
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(signingKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(signingKey.E)).Bytes()),
+			},
+		},
+	}
+
+	var hits atomic.Int32
+	var failing atomic.Bool
+	failing.Store(true)
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	defer jwksServer.Close()
+
+	cfg := &types.Config{JWKSURL: jwksServer.URL, JWTIssuer: testJWTIssuer, JWTAudience: testJWTAudience}
+	f := &jwtFixture{kid: "test-key-1"}
+	token := f.token(t, f.claims("svc-refresh"), signingKey)
+
+	verifier := services.NewJWTVerifier(cfg)
+	for i := 0; i < 3; i++ {
+		_, err := verifier.Verify(context.Background(), token, time.Now())
+		assert.Error(t, err)
+	}
+	assert.Equal(t, int32(1), hits.Load())
+	t.Log("✓ A failed fetch is not retried before the refetch interval")
+
+	failing.Store(false)
+	hits.Store(0)
+	verifier = services.NewJWTVerifier(cfg)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 20)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = verifier.Verify(context.Background(), token, time.Now())
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), hits.Load())
+	t.Log("✓ Unusable keys are skipped and concurrent refreshes share one fetch")
+}
@@ -0,0 +1,108 @@
+package test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"nova/api/config"
+	"nova/api/database"
+	"nova/api/types"
+)
+
+// setupMongo connects to the MongoDB and Redis from the environment. Only
+// tests of the Mongo backend itself need it, everything else runs on the
+// in-memory stores.
+func setupMongo(t *testing.T) (*types.Config, *types.Database) {
+	t.Helper()
+
+	if err := godotenv.Load("../.env"); err != nil {
+		t.Logf("Warning: Could not load .env file: %v", err)
+	}
+
+	if os.Getenv("MONGO_URI") == "" {
+		t.Skip("MONGO_URI not set - skipping MongoDB tests")
+	}
+	t.Setenv("SOLANA_RPC_URL", "http://127.0.0.1:8899")
+
+	cfg := config.Load()
+	db, err := database.Connect(cfg)
+	require.NoError(t, err, "MongoDB and Redis should be reachable")
+	t.Cleanup(func() { database.Close(db) })
+
+	return cfg, db
+}
+
+func TestMigrations_AppliedOnceAcrossReplicas(t *testing.T) {
+	baseCfg, db := setupMongo(t)
+
+	cfg := *baseCfg
+	cfg.MongoDatabase = "nova_migrations_test"
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	require.NoError(t, db.MongoDB.Database(cfg.MongoDatabase).Drop(ctx))
+	defer db.MongoDB.Database(cfg.MongoDatabase).Drop(context.Background())
+
+	var wg sync.WaitGroup
+	applied := make([][]database.MigrationRecord, 3)
+	errs := make([]error, 3)
+
+	for i := range applied {
+		wg.Add(1)
+		go func(replica int) {
+			defer wg.Done()
+			applied[replica], errs[replica] = database.Migrate(ctx, &cfg, db)
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for i := range applied {
+		require.NoError(t, errs[i])
+		total += len(applied[i])
+	}
+
+	status, err := database.MigrationStatus(ctx, &cfg, db)
+	require.NoError(t, err)
+	assert.Equal(t, len(status), total, "Each migration should run exactly once")
+
+	for _, record := range status {
+		assert.NotNil(t, record.AppliedAt, "Migration %d should be applied", record.Version)
+	}
+	t.Logf("✓ %d migrations applied once across 3 concurrent replicas", total)
+
+	cursor, err := db.MongoDB.Database(cfg.MongoDatabase).Collection("api_keys").Indexes().List(ctx)
+	require.NoError(t, err)
+
+	var indexes []bson.M
+	require.NoError(t, cursor.All(ctx, &indexes))
+
+	names := make(map[string]bson.M)
+	for _, index := range indexes {
+		names[index["name"].(string)] = index
+	}
+
+	assert.Contains(t, names, "key_id_unique")
+	assert.Contains(t, names, "key_hash_unique")
+	require.Contains(t, names, "expires_at_ttl")
+	assert.NotNil(t, names["expires_at_ttl"]["expireAfterSeconds"])
+	t.Log("✓ Lookup and TTL indexes created")
+
+	again, err := database.Migrate(ctx, &cfg, db)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	var lock bson.M
+	err = db.MongoDB.Database(cfg.MongoDatabase).Collection("schema_migrations").FindOne(ctx, bson.M{"_id": "lock"}).Decode(&lock)
+	assert.Error(t, err, "Lock should be released after migrating")
+	t.Log("✓ Rerunning migrations is a no-op")
+}
@@ -0,0 +1,265 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nova/api"
+	"nova/api/openapi"
+	"nova/api/types"
+)
+
+// undocumented are the routes that are not part of the API itself.
+var undocumented = map[string]bool{
+	"GET /metrics":      true,
+	"GET /openapi.json": true,
+	"GET /docs":         true,
+}
+
+func fetchOpenAPI(t *testing.T, ts *TestSuite) map[string]interface{} {
+	t.Helper()
+
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	resp, err := ts.app.Test(req, 30000)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var spec map[string]interface{}
+	body, _ := io.ReadAll(resp.Body)
+	require.NoError(t, json.Unmarshal(body, &spec))
+	return spec
+}
+
+// matchSchema reports where value does not fit schema. Objects may not have
+// properties the schema does not list, so a field added to a type without
+// the document changing is caught.
+func matchSchema(spec, schema map[string]interface{}, value interface{}, at string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		schemas := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+		resolved, ok := schemas[name].(map[string]interface{})
+		if !ok {
+			return []string{at + ": missing schema " + name}
+		}
+		return matchSchema(spec, resolved, value, at)
+	}
+
+	if options, ok := schema["oneOf"].([]interface{}); ok {
+		for _, option := range options {
+			if len(matchSchema(spec, option.(map[string]interface{}), value, at)) == 0 {
+				return nil
+			}
+		}
+		return []string{at + ": matches none of oneOf"}
+	}
+
+	var problems []string
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an object, got %T", at, value)}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				problems = append(problems, at+"."+name.(string)+": required but missing")
+			}
+		}
+		for name, field := range object {
+			if properties == nil {
+				if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+					problems = append(problems, matchSchema(spec, additional, field, at+"."+name)...)
+				}
+				continue
+			}
+			property, ok := properties[name].(map[string]interface{})
+			if !ok {
+				problems = append(problems, at+"."+name+": not in the document")
+				continue
+			}
+			if field != nil {
+				problems = append(problems, matchSchema(spec, property, field, at+"."+name)...)
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an array, got %T", at, value)}
+		}
+		for i, item := range items {
+			problems = append(problems, matchSchema(spec, schema["items"].(map[string]interface{}), item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			problems = append(problems, fmt.Sprintf("%s: expected a string, got %T", at, value))
+		}
+	case "integer", "number":
+		if _, ok := value.(float64); !ok {
+			problems = append(problems, fmt.Sprintf("%s: expected a number, got %T", at, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			problems = append(problems, fmt.Sprintf("%s: expected a boolean, got %T", at, value))
+		}
+	}
+	return problems
+}
+
+// checkResponse sends req and checks its JSON body against the document's
+// response for the route and status.
+func checkResponse(t *testing.T, ts *TestSuite, spec map[string]interface{}, route string, req *http.Request) []byte {
+	t.Helper()
+
+	resp, err := ts.app.Test(req, 30000)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+
+	method, path, _ := strings.Cut(route, " ")
+	operation, ok := spec["paths"].(map[string]interface{})[path].(map[string]interface{})[strings.ToLower(method)].(map[string]interface{})
+	require.True(t, ok, "%s is not documented", route)
+
+	response, ok := operation["responses"].(map[string]interface{})[strconv.Itoa(resp.StatusCode)].(map[string]interface{})
+	require.True(t, ok, "%s: status %d is not documented: %s", route, resp.StatusCode, body)
+
+	media := response["content"].(map[string]interface{})["application/json"].(map[string]interface{})
+	var value interface{}
+	require.NoError(t, json.Unmarshal(body, &value))
+	problems := matchSchema(spec, media["schema"].(map[string]interface{}), value, "body")
+	assert.Empty(t, problems, "%s %d", route, resp.StatusCode)
+	return body
+}
+
+func TestOpenAPI_DocumentsEveryRoute(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	spec := fetchOpenAPI(t, ts)
+	assert.Equal(t, openapi.Version, spec["openapi"])
+
+	registered := make(map[string]bool)
+	for _, route := range ts.app.GetRoutes(true) {
+		key := route.Method + " " + openapi.Path(route.Path)
+		if route.Method == fiber.MethodHead || undocumented[key] {
+			continue
+		}
+		registered[key] = true
+	}
+
+	documented := make(map[string]bool)
+	for path, item := range spec["paths"].(map[string]interface{}) {
+		for method := range item.(map[string]interface{}) {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	var missing, stale []string
+	for route := range registered {
+		if !documented[route] {
+			missing = append(missing, route)
+		}
+	}
+	for route := range documented {
+		if !registered[route] {
+			stale = append(stale, route)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(stale)
+
+	assert.Empty(t, missing, "Routes missing from openapi.Routes")
+	assert.Empty(t, stale, "Documented routes that are not registered")
+	t.Logf("✓ %d routes documented", len(documented))
+
+	schemes := spec["components"].(map[string]interface{})["securitySchemes"].(map[string]interface{})
+	assert.Contains(t, schemes, "apiKey")
+	assert.Contains(t, schemes, "bearerToken")
+	assert.Contains(t, schemes, "adminKey")
+
+	balances := spec["paths"].(map[string]interface{})["/api/get-balance"].(map[string]interface{})["post"].(map[string]interface{})
+	limited := balances["responses"].(map[string]interface{})["429"].(map[string]interface{})
+	assert.Contains(t, limited["headers"], "Retry-After")
+	assert.Contains(t, limited["headers"], "X-RateLimit-Limit")
+	t.Log("✓ Auth schemes and rate-limit headers are described")
+}
+
+func TestOpenAPI_ResponsesMatchDocument(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	spec := fetchOpenAPI(t, ts)
+
+	request := func(method, path string, body interface{}, clientIP string) *http.Request {
+		var reader io.Reader
+		if body != nil {
+			encoded, _ := json.Marshal(body)
+			reader = bytes.NewReader(encoded)
+		}
+		req, _ := http.NewRequest(method, path, reader)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", ts.testAPIKey)
+		req.Header.Set("X-Forwarded-For", clientIP)
+		return req
+	}
+
+	wallets := types.BalanceRequest{Wallets: []string{testWallets[0], "not-a-wallet"}}
+	checkResponse(t, ts, spec, "POST /api/get-balance", request("POST", "/api/get-balance", wallets, "172.27.0.1"))
+	checkResponse(t, ts, spec, "POST /api/get-balance", request("POST", "/api/get-balance", types.BalanceRequest{}, "172.27.0.2"))
+	checkResponse(t, ts, spec, "POST /api/v1/balances", request("POST", "/api/v1/balances", wallets, "172.27.0.3"))
+	checkResponse(t, ts, spec, "POST /api/v1/balances", request("POST", "/api/v1/balances", types.BalanceRequest{}, "172.27.0.4"))
+	checkResponse(t, ts, spec, "GET /api/v1/accounts/{address}/balance", request("GET", "/api/v1/accounts/"+testWallets[0]+"/balance", nil, "172.27.0.5"))
+	checkResponse(t, ts, spec, "GET /api/usage", request("GET", "/api/usage", nil, "172.27.0.6"))
+
+	body := checkResponse(t, ts, spec, "POST /api/v1/jobs/balances", request("POST", "/api/v1/jobs/balances", wallets, "172.27.0.7"))
+	var job types.JobResponse
+	require.NoError(t, json.Unmarshal(body, &job))
+	checkResponse(t, ts, spec, "GET /api/v1/jobs/{id}", request("GET", "/api/v1/jobs/"+job.Data.ID.Hex(), nil, "172.27.0.8"))
+	checkResponse(t, ts, spec, "GET /api/v1/jobs/{id}/results", request("GET", "/api/v1/jobs/"+job.Data.ID.Hex()+"/results", nil, "172.27.0.9"))
+	t.Log("✓ Balance, usage and job responses match their schemas")
+
+	admin := request("POST", "/admin/keys", types.CreateAPIKeyRequest{Name: "openapi"}, "172.27.1.1")
+	admin.Header.Set("X-Admin-Key", testAdminKey)
+	checkResponse(t, ts, spec, "POST /admin/keys", admin)
+
+	admin = request("GET", "/admin/keys", nil, "172.27.1.2")
+	admin.Header.Set("X-Admin-Key", testAdminKey)
+	checkResponse(t, ts, spec, "GET /admin/keys", admin)
+	t.Log("✓ Admin responses match their schemas")
+}
+
+func TestOpenAPI_DocsUI(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	req, _ := http.NewRequest("GET", "/docs", nil)
+	resp, err := ts.app.Test(req, 30000)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode, "The UI is off unless DOCS_UI is set")
+
+	cfg := testConfig(ts.cfg.SolaanRPCURL)
+	cfg.DocsUI = true
+	withUI := newTestSuite(t, types.CreateAPIKeyRequest{Name: "docs"}, api.WithConfig(cfg))
+	defer withUI.cleanup(t)
+
+	resp, err = withUI.app.Test(req, 30000)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), `spec-url="/openapi.json"`)
+	assert.Contains(t, string(body), "redoc@2.1.5/")
+	assert.NotContains(t, string(body), "latest")
+	assert.Contains(t, resp.Header.Get(fiber.HeaderContentSecurityPolicy), "script-src https://cdn.jsdelivr.net/npm/redoc@2.1.5/bundles/redoc.standalone.js;")
+	t.Log("✓ A pinned Redoc release is served at /docs when enabled")
+}
@@ -0,0 +1,132 @@
+package test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nova/api/services"
+)
+
+func TestLookupPool_BoundsConcurrency(t *testing.T) {
+	pool := services.NewLookupPool(3)
+
+	var active, peak atomic.Int32
+	var wg sync.WaitGroup
+
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			if !assert.NoError(t, pool.Acquire(context.Background(), key)) {
+				return
+			}
+			defer pool.Release()
+
+			current := active.Add(1)
+			for {
+				highest := peak.Load()
+				if current <= highest || peak.CompareAndSwap(highest, current) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			active.Add(-1)
+		}([]string{"a", "b", "c"}[i%3])
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, peak.Load(), int32(3))
+	assert.Equal(t, services.PoolStats{Size: 3}, pool.Stats())
+	t.Logf("✓ At most %d of 30 lookups ran at once", peak.Load())
+}
+
+func TestLookupPool_RoundRobinBetweenKeys(t *testing.T) {
+	pool := services.NewLookupPool(1)
+	require.NoError(t, pool.Acquire(context.Background(), "holder"))
+
+	var mu sync.Mutex
+	var order []string
+
+	var wg sync.WaitGroup
+	enqueue := func(key string) {
+		queued := pool.Stats().Queued
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !assert.NoError(t, pool.Acquire(context.Background(), key)) {
+				return
+			}
+			mu.Lock()
+			order = append(order, key)
+			mu.Unlock()
+			pool.Release()
+		}()
+
+		require.Eventually(t, func() bool { return pool.Stats().Queued == queued+1 }, time.Second, time.Millisecond)
+	}
+
+	for i := 0; i < 4; i++ {
+		enqueue("bulk")
+	}
+	enqueue("small")
+
+	pool.Release()
+	wg.Wait()
+
+	assert.Equal(t, []string{"bulk", "small", "bulk", "bulk", "bulk"}, order)
+	t.Log("✓ A key queued behind a bulk request is served on the next slot")
+}
+
+func TestLookupPool_CancelledWaiterLeavesQueue(t *testing.T) {
+	pool := services.NewLookupPool(1)
+	require.NoError(t, pool.Acquire(context.Background(), "holder"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := pool.Acquire(ctx, "impatient")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 0, pool.Stats().Queued)
+
+	pool.Release()
+	assert.Equal(t, services.PoolStats{Size: 1}, pool.Stats())
+
+	require.NoError(t, pool.Acquire(context.Background(), "next"))
+	pool.Release()
+	t.Log("✓ Cancelled waiters do not leak slots")
+}
+
+func TestMetrics_PoolExposed(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	authStatus(t, ts, ts.testAPIKey, "172.22.0.1")
+	balanceRequest(t, ts, testWallets, "172.22.0.2")
+
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	resp, err := ts.app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	body, _ := io.ReadAll(resp.Body)
+	for _, line := range []string{
+		"nova_rpc_pool_size 16",
+		"nova_rpc_pool_queue_depth 0",
+		"# TYPE nova_rpc_pool_wait_seconds histogram",
+		`nova_rpc_pool_wait_seconds_bucket{le="+Inf"} 3`,
+	} {
+		assert.Contains(t, string(body), line)
+	}
+	assert.NotContains(t, string(body), ts.testAPIKey)
+	t.Log("✓ Pool metrics are exposed in Prometheus format")
+}
@@ -0,0 +1,253 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nova/api"
+	"nova/api/types"
+	"nova/test/fakerpc"
+)
+
+type rpcErrorResult struct {
+	ID    json.RawMessage `json:"id"`
+	Error *struct {
+		Code    int
+		Message string
+		Data    types.RPCErrorData
+	} `json:"error"`
+	Result json.RawMessage `json:"result"`
+}
+
+func rpcRequest(t *testing.T, ts *TestSuite, apiKey, body, clientIP string) (*http.Response, []byte) {
+	t.Helper()
+
+	req, _ := http.NewRequest("POST", "/rpc", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	req.Header.Set("X-Forwarded-For", clientIP)
+
+	resp, err := ts.app.Test(req, 30000)
+	require.NoError(t, err)
+
+	data, _ := io.ReadAll(resp.Body)
+	return resp, data
+}
+
+func TestRPC_SolanaClient(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	client := rpc.NewWithHeaders(serveHTTP(t, ts.server.Handler())+"/rpc", map[string]string{
+		"X-API-Key":       ts.testAPIKey,
+		"X-Forwarded-For": "172.30.0.1",
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	wallet := solana.MustPublicKeyFromBase58(testWallets[0])
+	balance, err := client.GetBalance(ctx, wallet, rpc.CommitmentFinalized)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1_500_000_000), balance.Value)
+
+	again, err := client.GetBalance(ctx, wallet, "")
+	require.NoError(t, err)
+	assert.Equal(t, balance.Context.Slot, again.Context.Slot)
+	assert.Equal(t, 1, ts.rpc.Calls("getBalance"))
+	t.Log("✓ Cached methods are served from cache whether or not the default commitment is spelled out")
+
+	_, err = client.GetBalance(ctx, wallet, rpc.CommitmentProcessed)
+	require.NoError(t, err)
+	assert.Equal(t, 2, ts.rpc.Calls("getBalance"))
+	t.Log("✓ Cache keys include the commitment")
+
+	resp, data := accountRequest(t, ts, "/api/v1/accounts/"+testWallets[0]+"/balance?commitment=processed", "", "172.30.0.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode, string(data))
+	assert.Equal(t, 2, ts.rpc.Calls("getBalance"))
+
+	other := solana.MustPublicKeyFromBase58(testWallets[1])
+	resp, data = accountRequest(t, ts, "/api/v1/accounts/"+testWallets[1]+"/balance", "", "172.30.0.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode, string(data))
+	shared, err := client.GetBalance(ctx, other, rpc.CommitmentFinalized)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42_000_000), shared.Value)
+	assert.Equal(t, 3, ts.rpc.Calls("getBalance"))
+	t.Log("✓ getBalance shares the balance cache with the REST lookups")
+
+	info, err := client.GetAccountInfo(ctx, wallet)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1_500_000_000), info.Value.Lamports)
+	assert.Equal(t, fakerpc.SystemProgram, info.Value.Owner.String())
+
+	_, err = client.GetAccountInfo(ctx, solana.NewWallet().PublicKey())
+	assert.ErrorIs(t, err, rpc.ErrNotFound)
+
+	health, err := client.GetHealth(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, rpc.HealthOk, health)
+	t.Log("✓ A standard Solana client works against /rpc")
+}
+
+func TestRPC_Batch(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	ts := newTestSuite(t, types.CreateAPIKeyRequest{Name: "rpc-batch"}, api.WithClock(clock.Now))
+	defer ts.cleanup(t)
+
+	resp, data := rpcRequest(t, ts, ts.testAPIKey, `[
+		{"jsonrpc": "2.0", "id": 1, "method": "getBalance", "params": ["`+testWallets[1]+`"]},
+		{"jsonrpc": "2.0", "id": "slot", "method": "getSlot"},
+		{"jsonrpc": "2.0", "id": 3, "method": "requestAirdrop", "params": ["`+testWallets[1]+`", 1000000000]},
+		{"jsonrpc": "2.0", "id": 4, "method": "getTokenAccountsByOwner", "params": ["`+testWallets[1]+`", {"programId": "`+fakerpc.TokenProgram+`"}]},
+		{"jsonrpc": "2.0", "id": 5, "method": "getBalance", "params": {"address": "`+testWallets[1]+`"}},
+		{"jsonrpc": "1.0", "id": 6, "method": "getSlot"}
+	]`, "172.30.1.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode, string(data))
+
+	var results []rpcErrorResult
+	require.NoError(t, json.Unmarshal(data, &results))
+	require.Len(t, results, 6)
+
+	for i, id := range []string{`1`, `"slot"`, `3`, `4`, `5`, `6`} {
+		assert.JSONEq(t, id, string(results[i].ID))
+	}
+
+	assert.Nil(t, results[0].Error)
+	assert.JSONEq(t, `42000000`, string(mustField(t, results[0].Result, "value")))
+	assert.Nil(t, results[1].Error)
+	assert.NotEmpty(t, results[1].Result)
+	t.Log("✓ Batches are answered in order with the caller's ids")
+
+	require.NotNil(t, results[2].Error)
+	assert.Equal(t, types.RPCMethodNotFound, results[2].Error.Code)
+	assert.Zero(t, ts.rpc.Calls("requestAirdrop"))
+	t.Log("✓ Methods outside the allowlist are not forwarded")
+
+	require.NotNil(t, results[3].Error)
+	assert.Equal(t, types.RPCServerError, results[3].Error.Code)
+	assert.Equal(t, types.CodeForbidden, results[3].Error.Data.Code)
+	assert.Equal(t, resp.Header.Get(fiber.HeaderXRequestID), results[3].Error.Data.RequestID)
+	assert.Zero(t, ts.rpc.Calls("getTokenAccountsByOwner"))
+	t.Log("✓ Token methods need the tokens:read scope")
+
+	require.NotNil(t, results[4].Error)
+	assert.Equal(t, types.RPCInvalidParams, results[4].Error.Code)
+	require.NotNil(t, results[5].Error)
+	assert.Equal(t, types.RPCInvalidRequest, results[5].Error.Code)
+	t.Log("✓ Malformed calls fail on their own")
+
+	assert.Equal(t, "14", resp.Header.Get("X-RateLimit-Remaining"))
+	t.Log("✓ Every call of a batch takes a rate-limit credit")
+
+	calls := make([]string, 101)
+	for i := range calls {
+		calls[i] = `{"jsonrpc": "2.0", "id": 1, "method": "getSlot"}`
+	}
+	resp, data = rpcRequest(t, ts, ts.testAPIKey, "["+strings.Join(calls, ",")+"]", "172.30.1.1")
+	require.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	var rejected rpcErrorResult
+	require.NoError(t, json.Unmarshal(data, &rejected))
+	require.NotNil(t, rejected.Error)
+	assert.Equal(t, types.CodeInvalidRequest, rejected.Error.Data.Code)
+	assert.Equal(t, float64(100), rejected.Error.Data.Details["max"])
+	t.Log("✓ Batches are limited to 100 calls")
+}
+
+func TestRPC_Errors(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	getBalance := `{"jsonrpc": "2.0", "id": 1, "method": "getBalance", "params": ["` + testWallets[2] + `"]}`
+
+	resp, data := rpcRequest(t, ts, "", getBalance, "172.30.2.1")
+	require.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+	var result rpcErrorResult
+	require.NoError(t, json.Unmarshal(data, &result))
+	require.NotNil(t, result.Error)
+	assert.Equal(t, types.RPCServerError, result.Error.Code)
+	assert.Equal(t, types.CodeUnauthorized, result.Error.Data.Code)
+	assert.Equal(t, resp.Header.Get(fiber.HeaderXRequestID), result.Error.Data.RequestID)
+	assert.JSONEq(t, `null`, string(result.ID))
+	t.Log("✓ Rejected requests get a JSON-RPC error with the API error code")
+
+	resp, data = rpcRequest(t, ts, ts.testAPIKey, `{"jsonrpc": "2.0",`, "172.30.2.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	result = rpcErrorResult{}
+	require.NoError(t, json.Unmarshal(data, &result))
+	require.NotNil(t, result.Error)
+	assert.Equal(t, types.RPCParseError, result.Error.Code)
+	t.Log("✓ Unparseable bodies get a parse error")
+
+	resp, data = rpcRequest(t, ts, ts.testAPIKey, `[{"jsonrpc": "2.0", "id": 1, "method": "getSlot"}, 1, {"jsonrpc": "2.0", "id": 3, "method": 3}]`, "172.30.2.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var results []rpcErrorResult
+	require.NoError(t, json.Unmarshal(data, &results))
+	require.Len(t, results, 3)
+	assert.Nil(t, results[0].Error)
+	require.NotNil(t, results[1].Error)
+	assert.Equal(t, types.RPCInvalidRequest, results[1].Error.Code)
+	assert.JSONEq(t, `null`, string(results[1].ID))
+	require.NotNil(t, results[2].Error)
+	assert.Equal(t, types.RPCInvalidRequest, results[2].Error.Code)
+	assert.JSONEq(t, `3`, string(results[2].ID))
+
+	resp, data = rpcRequest(t, ts, ts.testAPIKey, `1`, "172.30.2.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	result = rpcErrorResult{}
+	require.NoError(t, json.Unmarshal(data, &result))
+	require.NotNil(t, result.Error)
+	assert.Equal(t, types.RPCInvalidRequest, result.Error.Code)
+	t.Log("✓ Calls that are not request objects get an Invalid Request of their own")
+
+	ts.rpc.FailAccount(testWallets[2], &fakerpc.Error{Code: -32005, Message: "Node is behind"})
+	resp, data = rpcRequest(t, ts, ts.testAPIKey, getBalance, "172.30.2.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	result = rpcErrorResult{}
+	require.NoError(t, json.Unmarshal(data, &result))
+	require.NotNil(t, result.Error)
+	assert.Equal(t, -32005, result.Error.Code)
+	assert.Equal(t, "Node is behind", result.Error.Message)
+	t.Log("✓ Node errors are passed through")
+
+	ts.rpc.RateLimitNext(1)
+	resp, data = rpcRequest(t, ts, ts.testAPIKey, `{"jsonrpc": "2.0", "id": 1, "method": "getHealth"}`, "172.30.2.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	result = rpcErrorResult{}
+	require.NoError(t, json.Unmarshal(data, &result))
+	require.NotNil(t, result.Error)
+	assert.Equal(t, types.CodeUpstreamError, result.Error.Data.Code)
+	assert.NotContains(t, string(data), "Too Many Requests")
+	t.Log("✓ Upstream failures are reported without their details")
+
+	resp, data = rpcRequest(t, ts, ts.testAPIKey, `{"jsonrpc": "2.0", "id": 1, "method": "sendTransaction", "params": ["AQ=="]}`, "172.30.2.1")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	result = rpcErrorResult{}
+	require.NoError(t, json.Unmarshal(data, &result))
+	require.NotNil(t, result.Error)
+	assert.Equal(t, types.CodeForbidden, result.Error.Data.Code)
+	assert.Equal(t, types.ScopeTxSend, result.Error.Data.Details["scope"])
+	t.Log("✓ sendTransaction needs the tx:send scope")
+}
+
+// mustField returns one field of a JSON object.
+func mustField(t *testing.T, object json.RawMessage, name string) json.RawMessage {
+	t.Helper()
+
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(object, &fields))
+	return fields[name]
+}
@@ -0,0 +1,107 @@
+package test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nova/api"
+	"nova/api/types"
+)
+
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// exhaustIPLimit fails to authenticate from clientIP until the per-IP limit
+// rejects it.
+func exhaustIPLimit(t *testing.T, ts *TestSuite, clientIP string) {
+	t.Helper()
+
+	for i := 0; i < 9; i++ {
+		require.Equal(t, fiber.StatusUnauthorized, authStatus(t, ts, "invalid-key", clientIP))
+	}
+	require.Equal(t, fiber.StatusTooManyRequests, authStatus(t, ts, "invalid-key", clientIP))
+}
+
+func TestServer_InstancesAreIsolated(t *testing.T) {
+	t.Parallel()
+
+	first := newTestSuite(t, types.CreateAPIKeyRequest{Name: "first", Tier: types.TierEnterprise})
+	defer first.cleanup(t)
+	second := newTestSuite(t, types.CreateAPIKeyRequest{Name: "second", Tier: types.TierEnterprise})
+	defer second.cleanup(t)
+
+	exhaustIPLimit(t, first, "172.21.0.1")
+
+	assert.Equal(t, fiber.StatusUnauthorized, authStatus(t, second, "invalid-key", "172.21.0.1"))
+	t.Log("✓ Rate limiters are not shared between servers")
+
+	assert.Equal(t, fiber.StatusUnauthorized, authStatus(t, second, first.testAPIKey, "172.21.0.2"))
+	t.Log("✓ Keys are not shared between servers with separate stores")
+}
+
+func TestServer_ClockDrivesRateLimits(t *testing.T) {
+	t.Parallel()
+
+	clock := &testClock{now: time.Now()}
+	ts := newTestSuite(t, types.CreateAPIKeyRequest{Name: "clock", Tier: types.TierEnterprise}, api.WithClock(clock.Now))
+	defer ts.cleanup(t)
+
+	exhaustIPLimit(t, ts, "172.21.1.1")
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, fiber.StatusUnauthorized, authStatus(t, ts, "invalid-key", "172.21.1.1"))
+	t.Log("✓ Advancing the injected clock refills the limiter")
+}
+
+func TestServer_Handler(t *testing.T) {
+	t.Parallel()
+
+	ts := newTestSuite(t, types.CreateAPIKeyRequest{Name: "handler"})
+	defer ts.cleanup(t)
+
+	httpServer := httptest.NewServer(ts.server.Handler())
+	defer httpServer.Close()
+
+	req, _ := http.NewRequest("POST", httpServer.URL+"/api/get-balance", bytes.NewReader([]byte(`{"wallets":["`+testWallets[0]+`"]}`)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", ts.testAPIKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	t.Log("✓ Server is usable as a net/http handler")
+
+	_, err = api.NewServer()
+	assert.ErrorIs(t, err, api.ErrMissingConfig)
+
+	cfg := testConfig("http://127.0.0.1:8899")
+	cfg.SigningEncryptionKey = "abc"
+	_, err = api.NewServer(api.WithConfig(cfg))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid signing encryption key")
+	t.Log("✓ A bad signing encryption key is returned as an error")
+}
@@ -0,0 +1,72 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nova/api/services"
+	"nova/api/signing"
+	"nova/api/types"
+)
+
+func signedStatus(t *testing.T, ts *TestSuite, keyID, secret, nonce string, timestamp time.Time, clientIP string) int {
+	t.Helper()
+
+	body := []byte(`{"wallets":[]}`)
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+
+	req, _ := http.NewRequest("POST", "/api/get-balance", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-For", clientIP)
+	req.Header.Set(signing.HeaderKeyID, keyID)
+	req.Header.Set(signing.HeaderTimestamp, unix)
+	req.Header.Set(signing.HeaderNonce, nonce)
+	req.Header.Set(signing.HeaderSignature, signing.Sign(secret, "POST", "/api/get-balance", unix, nonce, body))
+
+	resp, err := ts.app.Test(req, 30000)
+	require.NoError(t, err)
+	return resp.StatusCode
+}
+
+func TestSigning_HMACRequests(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	resp, body := adminRequest(t, ts, "POST", "/admin/keys", types.CreateAPIKeyRequest{
+		Name:             "signed",
+		RequireSignature: true,
+	})
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode, string(body))
+
+	var created types.APIKeyResponse
+	require.NoError(t, json.Unmarshal(body, &created))
+	require.NotEmpty(t, created.SigningSecret)
+
+	keyID, ok := services.ParseAPIKey(created.Key)
+	require.True(t, ok)
+
+	assert.Equal(t, fiber.StatusUnauthorized, authStatus(t, ts, created.Key, "172.19.0.1"))
+	t.Log("✓ Bare API key is rejected when signing is required")
+
+	nonce := fmt.Sprintf("nonce-%d", time.Now().UnixNano())
+	assert.Equal(t, fiber.StatusBadRequest, signedStatus(t, ts, keyID, created.SigningSecret, nonce, time.Now(), "172.19.0.2"))
+	t.Log("✓ Signed request is accepted")
+
+	assert.Equal(t, fiber.StatusUnauthorized, signedStatus(t, ts, keyID, created.SigningSecret, nonce, time.Now(), "172.19.0.3"))
+	t.Log("✓ Replayed nonce is rejected")
+
+	assert.Equal(t, fiber.StatusUnauthorized, signedStatus(t, ts, keyID, created.SigningSecret, nonce+"-old", time.Now().Add(-10*time.Minute), "172.19.0.4"))
+	t.Log("✓ Timestamp outside the skew window is rejected")
+
+	assert.Equal(t, fiber.StatusUnauthorized, signedStatus(t, ts, keyID, "nova_sig_wrong", nonce+"-forged", time.Now(), "172.19.0.5"))
+	t.Log("✓ Signature with the wrong secret is rejected")
+}
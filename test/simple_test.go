@@ -2,100 +2,28 @@ package test
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"testing"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/joho/godotenv"
-	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
-	"go.mongodb.org/mongo-driver/v2/mongo/options"
-
-	"nova/api/config"
-	"nova/api/middleware"
-	"nova/api/routes"
-	"nova/api/services"
+
 	"nova/api/types"
 )
 
 func setupSimpleTest(t *testing.T) (*fiber.App, string) {
 	t.Helper()
 
-	middleware.ClearRateLimiters()
-
-	if err := godotenv.Load("../.env"); err != nil {
-		t.Logf("Warning: Could not load .env file: %v", err)
-	}
-
-	if os.Getenv("HELIUS_API_KEY") == "" {
-		t.Skip("HELIUS_API_KEY not set")
-	}
-
-	cfg := config.Load()
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	mongoClient, err := mongo.Connect(options.Client().ApplyURI(cfg.MongoURI))
-	require.NoError(t, err)
-
-	err = mongoClient.Ping(ctx, nil)
-	require.NoError(t, err)
-
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: cfg.RedisURI,
-	})
-
-	err = redisClient.Ping(ctx).Err()
-	require.NoError(t, err)
-
-	db := &types.Database{
-		MongoDB: mongoClient,
-		Redis:   redisClient,
-	}
-
-	solanaService := services.NewSolanaService(cfg.SolaanRPCURL, redisClient)
-
-	testAPIKey := fmt.Sprintf("simple-test-key-%d", time.Now().UnixNano())
-	collection := mongoClient.Database("nova").Collection("api_keys")
-
-	apiKey := types.APIKey{
-		Key:       testAPIKey,
-		Active:    true,
-		CreatedAt: time.Now(),
-	}
-
-	_, err = collection.InsertOne(ctx, apiKey)
-	require.NoError(t, err)
-
-	t.Cleanup(func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		collection.DeleteOne(ctx, bson.M{"key": testAPIKey})
-		mongoClient.Disconnect(ctx)
-		redisClient.Close()
-	})
-
-	app := fiber.New(fiber.Config{
-		DisableStartupMessage: true,
-	})
-
-	app.Use(middleware.RateLimitMiddleware())
-	app.Use(middleware.AuthMiddleware(db))
-
-	routes.InitSolanaService(solanaService)
-	routes.InitRoutes(app, db)
+	ts := newTestSuite(t, types.CreateAPIKeyRequest{Name: "simple-test"})
+	t.Cleanup(ts.rpc.Close)
 
-	return app, testAPIKey
+	// Simulate network latency so cache hits are measurably faster.
+	ts.rpc.SetLatency(20 * time.Millisecond)
+	return ts.app, ts.testAPIKey
 }
 
 func TestSimple_SingleWallet(t *testing.T) {
@@ -0,0 +1,136 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nova/api/types"
+	"nova/test/fakerpc"
+)
+
+func balanceRequest(t *testing.T, ts *TestSuite, wallets []string, clientIP string) types.BalanceResponse {
+	t.Helper()
+
+	reqBody, _ := json.Marshal(types.BalanceRequest{Wallets: wallets})
+	req, _ := http.NewRequest("POST", "/api/get-balance", bytes.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", ts.testAPIKey)
+	req.Header.Set("X-Forwarded-For", clientIP)
+
+	resp, err := ts.app.Test(req, 30000)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var response types.BalanceResponse
+	body, _ := io.ReadAll(resp.Body)
+	require.NoError(t, json.Unmarshal(body, &response))
+	return response
+}
+
+func TestSolana_RPCErrorsArePerWallet(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	ts.rpc.FailAccount(testWallets[1], &fakerpc.Error{Code: -32005, Message: "Node is behind"})
+
+	response := balanceRequest(t, ts, testWallets, "172.20.0.1")
+	require.Len(t, response.Data, len(testWallets))
+
+	assert.Empty(t, response.Data[0].Error)
+	assert.Equal(t, 1.5, response.Data[0].Balance)
+	assert.Contains(t, response.Data[1].Error, "Node is behind")
+	assert.Empty(t, response.Data[2].Error)
+	t.Log("✓ A failing account does not fail the other wallets")
+
+	ts.rpc.FailAccount(testWallets[1], nil)
+
+	response = balanceRequest(t, ts, []string{testWallets[1]}, "172.20.0.2")
+	assert.Empty(t, response.Data[0].Error)
+	assert.Equal(t, 0.042, response.Data[0].Balance)
+	t.Log("✓ Errors are not cached")
+}
+
+func TestSolana_RateLimitedUpstream(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	ts.rpc.RateLimitNext(1)
+
+	response := balanceRequest(t, ts, []string{testWallets[0]}, "172.20.1.1")
+	assert.NotEmpty(t, response.Data[0].Error, "429 from the RPC should surface as a wallet error")
+
+	response = balanceRequest(t, ts, []string{testWallets[0]}, "172.20.1.2")
+	assert.Empty(t, response.Data[0].Error)
+	assert.Equal(t, 1.5, response.Data[0].Balance)
+	assert.Equal(t, 1, ts.rpc.Calls("getBalance"), "The rate limited request should not reach the RPC handler")
+	t.Log("✓ Upstream 429 fails only the affected request")
+}
+
+func TestSolana_SlowUpstreamIsCached(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	ts.rpc.SetLatency(100 * time.Millisecond)
+
+	start := time.Now()
+	balanceRequest(t, ts, testWallets, "172.20.2.1")
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	start = time.Now()
+	balanceRequest(t, ts, testWallets, "172.20.2.2")
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+	assert.Equal(t, len(testWallets), ts.rpc.Calls("getBalance"))
+	t.Log("✓ Slow balances are fetched once and then served from cache")
+}
+
+func TestSolana_RequestTimeout(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	balanceRequest(t, ts, testWallets[:1], "172.20.3.1")
+	ts.rpc.SetLatency(time.Second)
+
+	reqBody, _ := json.Marshal(types.BalanceRequest{Wallets: testWallets})
+	timedRequest := func(timeout string) *http.Response {
+		req, _ := http.NewRequest("POST", "/api/get-balance", bytes.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", ts.testAPIKey)
+		req.Header.Set("X-Request-Timeout", timeout)
+		req.Header.Set("X-Forwarded-For", "172.20.3.2")
+
+		resp, err := ts.app.Test(req, 5000)
+		require.NoError(t, err)
+		return resp
+	}
+
+	start := time.Now()
+	resp := timedRequest("100ms")
+	elapsed := time.Since(start)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var response types.BalanceResponse
+	body, _ := io.ReadAll(resp.Body)
+	require.NoError(t, json.Unmarshal(body, &response))
+	require.Len(t, response.Data, len(testWallets))
+
+	assert.Less(t, elapsed, 500*time.Millisecond, "The request should not wait for the slow RPC")
+	assert.Empty(t, response.Data[0].Error)
+	assert.Equal(t, 1.5, response.Data[0].Balance)
+	for _, result := range response.Data[1:] {
+		assert.Equal(t, "timeout", result.Error)
+	}
+	t.Log("✓ Cached wallets are returned and the rest time out at the deadline")
+
+	for _, invalid := range []string{"soon", "-1s", "0"} {
+		assert.Equal(t, fiber.StatusBadRequest, timedRequest(invalid).StatusCode, invalid)
+	}
+	t.Log("✓ Invalid timeouts are rejected")
+}
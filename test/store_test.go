@@ -0,0 +1,149 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"nova/api/services"
+	"nova/api/store"
+	"nova/api/types"
+)
+
+func TestMemoryStore_KeyLifecycleAcrossReplicas(t *testing.T) {
+	cfg := &types.Config{SignatureMaxSkew: 5 * time.Minute}
+	stores := store.NewMemory()
+
+	replicaA, err := services.NewKeyService(cfg, stores)
+	require.NoError(t, err)
+	replicaB, err := services.NewKeyService(cfg, stores)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go replicaB.ListenForRevocations(ctx)
+
+	keyDoc, rawKey, err := replicaA.Create(ctx, types.CreateAPIKeyRequest{Name: "memory", Tier: types.TierPro})
+	require.NoError(t, err)
+
+	principal, err := replicaB.Authenticate(ctx, rawKey)
+	require.NoError(t, err)
+	assert.Equal(t, keyDoc.KeyID, principal.KeyID)
+	assert.Equal(t, types.TierPro, principal.Tier)
+	t.Log("✓ Key created on one replica authenticates on another")
+
+	_, err = replicaB.Authenticate(ctx, rawKey+"x")
+	assert.ErrorIs(t, err, services.ErrInvalidAPIKey)
+
+	// Let the subscription start and the shared cache writes settle.
+	time.Sleep(200 * time.Millisecond)
+
+	_, err = replicaA.Revoke(ctx, keyDoc.ID.Hex())
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		_, err := replicaB.Authenticate(ctx, rawKey)
+		return err == services.ErrInvalidAPIKey
+	}, time.Second, 10*time.Millisecond)
+	t.Log("✓ Revocation propagated through the in-memory cache")
+
+	keys, total, err := replicaA.List(ctx, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.False(t, keys[0].Active)
+}
+
+func TestMemoryStore_UsageQuotaAndFlush(t *testing.T) {
+	usage := services.NewUsageService(store.NewMemory())
+	principal := &types.Principal{KeyID: "memory-usage", DailyQuota: 2}
+
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, usage.ConsumeQuota(ctx, principal, now))
+	require.NoError(t, usage.ConsumeQuota(ctx, principal, now))
+	assert.ErrorIs(t, usage.ConsumeQuota(ctx, principal, now), services.ErrQuotaExceeded)
+	t.Log("✓ Daily quota enforced")
+
+	minute := now.Add(-2 * time.Minute)
+	require.NoError(t, usage.Record(ctx, principal.KeyID, types.UsageCounters{Requests: 1, Wallets: 3, RPCCalls: 2, CacheHits: 1}, minute))
+	require.NoError(t, usage.Record(ctx, principal.KeyID, types.UsageCounters{Requests: 1, Wallets: 1, RPCCalls: 1}, now))
+
+	flushed, err := usage.Flush(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, flushed, "Only completed minutes are flushed")
+
+	report, err := usage.Report(ctx, principal, now.Add(-time.Hour), now.Add(time.Minute), now)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(2), report.Daily.Used)
+	assert.Len(t, report.History, 2)
+	assert.Equal(t, int64(2), report.Totals.Requests)
+	assert.Equal(t, int64(4), report.Totals.Wallets)
+	t.Log("✓ Report combines flushed and pending usage")
+}
+
+func TestMemoryStore_PastQuotasAreDropped(t *testing.T) {
+	cache := store.NewMemoryUsageCache()
+	ctx := context.Background()
+
+	monday := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	tuesday := monday.Add(24 * time.Hour)
+	april := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	_, _, err := cache.IncrementQuota(ctx, "memory-quota", monday)
+	require.NoError(t, err)
+	daily, monthly, err := cache.IncrementQuota(ctx, "memory-quota", tuesday)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), daily)
+	assert.Equal(t, int64(2), monthly)
+
+	daily, monthly, err = cache.QuotaUsed(ctx, "memory-quota", monday)
+	require.NoError(t, err)
+	assert.Zero(t, daily)
+	assert.Equal(t, int64(2), monthly)
+	t.Log("✓ Counters of past days are dropped")
+
+	_, _, err = cache.IncrementQuota(ctx, "memory-quota", april)
+	require.NoError(t, err)
+	daily, monthly, err = cache.QuotaUsed(ctx, "memory-quota", tuesday)
+	require.NoError(t, err)
+	assert.Zero(t, daily)
+	assert.Zero(t, monthly)
+	t.Log("✓ Counters of past months are dropped")
+}
+
+func TestMemoryStore_RetiredCacheKeysExpire(t *testing.T) {
+	keys := store.NewMemoryKeyStore()
+	ctx := context.Background()
+
+	id := bson.NewObjectID()
+	require.NoError(t, keys.Insert(ctx, &types.APIKey{ID: id, KeyID: "first", Active: true}))
+
+	start := time.Now()
+	rotate := func(keyID, retired string, at time.Time) {
+		t.Helper()
+		require.NoError(t, keys.Rotate(ctx, id, store.KeyRotation{
+			KeyID:             keyID,
+			RotatedAt:         at,
+			RetiredCacheKey:   retired,
+			DropRetiredBefore: at.Add(-15 * time.Minute),
+		}))
+	}
+	rotate("second", "api_key:first", start)
+	rotate("third", "api_key:second", start.Add(10*time.Minute))
+	rotate("fourth", "api_key:third", start.Add(20*time.Minute))
+
+	keyDoc, err := keys.Get(ctx, id)
+	require.NoError(t, err)
+	retired := make([]string, len(keyDoc.RetiredCacheKeys))
+	for i, entry := range keyDoc.RetiredCacheKeys {
+		retired[i] = entry.CacheKey
+	}
+	assert.Equal(t, []string{"api_key:second", "api_key:third"}, retired)
+	t.Log("✓ Rotation drops cache keys retired longer ago than the cache lifetime")
+}
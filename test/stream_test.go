@@ -0,0 +1,214 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nova/api/types"
+)
+
+func streamRequest(ts *TestSuite, baseURL string, wallets []string, accept, clientIP string) *http.Request {
+	reqBody, _ := json.Marshal(types.BalanceRequest{Wallets: wallets})
+	req, _ := http.NewRequest("POST", baseURL+"/api/get-balance", bytes.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-API-Key", ts.testAPIKey)
+	req.Header.Set("X-Forwarded-For", clientIP)
+	return req
+}
+
+// listen serves the suite's app on a real socket, which app.Test cannot
+// do for streamed bodies.
+func listen(t *testing.T, ts *TestSuite) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go ts.app.Listener(ln)
+	t.Cleanup(func() { ts.app.Shutdown() })
+	return "http://" + ln.Addr().String()
+}
+
+func readStream(t *testing.T, body io.Reader) ([]types.BalanceStreamItem, *types.BalanceStreamSummary) {
+	t.Helper()
+
+	var items []types.BalanceStreamItem
+	var summary *types.BalanceStreamSummary
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		require.Nil(t, summary, "The summary should be the last record")
+
+		var end types.BalanceStreamEnd
+		if strings.HasPrefix(scanner.Text(), `{"summary"`) {
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &end))
+			summary = &end.Summary
+			continue
+		}
+
+		var item types.BalanceStreamItem
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &item))
+		items = append(items, item)
+	}
+
+	require.NotNil(t, summary)
+	return items, summary
+}
+
+func TestStream_NDJSON(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	wallets := append([]string{}, testWallets...)
+	wallets = append(wallets, testWallets[0], "not-a-wallet")
+
+	resp, err := ts.app.Test(streamRequest(ts, "", wallets, "application/x-ndjson", "172.24.0.1"), 30000)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+	items, summary := readStream(t, resp.Body)
+	require.Len(t, items, len(wallets))
+
+	seen := make(map[int]types.BalanceStreamItem)
+	for _, item := range items {
+		assert.Equal(t, wallets[item.Index], item.Address)
+		seen[item.Index] = item
+	}
+	assert.Len(t, seen, len(wallets), "Every wallet should appear once")
+	assert.Equal(t, 1.5, seen[3].Balance)
+	assert.NotEmpty(t, seen[4].Error)
+
+	assert.Equal(t, len(wallets), summary.Wallets)
+	assert.Equal(t, 4, summary.Succeeded)
+	assert.Equal(t, 1, summary.Errors)
+	assert.Equal(t, len(testWallets), summary.RPCCalls)
+	t.Log("✓ Each wallet is streamed with its index, followed by a summary")
+
+	keyDoc, err := ts.stores.Keys.Get(context.Background(), ts.testKeyID)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		now := time.Now()
+		report, err := ts.server.Usage().Report(context.Background(), keyDoc.Principal(), now.Add(-time.Hour), now.Add(time.Hour), now)
+		return err == nil && report.Totals.RPCCalls == int64(len(testWallets))
+	}, 2*time.Second, 20*time.Millisecond)
+	t.Log("✓ Streamed lookups count towards the key's usage")
+}
+
+func TestStream_ServerSentEvents(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	resp, err := ts.app.Test(streamRequest(ts, "", testWallets, "text/event-stream", "172.24.1.1"), 30000)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	body, _ := io.ReadAll(resp.Body)
+	events := strings.Split(strings.TrimSpace(string(body)), "\n\n")
+	require.Len(t, events, len(testWallets)+1)
+
+	for _, event := range events[:len(testWallets)] {
+		assert.True(t, strings.HasPrefix(event, "event: balance\ndata: {"), event)
+	}
+	assert.True(t, strings.HasPrefix(events[len(testWallets)], "event: summary\ndata: {\"summary\""))
+	t.Log("✓ Balances and the summary are sent as named events")
+
+	resp, err = ts.app.Test(streamRequest(ts, "", testWallets, "application/json", "172.24.1.2"), 30000)
+	require.NoError(t, err)
+	var response types.BalanceResponse
+	body, _ = io.ReadAll(resp.Body)
+	require.NoError(t, json.Unmarshal(body, &response))
+	assert.Len(t, response.Data, len(testWallets))
+	t.Log("✓ Plain JSON is still the default")
+}
+
+func TestStream_SlowWalletDoesNotHoldBackOthers(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	baseURL := listen(t, ts)
+	ts.rpc.DelayAccount(testWallets[1], time.Second)
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(streamRequest(ts, baseURL, testWallets, "application/x-ndjson", "172.24.2.1"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadBytes('\n')
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "The first balance should not wait for the slow one")
+
+	var first types.BalanceStreamItem
+	require.NoError(t, json.Unmarshal(line, &first))
+	assert.NotEqual(t, testWallets[1], first.Address)
+
+	items, summary := readStream(t, reader)
+	assert.Len(t, items, len(testWallets)-1)
+	assert.GreaterOrEqual(t, summary.ElapsedMS, int64(1000))
+	t.Log("✓ Fast wallets are written before the slow one resolves")
+}
+
+func TestStream_TimeoutsAreCounted(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	baseURL := listen(t, ts)
+	ts.rpc.DelayAccount(testWallets[1], 2*time.Second)
+
+	req := streamRequest(ts, baseURL, testWallets, "application/x-ndjson", "172.24.4.1")
+	req.Header.Set("X-Request-Timeout", "300ms")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	items, summary := readStream(t, resp.Body)
+	require.Len(t, items, len(testWallets))
+	assert.Equal(t, 1, summary.Timeouts)
+	assert.Equal(t, 0, summary.Errors)
+	assert.Equal(t, len(testWallets)-1, summary.Succeeded)
+	t.Log("✓ Lookups cut short by the deadline are counted as timeouts")
+}
+
+func TestStream_ClientDisconnectStopsLookups(t *testing.T) {
+	ts := newTestSuite(t, types.CreateAPIKeyRequest{Name: "stream", Tier: types.TierPro})
+	defer ts.cleanup(t)
+
+	baseURL := listen(t, ts)
+	ts.rpc.SetLatency(100 * time.Millisecond)
+
+	wallets := make([]string, 0, 300)
+	for i := 0; i < 300; i++ {
+		wallets = append(wallets, solana.NewWallet().PublicKey().String())
+	}
+
+	resp, err := http.DefaultClient.Do(streamRequest(ts, baseURL, wallets, "application/x-ndjson", "172.24.3.1"))
+	require.NoError(t, err)
+
+	_, err = bufio.NewReader(resp.Body).ReadBytes('\n')
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.Eventually(t, func() bool {
+		return ts.solanaService.Pool().Stats().Active == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(300 * time.Millisecond)
+
+	assert.Less(t, ts.rpc.Calls("getBalance"), len(wallets), "Lookups should stop once the client is gone")
+	t.Logf("✓ %d of %d wallets were looked up before the client left", ts.rpc.Calls("getBalance"), len(wallets))
+}
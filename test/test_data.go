@@ -1,13 +1,15 @@
 package test
 
 import (
+	"nova/api"
 	"nova/api/services"
+	"nova/api/store"
 	"nova/api/types"
+	"nova/test/fakerpc"
 
-	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/bson"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/redis/go-redis/v9"
 )
 
 var testWallets = []string{
@@ -16,11 +18,25 @@ var testWallets = []string{
 	"Ag3Gao5hvTPDsHLBf5SBDse8wQwBrMcgE6ox1GoKgTuh",
 }
 
+// testBalances are the lamports the fake RPC reports for testWallets.
+var testBalances = []uint64{
+	1_500_000_000,
+	42_000_000,
+	0,
+}
+
+const (
+	testAdminKey             = "test-admin-key-123"
+	testSigningEncryptionKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+)
+
 type TestSuite struct {
 	app           *fiber.App
-	mongoClient   *mongo.Client
-	redisClient   *redis.Client
+	server        *api.Server
+	stores        *store.Stores
+	rpc           *fakerpc.Server
 	solanaService *services.SolanaService
 	testAPIKey    string
+	testKeyID     bson.ObjectID
 	cfg           *types.Config
 }
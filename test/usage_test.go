@@ -0,0 +1,122 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nova/api/services"
+	"nova/api/types"
+)
+
+func TestUsage_DailyQuotaEnforced(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	apiKey := createRestrictedKey(t, ts, types.CreateAPIKeyRequest{
+		Name:       "quota",
+		DailyQuota: 3,
+	})
+
+	statuses := make([]int, 0, 5)
+	for i := 0; i < 5; i++ {
+		statuses = append(statuses, authStatus(t, ts, apiKey, "172.18.0.1"))
+	}
+
+	assert.Equal(t, []int{400, 400, 400, 429, 429}, statuses)
+	t.Log("✓ Requests beyond the daily quota are rejected with 429")
+
+	req, _ := http.NewRequest("POST", "/api/get-balance", nil)
+	req.Header.Set("X-API-Key", apiKey)
+	req.Header.Set("X-Forwarded-For", "172.18.0.2")
+
+	resp, err := ts.app.Test(req, 30000)
+	require.NoError(t, err)
+
+	var errorResponse types.ErrorResponse
+	body, _ := io.ReadAll(resp.Body)
+	require.NoError(t, json.Unmarshal(body, &errorResponse))
+	assert.Equal(t, "quota_exceeded", errorResponse.Code)
+}
+
+func TestUsage_ReportAndFlush(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	keyID, _ := services.ParseAPIKey(ts.testAPIKey)
+
+	for i := 0; i < 2; i++ {
+		authStatus(t, ts, ts.testAPIKey, "172.18.1.1")
+	}
+
+	var report types.UsageResponse
+	require.Eventually(t, func() bool {
+		req, _ := http.NewRequest("GET", "/api/usage", nil)
+		req.Header.Set("X-API-Key", ts.testAPIKey)
+		req.Header.Set("X-Forwarded-For", "172.18.1.2")
+
+		resp, err := ts.app.Test(req, 30000)
+		if err != nil || resp.StatusCode != fiber.StatusOK {
+			return false
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		return json.Unmarshal(body, &report) == nil && report.Data.Totals.Requests >= 2
+	}, 2*time.Second, 50*time.Millisecond)
+
+	assert.Equal(t, keyID, report.Data.KeyID)
+	assert.GreaterOrEqual(t, report.Data.Daily.Used, int64(2))
+	t.Logf("✓ Usage report: %+v", report.Data.Totals)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	usage := services.NewUsageService(ts.stores)
+	_, err := usage.Flush(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+
+	now := time.Now()
+	records, err := ts.stores.Usage.History(ctx, ts.testKeyID.Hex(), now.Add(-time.Hour), now.Add(time.Hour), 10)
+	require.NoError(t, err)
+	require.NotEmpty(t, records)
+	assert.GreaterOrEqual(t, records[0].Requests, int64(2))
+	t.Log("✓ Usage counters were flushed to the usage store")
+}
+
+func TestUsage_SurvivesRotation(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	resp, body := adminRequest(t, ts, "POST", "/admin/keys", types.CreateAPIKeyRequest{Name: "rotated", DailyQuota: 3})
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode, string(body))
+
+	var created types.APIKeyResponse
+	require.NoError(t, json.Unmarshal(body, &created))
+
+	assert.Equal(t, fiber.StatusBadRequest, authStatus(t, ts, created.Key, "172.18.2.1"))
+	assert.Equal(t, fiber.StatusBadRequest, authStatus(t, ts, created.Key, "172.18.2.1"))
+
+	resp, body = adminRequest(t, ts, "POST", "/admin/keys/"+created.Data.ID.Hex()+"/rotate", nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode, string(body))
+
+	var rotated types.APIKeyResponse
+	require.NoError(t, json.Unmarshal(body, &rotated))
+
+	assert.Equal(t, fiber.StatusBadRequest, authStatus(t, ts, rotated.Key, "172.18.2.1"))
+	assert.Equal(t, fiber.StatusTooManyRequests, authStatus(t, ts, rotated.Key, "172.18.2.1"))
+	t.Log("✓ Rotating a key does not reset its quota")
+
+	now := time.Now()
+	require.Eventually(t, func() bool {
+		report, err := ts.server.Usage().Report(context.Background(), rotated.Data.Principal(), now.Add(-time.Hour), now.Add(time.Hour), now)
+		return err == nil && report.Totals.Requests == 3
+	}, 2*time.Second, 50*time.Millisecond)
+	t.Log("✓ Usage history carries over to the rotated key")
+}
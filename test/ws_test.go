@@ -0,0 +1,313 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nova/api/types"
+	"nova/test/fakerpc"
+)
+
+type wsMessage struct {
+	Type      string   `json:"type"`
+	Addresses []string `json:"addresses"`
+	types.BalanceUpdate
+	types.APIError
+}
+
+func dialWS(t *testing.T, baseURL, apiKey, clientIP string) *websocket.Conn {
+	t.Helper()
+
+	header := http.Header{"X-Forwarded-For": {clientIP}}
+	if apiKey != "" {
+		header.Set("X-API-Key", apiKey)
+	}
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(baseURL, "http")+"/ws", header)
+	require.NoError(t, err)
+	resp.Body.Close()
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func sendWS(t *testing.T, conn *websocket.Conn, messageType string, addresses ...string) {
+	t.Helper()
+	require.NoError(t, conn.WriteJSON(types.WSRequest{Type: messageType, Addresses: addresses}))
+}
+
+func readWS(t *testing.T, conn *websocket.Conn) wsMessage {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+
+	var message wsMessage
+	require.NoError(t, json.Unmarshal(data, &message))
+	return message
+}
+
+func TestWebSocket_BalanceUpdates(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	conn := dialWS(t, listen(t, ts), ts.testAPIKey, "172.31.0.1")
+	sendWS(t, conn, types.WSSubscribe, testWallets[0], " "+testWallets[1], testWallets[0])
+
+	ack := readWS(t, conn)
+	assert.Equal(t, types.WSSubscribed, ack.Type)
+	assert.Equal(t, []string{testWallets[0], testWallets[1]}, ack.Addresses)
+
+	first, second := readWS(t, conn), readWS(t, conn)
+	assert.Equal(t, types.WSBalance, first.Type)
+	assert.Equal(t, testWallets[0], first.Address)
+	assert.Equal(t, 1.5, first.Balance)
+	assert.Equal(t, testWallets[1], second.Address)
+	assert.Equal(t, uint64(42_000_000), second.Lamports)
+	t.Log("✓ Subscribing sends the current balances")
+
+	require.Eventually(t, func() bool { return ts.rpc.Subscriptions(testWallets[0]) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	ts.rpc.SetBalance(testWallets[0], 2_000_000_000)
+	update := readWS(t, conn)
+	assert.Equal(t, types.WSBalance, update.Type)
+	assert.Equal(t, testWallets[0], update.Address)
+	assert.Equal(t, 2.0, update.Balance)
+	assert.Greater(t, update.Slot, first.Slot)
+	t.Log("✓ Balance changes are pushed")
+
+	calls := ts.rpc.Calls("getBalance")
+	reqBody, _ := json.Marshal(types.BalanceRequest{Wallets: []string{testWallets[0]}})
+	req, _ := http.NewRequest("POST", "/api/get-balance", bytes.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", ts.testAPIKey)
+	req.Header.Set("X-Forwarded-For", "172.31.0.2")
+	resp, err := ts.app.Test(req, 30000)
+	require.NoError(t, err)
+
+	var balances types.BalanceResponse
+	body, _ := io.ReadAll(resp.Body)
+	require.NoError(t, json.Unmarshal(body, &balances))
+	require.Len(t, balances.Data, 1)
+	assert.Equal(t, 2.0, balances.Data[0].Balance)
+	assert.Equal(t, calls, ts.rpc.Calls("getBalance"))
+	t.Log("✓ Pushed balances refresh the balance cache")
+
+	sendWS(t, conn, types.WSUnsubscribe, testWallets[0], testWallets[2])
+	ack = readWS(t, conn)
+	assert.Equal(t, types.WSUnsubscribed, ack.Type)
+	assert.Equal(t, []string{testWallets[0]}, ack.Addresses)
+	require.Eventually(t, func() bool { return ts.rpc.Subscriptions(testWallets[0]) == 0 }, 5*time.Second, 10*time.Millisecond)
+
+	ts.rpc.SetBalance(testWallets[0], 3_000_000_000)
+	ts.rpc.SetBalance(testWallets[1], 50_000_000)
+	update = readWS(t, conn)
+	assert.Equal(t, testWallets[1], update.Address)
+	assert.Equal(t, 0.05, update.Balance)
+	t.Log("✓ Unsubscribed addresses are no longer pushed")
+}
+
+func TestWebSocket_Multiplexing(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	url := listen(t, ts)
+	clients := []*websocket.Conn{
+		dialWS(t, url, ts.testAPIKey, "172.31.1.1"),
+		dialWS(t, url, ts.testAPIKey, "172.31.1.2"),
+	}
+	for _, conn := range clients {
+		sendWS(t, conn, types.WSSubscribe, testWallets[0])
+		assert.Equal(t, types.WSSubscribed, readWS(t, conn).Type)
+		assert.Equal(t, types.WSBalance, readWS(t, conn).Type)
+	}
+
+	require.Eventually(t, func() bool { return ts.rpc.Subscriptions(testWallets[0]) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, ts.rpc.Connections())
+	assert.Equal(t, 1, ts.rpc.Calls("accountSubscribe"))
+	t.Log("✓ Clients share one upstream connection and subscription")
+
+	ts.rpc.SetBalance(testWallets[0], 7_000_000_000)
+	for _, conn := range clients {
+		update := readWS(t, conn)
+		assert.Equal(t, testWallets[0], update.Address)
+		assert.Equal(t, 7.0, update.Balance)
+	}
+	t.Log("✓ Every subscriber gets the update")
+
+	clients[0].Close()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, ts.rpc.Subscriptions(testWallets[0]))
+
+	ts.rpc.SetBalance(testWallets[0], 8_000_000_000)
+	assert.Equal(t, 8.0, readWS(t, clients[1]).Balance)
+	t.Log("✓ The upstream subscription stays while a subscriber remains")
+
+	clients[1].Close()
+	require.Eventually(t, func() bool { return ts.rpc.Connections() == 0 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, ts.rpc.Calls("accountUnsubscribe"))
+	t.Log("✓ The upstream connection closes after the last subscriber")
+}
+
+func TestWebSocket_Reconnect(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	conn := dialWS(t, listen(t, ts), ts.testAPIKey, "172.31.2.1")
+	sendWS(t, conn, types.WSSubscribe, testWallets[0], testWallets[1])
+	for range 3 {
+		readWS(t, conn)
+	}
+
+	subscribed := func() bool {
+		return ts.rpc.Subscriptions(testWallets[0]) == 1 && ts.rpc.Subscriptions(testWallets[1]) == 1
+	}
+	require.Eventually(t, subscribed, 5*time.Second, 10*time.Millisecond)
+
+	ts.rpc.DropConnections()
+	require.Eventually(t, subscribed, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 4, ts.rpc.Calls("accountSubscribe"))
+	t.Log("✓ Every address is subscribed again after the connection drops")
+
+	ts.rpc.SetBalance(testWallets[1], 1_000_000)
+	update := readWS(t, conn)
+	assert.Equal(t, testWallets[1], update.Address)
+	assert.Equal(t, 0.001, update.Balance)
+	t.Log("✓ Updates resume on the new connection")
+}
+
+func TestWebSocket_Errors(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	url := listen(t, ts)
+
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http")+"/ws", http.Header{"X-Forwarded-For": {"172.31.3.1"}})
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+	var apiErr types.APIError
+	body, _ := io.ReadAll(resp.Body)
+	require.NoError(t, json.Unmarshal(body, &apiErr))
+	assert.Equal(t, types.CodeUnauthorized, apiErr.Code)
+	assert.NotEmpty(t, apiErr.RequestID)
+	t.Log("✓ Handshakes need an API key")
+
+	req, _ := http.NewRequest("GET", "/ws", nil)
+	req.Header.Set("X-API-Key", ts.testAPIKey)
+	req.Header.Set("X-Forwarded-For", "172.31.3.1")
+	resp, err = ts.app.Test(req, 30000)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUpgradeRequired, resp.StatusCode)
+	t.Log("✓ Plain requests are told to upgrade")
+
+	conn := dialWS(t, url, ts.testAPIKey, "172.31.3.2")
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"type":`)))
+	message := readWS(t, conn)
+	assert.Equal(t, types.WSError, message.Type)
+	assert.Equal(t, types.CodeInvalidRequest, message.Code)
+
+	sendWS(t, conn, "poll", testWallets[0])
+	message = readWS(t, conn)
+	assert.Equal(t, types.CodeInvalidRequest, message.Code)
+	assert.Equal(t, "poll", message.Details["type"])
+
+	sendWS(t, conn, types.WSSubscribe, testWallets[0], "not-an-address")
+	message = readWS(t, conn)
+	assert.Equal(t, types.CodeInvalidAddress, message.Code)
+	t.Log("✓ Bad messages get an error and leave the connection open")
+
+	many := make([]string, 101)
+	for i := range many {
+		many[i] = solana.NewWallet().PublicKey().String()
+	}
+	sendWS(t, conn, types.WSSubscribe, many...)
+	message = readWS(t, conn)
+	assert.Equal(t, types.CodeTooManyWallets, message.Code)
+	assert.Equal(t, float64(100), message.Details["max"])
+	assert.Zero(t, ts.rpc.Calls("accountSubscribe"))
+	t.Log("✓ A connection is limited to the tier's wallet count")
+
+	ts.rpc.FailAccount(testWallets[2], &fakerpc.Error{Code: -32005, Message: "Node is behind"})
+	sendWS(t, conn, types.WSSubscribe, testWallets[2])
+	assert.Equal(t, types.WSSubscribed, readWS(t, conn).Type)
+	message = readWS(t, conn)
+	assert.Equal(t, types.CodeUpstreamError, message.Code)
+	assert.Equal(t, testWallets[2], message.Details["address"])
+	assert.NotContains(t, message.Message, "Node is behind")
+	t.Log("✓ Failed snapshots are reported per address")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, ts.server.Shutdown(ctx))
+
+	message = readWS(t, conn)
+	assert.Equal(t, types.CodeUnavailable, message.Code)
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "%v", err)
+	t.Log("✓ Shutdown closes open connections")
+}
+
+func TestWebSocket_Reauthentication(t *testing.T) {
+	ts := setupTest(t)
+	defer ts.cleanup(t)
+
+	url := listen(t, ts)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	expectClosed := func(conn *websocket.Conn, code string) {
+		t.Helper()
+		message := readWS(t, conn)
+		assert.Equal(t, types.WSError, message.Type)
+		assert.Equal(t, code, message.Code)
+		_, _, err := conn.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), "%v", err)
+	}
+
+	conn := dialWS(t, url, ts.testAPIKey, "172.31.4.1")
+	sendWS(t, conn, types.WSSubscribe, testWallets[0])
+	assert.Equal(t, types.WSSubscribed, readWS(t, conn).Type)
+	assert.Equal(t, types.WSBalance, readWS(t, conn).Type)
+
+	_, err := ts.server.Keys().Revoke(ctx, ts.testKeyID.Hex())
+	require.NoError(t, err)
+	expectClosed(conn, types.CodeUnauthorized)
+	t.Log("✓ Revoking a key closes its open connections")
+
+	keyDoc, rawKey, err := ts.server.Keys().Create(ctx, types.CreateAPIKeyRequest{Name: "ws-rotate"})
+	require.NoError(t, err)
+	conn = dialWS(t, url, rawKey, "172.31.4.2")
+	sendWS(t, conn, types.WSSubscribe, testWallets[1])
+	assert.Equal(t, types.WSSubscribed, readWS(t, conn).Type)
+	assert.Equal(t, types.WSBalance, readWS(t, conn).Type)
+
+	_, _, err = ts.server.Keys().Rotate(ctx, keyDoc.ID.Hex())
+	require.NoError(t, err)
+	expectClosed(conn, types.CodeUnauthorized)
+	t.Log("✓ Rotating a key closes connections made with the old key")
+
+	expiresAt := time.Now().Add(time.Second)
+	_, rawKey, err = ts.server.Keys().Create(ctx, types.CreateAPIKeyRequest{Name: "ws-expiry", ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+	conn = dialWS(t, url, rawKey, "172.31.4.3")
+	sendWS(t, conn, types.WSSubscribe, testWallets[2])
+	assert.Equal(t, types.WSSubscribed, readWS(t, conn).Type)
+	assert.Equal(t, types.WSBalance, readWS(t, conn).Type)
+	expectClosed(conn, types.CodeKeyExpired)
+	assert.False(t, time.Now().Before(expiresAt))
+	t.Log("✓ Connections close when their key expires")
+}